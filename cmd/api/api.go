@@ -18,9 +18,14 @@ import (
 
 	"github.com/sikozonpc/social/docs" // This is required to generate swagger docs
 	"github.com/sikozonpc/social/internal/auth"
+	"github.com/sikozonpc/social/internal/blob"
 	"github.com/sikozonpc/social/internal/env"
+	"github.com/sikozonpc/social/internal/live"
 	"github.com/sikozonpc/social/internal/mailer"
+	"github.com/sikozonpc/social/internal/messenger"
 	"github.com/sikozonpc/social/internal/ratelimiter"
+	"github.com/sikozonpc/social/internal/render"
+	"github.com/sikozonpc/social/internal/sms"
 	"github.com/sikozonpc/social/internal/store"
 	"github.com/sikozonpc/social/internal/store/cache"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
@@ -34,6 +39,18 @@ type application struct {
 	mailer        mailer.Client
 	authenticator auth.Authenticator
 	rateLimiter   ratelimiter.Limiter
+	renderer      *render.Renderer
+	cardSigner    *auth.CardSigner
+	streamTickets *auth.TicketSigner
+	broker        live.Broker
+	sms           sms.Client
+	messenger     messenger.Client
+	blobs         blob.Store
+	// mailCapture keeps the sent mails when the capture mailer is used
+	mailCapture *mailer.CaptureMailer
+	// mailEvents reads the event webhook of the mail provider, nil when it
+	// is disabled
+	mailEvents *mailer.EventWebhook
 }
 
 type config struct {
@@ -46,6 +63,56 @@ type config struct {
 	auth        authConfig
 	redisCfg    redisConfig
 	rateLimiter ratelimiter.Config
+	cards       cardsConfig
+	scheduler   schedulerConfig
+	sms         smsConfig
+	messenger   messengerConfig
+}
+
+type smsConfig struct {
+	// provider is "http", "file" or "stdout"
+	provider    string
+	countryCode string
+	file        string
+	http        sms.HTTPConfig
+}
+
+type messengerConfig struct {
+	// provider is "http" or "stdout"
+	provider string
+	// imagesDir keeps the card images of the stdout provider when set
+	imagesDir string
+	http      messenger.HTTPConfig
+}
+
+type schedulerConfig struct {
+	// rsvpInterval is how often events past their RSVP deadline are closed
+	rsvpInterval time.Duration
+	// reminderInterval is how often campaign reminders are dispatched
+	reminderInterval time.Duration
+	// mailInterval is how often the mail outbox is delivered
+	mailInterval time.Duration
+	// cardInterval is how often card jobs are looked for
+	cardInterval time.Duration
+	// blobInterval is how often images no card or template refers to are
+	// deleted
+	blobInterval time.Duration
+}
+
+type cardsConfig struct {
+	// storage keeps template images and the rendered cards, "local" or "s3"
+	storage string
+	// assetsDir is the directory of the local storage
+	assetsDir string
+	// urlSecret signs the download urls of the local storage, served under
+	// blobsURL
+	urlSecret string
+	blobsURL  string
+	// urlExpiry is how long signed download urls stay valid
+	urlExpiry time.Duration
+	s3        blob.S3Config
+	// workers is how many cards of a card job are rendered at once
+	workers int
 }
 
 type redisConfig struct {
@@ -58,6 +125,11 @@ type redisConfig struct {
 type authConfig struct {
 	basic basicConfig
 	token tokenConfig
+	card  cardSigningConfig
+}
+
+type cardSigningConfig struct {
+	secret string
 }
 
 type tokenConfig struct {
@@ -72,18 +144,29 @@ type basicConfig struct {
 }
 
 type mailConfig struct {
+	// mailer is "capture", "smtp", "mailtrap" or "sendgrid"
+	mailer    string
 	sendGrid  sendGridConfig
 	mailTrap  mailTrapConfig
+	smtp      mailer.SMTPConfig
+	capture   captureConfig
 	fromEmail string
 	exp       time.Duration
 }
 
+type captureConfig struct {
+	// dir keeps the captured mails on disk, in memory when empty
+	dir string
+}
+
 type mailTrapConfig struct {
 	apiKey string
 }
 
 type sendGridConfig struct {
 	apiKey string
+	// webhookKey verifies the signed event webhook
+	webhookKey string
 }
 
 type dbConfig struct {
@@ -102,7 +185,7 @@ func (app *application) mount() http.Handler {
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{env.GetString("CORS_ALLOWED_ORIGIN", "http://localhost:5174")},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
@@ -115,8 +198,9 @@ func (app *application) mount() http.Handler {
 
 	// Set a timeout value on the request context (ctx), that will signal
 	// through ctx.Done() that the request has timed out and further
-	// processing should be stopped.
-	r.Use(middleware.Timeout(60 * time.Second))
+	// processing should be stopped. Streaming requests and card exports
+	// stay open.
+	r.Use(app.timeoutMiddleware(60 * time.Second))
 
 	r.Route("/v1", func(r chi.Router) {
 		// Operations
@@ -130,20 +214,96 @@ func (app *application) mount() http.Handler {
 		r.Route("/cards", func(r chi.Router) {
 			r.Use(app.AuthTokenMiddleware)
 			r.Post("/create", app.createCardHandler)
+
+			r.Route("/{cardID}", func(r chi.Router) {
+				r.Use(app.cardsContextMiddleware)
+
+				r.Get("/", app.checkEventOwnership("admin", app.getCardHandler))
+				r.Post("/revoke", app.checkEventOwnership("admin", app.revokeCardHandler))
+				r.Post("/reissue", app.checkEventOwnership("admin", app.reissueCardHandler))
+			})
+		})
+
+		// card templates, personal to their owner, shared or system wide
+		r.Route("/card-templates", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getCardTemplatesHandler)
+			r.Post("/", app.createCardTemplateHandler)
+
+			r.Route("/{templateID}", func(r chi.Router) {
+				r.Use(app.cardTemplateContextMiddleware)
+				r.Get("/", app.getCardTemplateHandler)
+				r.Get("/image", app.getCardTemplateImageHandler)
+				r.Get("/thumbnail", app.getCardTemplateThumbnailHandler)
+
+				r.Patch("/", app.checkCardTemplateOwnership(app.updateCardTemplateHandler))
+				r.Put("/image", app.checkCardTemplateOwnership(app.replaceCardTemplateImageHandler))
+				r.Delete("/", app.checkCardTemplateOwnership(app.deleteCardTemplateHandler))
+			})
 		})
 
 		//events route
 		r.Route("/events", func(r chi.Router) {
-			r.Use(app.AuthTokenMiddleware)
-			r.Post("/create", app.createEventHandler)
-			r.Get("/", app.getAllEventsHandler)
+			// live feeds, opened with a stream ticket by browsers
+			r.Group(func(r chi.Router) {
+				r.Use(app.streamAuthMiddleware, app.eventsContextMiddleware)
+				r.Get("/{eventID}/checkins/stream", app.checkEventOwnership("moderator", app.checkInStreamHandler))
+				r.Get("/{eventID}/checkins/ws", app.checkEventOwnership("moderator", app.checkInWebSocketHandler))
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Post("/create", app.createEventHandler)
+				r.Get("/", app.getAllEventsHandler)
+
+				r.Route("/{eventID}", func(r chi.Router) {
+					r.Use(app.eventsContextMiddleware)
+					r.Get("/", app.getEventHandler)
+					r.Get("/verification-key", app.getEventVerificationKeyHandler)
+
+					r.Patch("/", app.checkEventOwnership("admin", app.updateEventHandler))
+					r.Delete("/", app.checkEventOwnership("admin", app.deleteEventHandler))
+
+					r.Post("/guests", app.checkEventOwnership("admin", app.createEventGuestsHandler))
+					r.Post("/guests/import", app.checkEventOwnership("admin", app.importGuestsHandler))
+					r.Get("/guests/export", app.checkEventOwnership("admin", app.exportGuestsHandler))
+
+					r.Get("/card-messages", app.checkEventOwnership("admin", app.getEventCardMessagesHandler))
 
-			r.Route("/{eventID}", func(r chi.Router) {
-				r.Use(app.eventsContextMiddleware)
-				r.Get("/", app.getEventHandler)
+					r.Post("/checkins", app.checkEventOwnership("moderator", app.createCheckInHandler))
+					r.Get("/checkins/snapshot", app.checkEventOwnership("moderator", app.getCheckInSnapshotHandler))
+					r.Post("/checkins/sync", app.checkEventOwnership("moderator", app.syncCheckInsHandler))
+					r.Post("/checkins/stream-ticket", app.checkEventOwnership("moderator", app.createStreamTicketHandler))
 
-				r.Patch("/", app.checkEventOwnership("admin", app.updateEventHandler))
-				r.Delete("/", app.checkEventOwnership("admin", app.deleteEventHandler))
+					r.Get("/cards", app.checkEventOwnership("admin", app.getEventCardsHandler))
+					r.Get("/cards/export", app.checkEventOwnership("admin", app.exportCardsHandler))
+
+					r.Route("/card-jobs", func(r chi.Router) {
+						r.Post("/", app.checkEventOwnership("admin", app.createCardJobHandler))
+						r.Get("/", app.checkEventOwnership("admin", app.getEventCardJobsHandler))
+
+						r.Route("/{jobID}", func(r chi.Router) {
+							r.Use(app.cardJobsContextMiddleware)
+
+							r.Get("/", app.checkEventOwnership("admin", app.getCardJobHandler))
+							r.Get("/items", app.checkEventOwnership("admin", app.getCardJobItemsHandler))
+						})
+					})
+
+					r.Route("/campaigns", func(r chi.Router) {
+						r.Post("/", app.checkEventOwnership("admin", app.createCampaignHandler))
+						r.Get("/", app.checkEventOwnership("admin", app.getEventCampaignsHandler))
+
+						r.Route("/{campaignID}", func(r chi.Router) {
+							r.Use(app.campaignsContextMiddleware)
+
+							r.Get("/", app.checkEventOwnership("admin", app.getCampaignHandler))
+							r.Delete("/", app.checkEventOwnership("admin", app.deleteCampaignHandler))
+							r.Get("/deliveries", app.checkEventOwnership("admin", app.getCampaignDeliveriesHandler))
+						})
+					})
+					r.Delete("/checkins/{guestID}", app.checkEventOwnership("admin", app.deleteCheckInHandler))
+				})
 			})
 		})
 
@@ -153,10 +313,94 @@ func (app *application) mount() http.Handler {
 			r.Get("/event/{eventID}", app.getEventGuestsHandler)
 
 			r.Route("/{guestID}", func(r chi.Router) {
+				r.Use(app.guestsContextMiddleware)
+
+				r.Get("/", app.checkEventOwnership("admin", app.getGuestHandler))
+				r.Patch("/", app.checkEventOwnership("admin", app.updateGuestHandler))
 				r.Delete("/", app.checkEventOwnership("admin", app.deleteGuestHandler))
+				r.Get("/card", app.checkEventOwnership("admin", app.renderGuestCardHandler))
+				r.Post("/card/send", app.checkEventOwnership("admin", app.sendGuestCardHandler))
+				r.Post("/card/email", app.checkEventOwnership("admin", app.emailGuestCardHandler))
+				r.Get("/card/messages", app.checkEventOwnership("admin", app.getGuestCardMessagesHandler))
+				r.Get("/rsvps", app.checkEventOwnership("admin", app.getGuestRSVPsHandler))
 			})
 		})
 
+		// status reports of the SMS and messenger providers, authenticated by
+		// signature. The sandbox providers' reports are unsigned and only
+		// accepted outside production.
+		if _, ok := app.sms.(*sms.HTTPClient); ok || app.config.env != "production" {
+			r.Post("/sms/status", app.smsStatusHandler)
+		}
+		if _, ok := app.messenger.(*messenger.HTTPClient); ok || app.config.env != "production" {
+			r.Post("/messages/status", app.messageStatusHandler)
+		}
+
+		// bounces and complaints reported by the mail provider
+		if app.mailEvents != nil {
+			r.Post("/mail/events", app.mailEventsHandler)
+		}
+
+		// signed download links of the local blob storage
+		if _, ok := app.blobs.(*blob.LocalStore); ok {
+			r.Get("/blobs/*", app.getBlobHandler)
+		}
+
+		// public RSVP links sent to guests
+		r.Route("/rsvp/{token}", func(r chi.Router) {
+			r.Use(app.rsvpContextMiddleware)
+
+			r.Get("/", app.getInvitationHandler)
+			r.Post("/", app.respondRSVPHandler)
+			r.Get("/card", app.getInvitationCardHandler)
+		})
+
+		// mails kept by the capture mailer, never served in production
+		if app.mailCapture != nil && app.config.env != "production" {
+			r.Route("/dev/mail", func(r chi.Router) {
+				r.Get("/", app.getCapturedMailsHandler)
+				r.Delete("/", app.clearCapturedMailsHandler)
+				r.Get("/{mailID}", app.getCapturedMailHandler)
+				r.Get("/{mailID}/html", app.getCapturedMailHTMLHandler)
+			})
+		}
+
+		// mail templates, overridden by admins without a deploy
+		r.Route("/mail-templates", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.checkRole("admin"))
+
+			r.Get("/", app.getMailTemplatesHandler)
+
+			r.Route("/{name}", func(r chi.Router) {
+				r.Get("/", app.getMailTemplateHandler)
+				r.Put("/", app.updateMailTemplateHandler)
+				r.Delete("/", app.deleteMailTemplateHandler)
+				r.Post("/validate", app.validateMailTemplateHandler)
+				r.Post("/preview", app.previewMailTemplateHandler)
+			})
+		})
+
+		// addresses no mail is sent to, for admins to lift suppressions
+		r.Route("/suppressions", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.checkRole("admin"))
+
+			r.Get("/", app.getSuppressionsHandler)
+			r.Delete("/{email}", app.deleteSuppressionHandler)
+		})
+
+		// mail outbox, for admins to inspect and re-drive failed mails
+		r.Route("/outbox", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.checkRole("admin"))
+
+			r.Get("/", app.getOutboxHandler)
+			r.Post("/redrive", app.redriveAllMailHandler)
+			r.Get("/{mailID}", app.getOutboxMailHandler)
+			r.Post("/{mailID}/redrive", app.redriveMailHandler)
+		})
+
 		// users route
 		r.Route("/users", func(r chi.Router) {
 			r.Put("/activate/{token}", app.activateUserHandler)
@@ -201,6 +445,11 @@ func (app *application) run(mux http.Handler) error {
 
 	shutdown := make(chan error)
 
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	app.startWorkers(workersCtx)
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 
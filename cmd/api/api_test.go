@@ -52,3 +52,44 @@ func TestRateLimiterMiddleware(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckProductionConfig(t *testing.T) {
+	valid := func() config {
+		return config{
+			env:       "production",
+			mail:      mailConfig{mailer: "sendgrid"},
+			sms:       smsConfig{provider: "http"},
+			messenger: messengerConfig{provider: "http"},
+			cards:     cardsConfig{storage: "local", urlSecret: "s3cr3t"},
+			auth:      authConfig{card: cardSigningConfig{secret: "s3cr3t"}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(cfg *config)
+		valid  bool
+	}{
+		{"providers set up", func(cfg *config) {}, true},
+		{"captured mail", func(cfg *config) { cfg.mail.mailer = "capture" }, false},
+		{"sms printed to stdout", func(cfg *config) { cfg.sms.provider = "stdout" }, false},
+		{"sms written to a file", func(cfg *config) { cfg.sms.provider = "file" }, false},
+		{"messages printed to stdout", func(cfg *config) { cfg.messenger.provider = "stdout" }, false},
+		{"default download link secret", func(cfg *config) { cfg.cards.urlSecret = devSecret }, false},
+		{"default card signing secret", func(cfg *config) { cfg.auth.card.secret = devSecret }, false},
+		{"no card signing secret", func(cfg *config) { cfg.auth.card.secret = "" }, false},
+		{"links signed by the bucket", func(cfg *config) { cfg.cards = cardsConfig{storage: "s3", urlSecret: devSecret} }, true},
+		{"sandboxes in development", func(cfg *config) { *cfg = config{env: "development", mail: mailConfig{mailer: "capture"}} }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(&cfg)
+
+			if err := checkProductionConfig(cfg); (err == nil) != tt.valid {
+				t.Errorf("expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
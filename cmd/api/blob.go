@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sikozonpc/social/internal/blob"
+)
+
+const (
+	// blobGracePeriod keeps new blobs from being swept before the card or
+	// template referring to them is saved.
+	blobGracePeriod = time.Hour
+	blobSweepBatch  = 500
+)
+
+// blobPrefixes are the prefixes of the blobs referred to by cards and
+// templates.
+var blobPrefixes = []string{"cards/", "templates/"}
+
+// getBlobHandler serves the signed download links of the local storage.
+// Links of the S3 storage point to the bucket.
+func (app *application) getBlobHandler(w http.ResponseWriter, r *http.Request) {
+	local, ok := app.blobs.(*blob.LocalStore)
+	if !ok {
+		app.notFoundResponse(w, r, errors.New("blob downloads are served by the storage"))
+		return
+	}
+
+	key := chi.URLParam(r, "*")
+	if err := local.Verify(key, r.URL.Query()); err != nil {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	app.serveBlob(w, r, key, "")
+}
+
+// serveBlob writes a stored blob, with contentType when the storage does
+// not know it.
+func (app *application) serveBlob(w http.ResponseWriter, r *http.Request, key, contentType string) {
+	content, info, err := app.blobs.Get(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, blob.ErrNotFound), errors.Is(err, blob.ErrInvalidKey):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	defer content.Close()
+
+	if info.ContentType != "" {
+		contentType = info.ContentType
+	}
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	// local blobs can seek, which ServeContent uses for range requests
+	if rs, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", info.ModTime, rs)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, content)
+}
+
+// signedBlobURL returns a download link of key, empty when there is no blob
+// or the link cannot be signed.
+func (app *application) signedBlobURL(ctx context.Context, key string) string {
+	if key == "" {
+		return ""
+	}
+
+	url, err := app.blobs.SignedURL(ctx, key, app.config.cards.urlExpiry)
+	if err != nil {
+		app.logger.Warnw("error signing blob url", "key", key, "error", err)
+		return ""
+	}
+
+	return url
+}
+
+// releaseBlobs deletes the blobs of keys no card or template refers to any
+// more. Blobs newer than blobGracePeriod are left to the sweep, as the same
+// content may just have been stored for a card or template not saved yet.
+// Failures are logged, the sweep deletes them later.
+func (app *application) releaseBlobs(ctx context.Context, keys ...string) {
+	cutoff := time.Now().Add(-blobGracePeriod)
+
+	var candidates []string
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		info, err := app.blobs.Stat(ctx, key)
+		if err != nil {
+			if !errors.Is(err, blob.ErrNotFound) {
+				app.logger.Warnw("error releasing blob", "key", key, "error", err)
+			}
+			continue
+		}
+
+		if info.ModTime.Before(cutoff) {
+			candidates = append(candidates, key)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return
+	}
+
+	if err := app.deleteUnusedBlobs(ctx, candidates); err != nil {
+		app.logger.Warnw("error releasing blobs", "keys", candidates, "error", err)
+	}
+}
+
+// cleanupBlobs deletes the cards and template images that nothing refers to,
+// e.g. the cards of deleted guests and events. Blobs newer than
+// blobGracePeriod are kept.
+func (app *application) cleanupBlobs(ctx context.Context) error {
+	cutoff := time.Now().Add(-blobGracePeriod)
+
+	var batch []string
+	for _, prefix := range blobPrefixes {
+		err := app.blobs.List(ctx, prefix, func(info blob.Info) error {
+			if info.ModTime.After(cutoff) {
+				return nil
+			}
+
+			batch = append(batch, info.Key)
+			if len(batch) < blobSweepBatch {
+				return nil
+			}
+
+			err := app.deleteUnusedBlobs(ctx, batch)
+			batch = batch[:0]
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	return app.deleteUnusedBlobs(ctx, batch)
+}
+
+func (app *application) deleteUnusedBlobs(ctx context.Context, keys []string) error {
+	used, err := app.store.Assets.InUse(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if used[key] {
+			continue
+		}
+
+		if err := app.blobs.Delete(ctx, key); err != nil {
+			return err
+		}
+
+		app.logger.Infow("orphaned blob deleted", "key", key)
+	}
+
+	return nil
+}
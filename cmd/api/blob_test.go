@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sikozonpc/social/internal/blob"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+func TestBlobDownload(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	ctx := context.Background()
+	key, err := blob.PutContent(ctx, app.blobs, "cards", []byte("png"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := app.signedBlobURL(ctx, key)
+
+	tests := []struct {
+		name     string
+		url      string
+		expected int
+	}{
+		{"signed", signed, http.StatusOK},
+		{"other key", strings.Replace(signed, key, "cards/00/other.png", 1), http.StatusForbidden},
+		{"unsigned", "/v1/blobs/" + key, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+
+			if rr.Code == http.StatusOK && rr.Header().Get("Content-Type") != "image/png" {
+				t.Errorf("unexpected content type %q", rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestCleanupBlobs(t *testing.T) {
+	dir := t.TempDir()
+	app := newTestApplication(t, config{cards: cardsConfig{assetsDir: dir}})
+
+	ctx := context.Background()
+
+	put := func(content string, age time.Duration) string {
+		key, err := blob.PutContent(ctx, app.blobs, "cards", []byte(content), "image/png")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		modTime := time.Now().Add(-age)
+		if err := os.Chtimes(filepath.Join(dir, filepath.FromSlash(key)), modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+
+		return key
+	}
+
+	used := put("used", 2*blobGracePeriod)
+	orphaned := put("orphaned", 2*blobGracePeriod)
+	recent := put("recent", 0)
+
+	app.store.Assets.(*store.MockAssetStore).Used = map[string]bool{used: true}
+
+	if err := app.cleanupBlobs(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, kept := range map[string]bool{used: true, orphaned: false, recent: true} {
+		_, err := app.blobs.Stat(ctx, key)
+		if kept && err != nil {
+			t.Errorf("expected %s to be kept, got %v", key, err)
+		}
+
+		if !kept && err != blob.ErrNotFound {
+			t.Errorf("expected %s to be deleted, got %v", key, err)
+		}
+	}
+}
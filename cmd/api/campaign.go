@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+type campaignKey string
+
+const campaignCtx campaignKey = "campaign"
+
+// campaignSchedules are the named schedules a campaign can be created with.
+var campaignSchedules = map[string]struct {
+	daysBefore int
+	atTime     string
+}{
+	"week_before": {7, ""},
+	"day_before":  {1, ""},
+	"morning_of":  {0, "08:00"},
+}
+
+type CreateCampaignPayload struct {
+	Name    string `json:"name" validate:"required,max=100"`
+	Channel string `json:"channel" validate:"required,oneof=email sms"`
+	// Schedule picks a named schedule, otherwise DaysBefore (and optionally
+	// AtTime) must be given.
+	Schedule   string   `json:"schedule" validate:"omitempty,oneof=week_before day_before morning_of"`
+	DaysBefore *int     `json:"days_before" validate:"required_without=Schedule,omitempty,min=0,max=365"`
+	AtTime     string   `json:"at_time" validate:"omitempty,datetime=15:04"`
+	Statuses   []string `json:"statuses" validate:"max=5,dive,oneof=pending accepted declined maybe no_response"`
+	Types      []string `json:"types" validate:"max=3,dive,oneof=single double vip"`
+}
+
+func (app *application) createCampaignHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+
+	var payload CreateCampaignPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	campaign := &store.Campaign{
+		EventID:  event.ID,
+		Name:     payload.Name,
+		Channel:  payload.Channel,
+		AtTime:   payload.AtTime,
+		Statuses: payload.Statuses,
+		Types:    payload.Types,
+	}
+
+	if schedule, ok := campaignSchedules[payload.Schedule]; ok {
+		campaign.DaysBefore = schedule.daysBefore
+		if campaign.AtTime == "" {
+			campaign.AtTime = schedule.atTime
+		}
+	}
+	if payload.DaysBefore != nil {
+		campaign.DaysBefore = *payload.DaysBefore
+	}
+
+	if campaign.Statuses == nil {
+		campaign.Statuses = []string{}
+	}
+	if campaign.Types == nil {
+		campaign.Types = []string{}
+	}
+
+	if err := app.store.Campaigns.Create(r.Context(), campaign); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, campaign); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) getEventCampaignsHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+
+	campaigns, err := app.store.Campaigns.GetByEvent(r.Context(), event.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, campaigns); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) getCampaignHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, getCampaignFromCtx(r)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getCampaignDeliveriesHandler returns the delivery state of every guest
+// the campaign was sent to.
+func (app *application) getCampaignDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	campaign := getCampaignFromCtx(r)
+
+	deliveries, err := app.store.Campaigns.GetDeliveries(r.Context(), campaign.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, deliveries); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteCampaignHandler deletes a campaign, stopping any delivery not made
+// yet.
+func (app *application) deleteCampaignHandler(w http.ResponseWriter, r *http.Request) {
+	campaign := getCampaignFromCtx(r)
+
+	if err := app.store.Campaigns.Delete(r.Context(), campaign.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// campaignsContextMiddleware loads a campaign of the event in context.
+func (app *application) campaignsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := getEventFromCtx(r)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "campaignID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		campaign, err := app.store.Campaigns.GetByID(ctx, id)
+		if err == nil && campaign.EventID != event.ID {
+			err = fmt.Errorf("campaign %d of event %d: %w", id, event.ID, store.ErrNotFound)
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, campaignCtx, campaign)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getCampaignFromCtx(r *http.Request) *store.Campaign {
+	campaign, _ := r.Context().Value(campaignCtx).(*store.Campaign)
+	return campaign
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sikozonpc/social/internal/mailer"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+func TestCreateCampaign(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+		contains string
+	}{
+		{"named schedule", `{"name": "Morning of", "channel": "sms", "schedule": "morning_of"}`, http.StatusCreated, `"at_time":"08:00"`},
+		{"custom schedule", `{"name": "Three days", "channel": "email", "days_before": 3, "statuses": ["accepted", "maybe"]}`, http.StatusCreated, `"days_before":3`},
+		{"without schedule", `{"name": "Reminder", "channel": "email"}`, http.StatusBadRequest, "DaysBefore"},
+		{"unknown channel", `{"name": "Reminder", "channel": "fax", "schedule": "day_before"}`, http.StatusBadRequest, "Channel"},
+		{"unknown status", `{"name": "Reminder", "channel": "email", "schedule": "day_before", "statuses": ["gone"]}`, http.StatusBadRequest, "Statuses"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/v1/events/1/campaigns", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+
+			if !strings.Contains(rr.Body.String(), tt.contains) {
+				t.Errorf("expected response to contain %q, got %s", tt.contains, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestCampaignDeliveries(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]int{
+		"/v1/events/1/campaigns/1/deliveries": http.StatusOK,
+		"/v1/events/2/campaigns/1/deliveries": http.StatusNotFound,
+		"/v1/events/1/campaigns/9/deliveries": http.StatusNotFound,
+	} {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, expected, rr.Code)
+	}
+
+	t.Run("should dispatch due reminders", func(t *testing.T) {
+		if err := app.dispatchReminders(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if sent := app.mailer.(*mailer.MockClient).Sent; len(sent) != 0 {
+			t.Fatalf("expected the reminder to go through the outbox, got %+v", sent)
+		}
+
+		queued := app.store.Outbox.(*store.MockOutboxStore).Queued
+		if len(queued) != 1 || queued[0].Template != mailer.ReminderTemplate {
+			t.Fatalf("expected one reminder mail to be queued, got %+v", queued)
+		}
+
+		msg, err := decodeOutboxMail(&queued[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data := msg.Data.(map[string]any)
+		if data["EventName"] != "Harusi" || !strings.HasSuffix(data["RSVPURL"].(string), "/rsvp/rsvp-token") {
+			t.Errorf("unexpected reminder data %+v", data)
+		}
+	})
+}
+
+func TestReminderWhen(t *testing.T) {
+	// 21:30 in UTC is already the next day in Dar es Salaam
+	now := time.Date(2024, 12, 14, 21, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		date     string
+		timezone string
+		expected string
+	}{
+		{"later today", "2024-12-14T23:00:00Z", "", "today"},
+		{"tomorrow", "2024-12-15T18:00:00Z", "UTC", "tomorrow"},
+		{"tomorrow is today in the event's timezone", "2024-12-15T18:00:00Z", "Africa/Dar_es_Salaam", "today"},
+		{"a week away", "2024-12-21T18:00:00Z", "UTC", "in one week"},
+		{"a week before, sent late", "2024-12-16T18:00:00Z", "UTC", "in 2 days"},
+		{"already started", "2024-12-14T18:00:00Z", "UTC", "today"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &store.Event{Date: tt.date, Timezone: tt.timezone}
+			if when := reminderWhen(event, now); when != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, when)
+			}
+		})
+	}
+}
+
+func TestSMSStatus(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"delivered", `{"id": "sandbox-1", "status": "delivered"}`, http.StatusNoContent},
+		{"failed", `{"id": "sandbox-1", "status": "failed", "error": "unreachable"}`, http.StatusNoContent},
+		{"unknown message", `{"id": "other", "status": "delivered"}`, http.StatusNotFound},
+		{"unknown status", `{"id": "sandbox-1", "status": "lost"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/v1/sms/status", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+		})
+	}
+
+	t.Run("should not take unsigned reports in production", func(t *testing.T) {
+		mux := newTestApplication(t, config{env: "production"}).mount()
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/sms/status", strings.NewReader(tests[0].body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sikozonpc/social/internal/mailer"
+	"github.com/sikozonpc/social/internal/render"
+	"github.com/sikozonpc/social/internal/sms"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+const (
+	maxDeliveryAttempts = 5
+	deliveryBatchSize   = 100
+	// deliveryLease is how long a claimed delivery is left to its worker
+	// before it is handed out again.
+	deliveryLease = 5 * time.Minute
+)
+
+// reminderSender delivers campaign reminders over one channel. It returns
+// the provider's message ID when the channel reports delivery statuses.
+type reminderSender interface {
+	SendReminder(ctx context.Context, delivery *store.CampaignDelivery, data mailer.ReminderData) (string, error)
+}
+
+// mailReminderSender hands reminders to the outbox, which retries them and
+// skips suppressed addresses like any other mail. A queued reminder counts
+// as sent.
+type mailReminderSender struct {
+	queue func(context.Context, *sql.Tx, *mailer.Message) (*store.OutboxMail, error)
+}
+
+func (s mailReminderSender) SendReminder(ctx context.Context, delivery *store.CampaignDelivery, data mailer.ReminderData) (string, error) {
+	_, err := s.queue(ctx, nil, &mailer.Message{
+		Template: mailer.ReminderTemplate,
+		Username: delivery.GuestName,
+		Email:    delivery.Recipient,
+		Data:     data,
+	})
+	return "", err
+}
+
+type smsReminderSender struct {
+	client    sms.Client
+	isSandbox bool
+}
+
+func (s smsReminderSender) SendReminder(ctx context.Context, delivery *store.CampaignDelivery, data mailer.ReminderData) (string, error) {
+	return s.client.Send(sms.EventReminderTemplate, delivery.Recipient, data, s.isSandbox)
+}
+
+// reminderSenders returns the sender of every campaign channel.
+func (app *application) reminderSenders() map[string]reminderSender {
+	isSandbox := app.config.env != "production"
+
+	return map[string]reminderSender{
+		store.ChannelEmail: mailReminderSender{queue: app.queueMail},
+		store.ChannelSMS:   smsReminderSender{client: app.sms, isSandbox: isSandbox},
+	}
+}
+
+// dispatchReminders starts the campaigns that are due and delivers a batch
+// of pending reminders. Failed deliveries are retried with an exponential
+// backoff until maxDeliveryAttempts.
+func (app *application) dispatchReminders(ctx context.Context) error {
+	campaigns, err := app.store.Campaigns.GetDue(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, campaign := range campaigns {
+		queued, err := app.store.Campaigns.Enqueue(ctx, campaign.ID)
+		if err != nil {
+			if !errors.Is(err, store.ErrNotFound) {
+				app.logger.Errorw("error starting campaign", "campaign", campaign.ID, "error", err)
+			}
+			continue
+		}
+
+		app.logger.Infow("campaign started", "campaign", campaign.ID, "deliveries", queued)
+	}
+
+	deliveries, err := app.store.Campaigns.ClaimDeliveries(ctx, deliveryBatchSize, deliveryLease)
+	if err != nil {
+		return err
+	}
+
+	senders := app.reminderSenders()
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		sender, ok := senders[delivery.Channel]
+		if !ok {
+			app.failDelivery(ctx, delivery, fmt.Errorf("no sender for channel %q", delivery.Channel), false)
+			continue
+		}
+
+		providerID, err := sender.SendReminder(ctx, delivery, app.reminderData(delivery))
+		if err != nil {
+			// a number that cannot be normalised will not get better
+			retry := delivery.Attempts < maxDeliveryAttempts &&
+				!errors.Is(err, sms.ErrInvalidPhoneNumber)
+			app.failDelivery(ctx, delivery, err, retry)
+			continue
+		}
+
+		if err := app.store.Campaigns.MarkSent(ctx, delivery.ID, providerID); err != nil {
+			app.logger.Errorw("error marking delivery sent", "delivery", delivery.ID, "error", err)
+		}
+	}
+
+	return app.store.Campaigns.Complete(ctx)
+}
+
+func (app *application) failDelivery(ctx context.Context, delivery *store.CampaignDelivery, reason error, retry bool) {
+	app.logger.Warnw("reminder delivery failed", "delivery", delivery.ID, "attempt", delivery.Attempts, "retry", retry, "error", reason)
+
+	var retryAt time.Time
+	if retry {
+		retryAt = time.Now().Add(time.Minute << (delivery.Attempts - 1))
+	}
+
+	if err := app.store.Campaigns.MarkFailed(ctx, delivery.ID, reason.Error(), retryAt); err != nil {
+		app.logger.Errorw("error marking delivery failed", "delivery", delivery.ID, "error", err)
+	}
+}
+
+func (app *application) reminderData(delivery *store.CampaignDelivery) mailer.ReminderData {
+	return mailer.ReminderData{
+		GuestName: delivery.GuestName,
+		EventName: delivery.Event.Name,
+		Date:      render.FormatDate(delivery.Event.Date, ""),
+		Location:  delivery.Event.Location,
+		When:      reminderWhen(&delivery.Event, time.Now()),
+		RSVPURL:   fmt.Sprintf("%s/rsvp/%s", app.config.frontendURL, delivery.RSVPToken),
+	}
+}
+
+// reminderWhen tells how far the event is from now in calendar days of the
+// event's timezone, as reminders can go out later than scheduled, e.g. for
+// campaigns created close to the event.
+func reminderWhen(event *store.Event, now time.Time) string {
+	date, err := time.Parse(time.RFC3339, event.Date)
+	if err != nil {
+		return "soon"
+	}
+
+	loc := eventLocation(event)
+
+	day := func(t time.Time) time.Time {
+		y, m, d := t.In(loc).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	switch days := int(day(date).Sub(day(now)).Hours() / 24); {
+	case days <= 0:
+		return "today"
+	case days == 1:
+		return "tomorrow"
+	case days == 7:
+		return "in one week"
+	default:
+		return fmt.Sprintf("in %d days", days)
+	}
+}
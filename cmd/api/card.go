@@ -1,44 +1,393 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"mime"
 	"net/http"
+	"os"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/sikozonpc/social/internal/auth"
+	"github.com/sikozonpc/social/internal/blob"
+	"github.com/sikozonpc/social/internal/render"
 	"github.com/sikozonpc/social/internal/store"
 )
 
+type cardKey string
+
+const cardCtx cardKey = "card"
+
+var errNoCardTemplate = errors.New("no card template selected for this event")
+
 type CreateCardPayload struct {
-	EventID        int64  `json:"event_id" validate:"required"`
-	ImagePath      string `json:"name"`
-	GuestID        int64  `json:"guest_id"`
-	CardTemplateID int64
+	EventID        int64 `json:"event_id" validate:"required"`
+	GuestID        int64 `json:"guest_id" validate:"required"`
+	CardTemplateID int64 `json:"card_template_id"`
 }
 
+// createCardHandler renders the guest's card from the selected template (or
+// the event's template) and stores the resulting image.
 func (app *application) createCardHandler(w http.ResponseWriter, r *http.Request) {
 	var payload CreateCardPayload
 	if err := readJSON(w, r, &payload); err != nil {
 		app.badRequestResponse(w, r, err)
+		return
 	}
 
 	if err := Validate.Struct(payload); err != nil {
 		app.badRequestResponse(w, r, err)
+		return
 	}
 
-	card := &store.Card{
-		EventID:        payload.EventID,
-		GuestID:        payload.GuestID,
-		CardTemplateID: payload.CardTemplateID,
-		ImagePath:      payload.ImagePath,
+	ctx := r.Context()
+
+	guest, err := app.store.Guests.GetByID(ctx, payload.GuestID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if guest.EventID != payload.EventID {
+		app.badRequestResponse(w, r, fmt.Errorf("guest %d is not invited to event %d", guest.ID, payload.EventID))
+		return
+	}
+
+	event, err := app.store.Events.GetByID(ctx, guest.EventID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	allowed, err := app.canManageEvent(ctx, user, event, "admin")
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !allowed {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	var tmpl *store.CardTemplate
+	if payload.CardTemplateID != 0 {
+		tmpl, err = app.availableCardTemplate(ctx, user, payload.CardTemplateID)
+		if err != nil {
+			app.eventCardTemplateError(w, r, err)
+			return
+		}
+	} else {
+		tmpl, err = app.cardTemplate(ctx, event, 0)
+		if err != nil {
+			app.cardRenderError(w, r, err)
+			return
+		}
+	}
+
+	card, err := app.newCard(ctx, event, guest, tmpl, user.ID)
+	if err != nil {
+		app.cardRenderError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, card); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// renderGuestCardHandler renders the guest's card on the fly as a png, or a
+// printable pdf with ?format=pdf. A different template can be previewed with
+// ?template_id=.
+func (app *application) renderGuestCardHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+	guest := getGuestFromCtx(r)
+
+	var templateID int64
+	if v := r.URL.Query().Get("template_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		templateID = id
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "png" && format != "pdf" {
+		app.badRequestResponse(w, r, fmt.Errorf("unsupported card format %q", format))
+		return
 	}
 
 	ctx := r.Context()
 
+	var tmpl *store.CardTemplate
+	var err error
+	if templateID != 0 {
+		tmpl, err = app.availableCardTemplate(ctx, getUserFromContext(r), templateID)
+		if err != nil {
+			app.eventCardTemplateError(w, r, err)
+			return
+		}
+	} else {
+		tmpl, err = app.cardTemplate(ctx, event, 0)
+		if err != nil {
+			app.cardRenderError(w, r, err)
+			return
+		}
+	}
+
+	data := render.NewData(event, guest)
+	if guest.CardID != 0 {
+		data.QRCode, err = app.cardSigner.Sign(auth.CardClaims{
+			CardID:  guest.CardID,
+			GuestID: guest.ID,
+			EventID: event.ID,
+		})
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	img, err := app.renderer.Render(tmpl, data)
+	if err != nil {
+		app.cardRenderError(w, r, err)
+		return
+	}
+
+	// encode first so a failure can still be reported as json
+	var buf bytes.Buffer
+	contentType := "image/png"
+	if format == "pdf" {
+		contentType = "application/pdf"
+		err = render.EncodePDF(&buf, img)
+	} else {
+		err = render.EncodePNG(&buf, img)
+	}
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// cardTemplate loads templateID, falling back to the template selected on
+// the event when it is zero.
+func (app *application) cardTemplate(ctx context.Context, event *store.Event, templateID int64) (*store.CardTemplate, error) {
+	if templateID == 0 && event.CardTemplateID != "" {
+		id, err := strconv.ParseInt(event.CardTemplateID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		templateID = id
+	}
+
+	if templateID == 0 {
+		return nil, errNoCardTemplate
+	}
+
+	return app.store.CardTemplates.GetByID(ctx, templateID)
+}
+
+// newCard issues guest a new card. It only replaces their current card, which
+// is revoked on behalf of userID, once it has been rendered.
+func (app *application) newCard(ctx context.Context, event *store.Event, guest *store.Guest, tmpl *store.CardTemplate, userID int64) (*store.Card, error) {
+	// the card is created first as its ID is part of the signed QR code
+	card := &store.Card{
+		EventID:        event.ID,
+		GuestID:        guest.ID,
+		CardTemplateID: tmpl.ID,
+	}
+
 	if err := app.store.Cards.Create(ctx, card); err != nil {
+		return nil, err
+	}
+
+	if err := app.issueCard(ctx, event, guest, tmpl, card); err != nil {
+		app.discardCard(ctx, card)
+		return nil, err
+	}
+
+	if err := app.store.Cards.SetCurrent(ctx, card, userID); err != nil {
+		app.discardCard(ctx, card)
+		app.releaseBlobs(ctx, card.ImagePath)
+		return nil, err
+	}
+
+	return card, nil
+}
+
+// discardCard deletes a card that could not be issued.
+func (app *application) discardCard(ctx context.Context, card *store.Card) {
+	if err := app.store.Cards.Delete(ctx, card.ID); err != nil {
+		app.logger.Errorw("error deleting card", "card", card.ID, "error", err)
+	}
+}
+
+// issueCard signs the QR code of a created card, renders it and stores the
+// image on the card. The image of a card rendered before is released.
+func (app *application) issueCard(ctx context.Context, event *store.Event, guest *store.Guest, tmpl *store.CardTemplate, card *store.Card) error {
+	previous := card.ImagePath
+
+	code, err := app.cardSigner.Sign(auth.CardClaims{
+		CardID:  card.ID,
+		GuestID: guest.ID,
+		EventID: event.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	data := render.NewData(event, guest)
+	data.QRCode = code
+
+	img, err := app.renderer.Render(tmpl, data)
+	if err != nil {
+		return err
+	}
+
+	key, err := app.saveCardImage(ctx, img)
+	if err != nil {
+		return err
+	}
+
+	card.ImagePath = key
+	card.CardTemplateID = tmpl.ID
+	card.Fingerprint = render.Fingerprint(tmpl, data)
+
+	if err := app.store.Cards.Update(ctx, nil, card); err != nil {
+		card.ImagePath = previous
+		app.releaseBlobs(ctx, key)
+		return err
+	}
+
+	if previous != "" && previous != key {
+		app.releaseBlobs(ctx, previous)
+	}
+
+	card.Code = code
+	return nil
+}
+
+func (app *application) cardRenderError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errNoCardTemplate), errors.Is(err, render.ErrEmptyCanvas), errors.Is(err, render.ErrCanvasSize):
+		app.badRequestResponse(w, r, err)
+	case errors.Is(err, store.ErrNotFound), errors.Is(err, os.ErrNotExist), errors.Is(err, blob.ErrNotFound):
+		app.notFoundResponse(w, r, err)
+	default:
 		app.internalServerError(w, r, err)
+	}
+}
+
+// saveCardImage stores the rendered card and returns its key.
+func (app *application) saveCardImage(ctx context.Context, img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := render.EncodePNG(&buf, img); err != nil {
+		return "", err
+	}
+
+	return blob.PutContent(ctx, app.blobs, "cards", buf.Bytes(), "image/png")
+}
+
+// getEventCardsHandler lists the current card of the event's guests, with
+// ?search= on guest names and phone numbers.
+func (app *application) getEventCardsHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+
+	fq := store.PaginatedFeedQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	fq, err := fq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusCreated, card); err != nil {
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	cards, err := app.store.Cards.GetCards(r.Context(), event.ID, fq)
+	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+
+	if err := app.jsonResponse(w, http.StatusOK, cards); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getCardHandler streams the image of a card, as an attachment named after
+// the guest with ?download=true.
+func (app *application) getCardHandler(w http.ResponseWriter, r *http.Request) {
+	card := getCardFromCtx(r)
+
+	if download, _ := strconv.ParseBool(r.URL.Query().Get("download")); download {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": cardFileName(card) + ".png",
+		}))
+	}
+
+	app.serveBlob(w, r, card.ImagePath, "image/png")
+}
+
+// cardsContextMiddleware loads a card and its event in context.
+func (app *application) cardsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "cardID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		card, err := app.store.Cards.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		event, err := app.store.Events.GetByID(ctx, card.EventID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, cardCtx, card)
+		ctx = context.WithValue(ctx, eventCtx, event)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getCardFromCtx(r *http.Request) *store.Card {
+	card, _ := r.Context().Value(cardCtx).(*store.Card)
+	return card
 }
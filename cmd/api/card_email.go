@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"path"
+
+	"github.com/sikozonpc/social/internal/mailer"
+)
+
+var errNoEmail = errors.New("the guest has no email address")
+
+// cardContentID names the card image embedded in invitation mails.
+const cardContentID = "card"
+
+// emailGuestCardHandler queues a mail of the guest's issued card, shown
+// inline and attached, with their RSVP link. Answers go to the event's host.
+func (app *application) emailGuestCardHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+	guest := getGuestFromCtx(r)
+
+	if guest.CardID == 0 {
+		app.conflictResponse(w, r, errCardNotIssued)
+		return
+	}
+
+	if guest.Email == "" {
+		app.badRequestResponse(w, r, errNoEmail)
+		return
+	}
+
+	ctx := r.Context()
+
+	card, image, err := app.guestCardImage(ctx, guest)
+	if err != nil {
+		app.cardRenderError(w, r, err)
+		return
+	}
+
+	host, err := app.store.Users.GetByID(ctx, event.UserID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	data := app.cardInvitationData(event, guest)
+	data.CardCID = cardContentID
+
+	filename := path.Base(card.ImagePath)
+	msg := &mailer.Message{
+		Template: mailer.GuestInvitationTemplate,
+		Username: guest.Name,
+		Email:    guest.Email,
+		Data:     data,
+		ReplyTo:  host.Email,
+		Attachments: []mailer.Attachment{
+			{Filename: filename, ContentType: "image/png", Content: image, Inline: true, ContentID: cardContentID},
+			{Filename: filename, ContentType: "image/png", Content: image},
+		},
+	}
+
+	mail, err := app.queueMail(ctx, nil, msg)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, mail); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sikozonpc/social/internal/blob"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+const (
+	maxCardFileName = 100
+	// cardExportWriteTimeout bounds the write of each card rather than the
+	// whole bundle, which can take minutes for large events.
+	cardExportWriteTimeout = 30 * time.Second
+)
+
+// exportCardsHandler streams a zip of the current card of every guest of the
+// event matching the optional ?search= filter, one png per guest named after
+// them. It is exempt from the request timeout, see timeoutMiddleware.
+func (app *application) exportCardsHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+
+	search := r.URL.Query().Get("search")
+	if len(search) > 100 {
+		app.badRequestResponse(w, r, fmt.Errorf("search must be at most 100 characters"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="event-%d-cards.zip"`, event.ID))
+
+	ctx := r.Context()
+
+	rc := http.NewResponseController(w)
+	zw := zip.NewWriter(w)
+	names := map[string]int{}
+
+	err := app.store.Cards.ForEach(ctx, event.ID, search, func(card *store.Card) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(cardExportWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+
+		content, info, err := app.blobs.Get(ctx, card.ImagePath)
+		if err != nil {
+			// a card whose image is gone is left out rather than failing
+			// the whole bundle
+			if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+				app.logger.Warnw("card image missing from export", "card", card.ID, "path", card.ImagePath)
+				return nil
+			}
+			return err
+		}
+		defer content.Close()
+
+		name := cardFileName(card)
+		names[name]++
+		if n := names[name]; n > 1 {
+			name = fmt.Sprintf("%s (%d)", name, n)
+		}
+
+		// png is compressed already
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name + ".png",
+			Method:   zip.Store,
+			Modified: info.ModTime,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(f, content)
+		return err
+	})
+	if err != nil {
+		app.logger.Errorw("error writing card export", "event", event.ID, "error", err)
+		return
+	}
+
+	if err := zw.Close(); err != nil {
+		app.logger.Errorw("error writing card export", "event", event.ID, "error", err)
+	}
+}
+
+// cardFileName derives a file name without extension from the guest's
+// name, e.g. "Neema & Baraka" becomes "Neema _ Baraka".
+func cardFileName(card *store.Card) string {
+	var name string
+	if card.Guest != nil {
+		name = card.Guest.Name
+	}
+
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == ' ', r == '-', r == '.', r == '\'':
+			return r
+		default:
+			return '_'
+		}
+	}, strings.TrimSpace(name))
+
+	if runes := []rune(name); len(runes) > maxCardFileName {
+		name = string(runes[:maxCardFileName])
+	}
+
+	name = strings.Trim(name, ". ")
+	if name == "" {
+		return fmt.Sprintf("guest-%d", card.GuestID)
+	}
+
+	return name
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sikozonpc/social/internal/blob"
+	"github.com/sikozonpc/social/internal/render"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+const (
+	cardJobBatchSize = 50
+	// cardJobLease is how long a claimed guest is left to its worker before
+	// it is handed out again.
+	cardJobLease = 5 * time.Minute
+)
+
+type cardJobKey string
+
+const cardJobCtx cardJobKey = "cardJob"
+
+var errCardJobRunning = errors.New("a card job is already running for this event")
+
+type CreateCardJobPayload struct {
+	// CardTemplateID defaults to the template of the event.
+	CardTemplateID int64    `json:"card_template_id"`
+	GuestIDs       []int64  `json:"guest_ids" validate:"max=5000"`
+	Search         string   `json:"search" validate:"max=100"`
+	Statuses       []string `json:"statuses" validate:"max=5,dive,oneof=pending accepted declined maybe no_response"`
+	Types          []string `json:"types" validate:"max=3,dive,oneof=single double vip"`
+	// Force renders the cards that are up to date again.
+	Force bool `json:"force"`
+}
+
+// createCardJobHandler starts generating the cards of the event's guests,
+// or of those matching the payload's filters. The cards are rendered in the
+// background, the job reports the progress.
+func (app *application) createCardJobHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+	user := getUserFromContext(r)
+
+	var payload CreateCardJobPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	templateID := payload.CardTemplateID
+	if templateID != 0 {
+		if err := app.checkCardTemplateAccess(ctx, user, strconv.FormatInt(templateID, 10)); err != nil {
+			app.eventCardTemplateError(w, r, err)
+			return
+		}
+	} else {
+		// the job keeps the template the event has now
+		tmpl, err := app.cardTemplate(ctx, event, 0)
+		if err != nil {
+			app.cardRenderError(w, r, err)
+			return
+		}
+		templateID = tmpl.ID
+	}
+
+	job := &store.CardJob{
+		EventID:        event.ID,
+		UserID:         user.ID,
+		CardTemplateID: templateID,
+		GuestIDs:       payload.GuestIDs,
+		Search:         payload.Search,
+		Statuses:       payload.Statuses,
+		Types:          payload.Types,
+		Force:          payload.Force,
+	}
+
+	if job.GuestIDs == nil {
+		job.GuestIDs = []int64{}
+	}
+	if job.Statuses == nil {
+		job.Statuses = []string{}
+	}
+	if job.Types == nil {
+		job.Types = []string{}
+	}
+
+	if err := app.store.CardJobs.Create(ctx, job); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errCardJobRunning)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, job); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) getEventCardJobsHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+
+	jobs, err := app.store.CardJobs.GetByEvent(r.Context(), event.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, jobs); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getCardJobHandler reports the progress of a job.
+func (app *application) getCardJobHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, getCardJobFromCtx(r)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getCardJobItemsHandler returns the card of every guest of the job,
+// narrowed with ?status=, e.g. to the failed ones.
+func (app *application) getCardJobItemsHandler(w http.ResponseWriter, r *http.Request) {
+	job := getCardJobFromCtx(r)
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", store.CardItemPending, store.CardItemRendered, store.CardItemSkipped, store.CardItemFailed:
+	default:
+		app.badRequestResponse(w, r, errors.New("status must be pending, rendered, skipped or failed"))
+		return
+	}
+
+	items, err := app.store.CardJobs.GetItems(r.Context(), job.ID, status)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, items); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// generateCards renders the cards of the pending guests of every running job
+// with a pool of app.config.cards.workers workers, until none are left.
+// Guests being rendered when the API stops are claimed again once their
+// lease runs out, which makes jobs resume after a crash.
+func (app *application) generateCards(ctx context.Context) error {
+	workers := app.config.cards.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	for ctx.Err() == nil {
+		items, err := app.store.CardJobs.ClaimItems(ctx, cardJobBatchSize, cardJobLease)
+		if err != nil {
+			return err
+		}
+
+		if len(items) == 0 {
+			break
+		}
+
+		queue := make(chan *store.CardJobItem)
+
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for item := range queue {
+					app.generateJobCard(ctx, item)
+				}
+			}()
+		}
+
+		for i := range items {
+			queue <- &items[i]
+		}
+
+		close(queue)
+		wg.Wait()
+	}
+
+	return app.store.CardJobs.Complete(ctx)
+}
+
+// generateJobCard renders the card of a job's guest and records the outcome.
+// Failures that rendering again will not fix are not retried.
+func (app *application) generateJobCard(ctx context.Context, item *store.CardJobItem) {
+	status, cardID, err := app.generateCard(ctx, item)
+	if err == nil {
+		if err := app.store.CardJobs.MarkDone(ctx, item.ID, status, cardID); err != nil {
+			app.logger.Errorw("error marking card done", "job", item.JobID, "guest", item.GuestID, "error", err)
+		}
+		return
+	}
+
+	permanent := errors.Is(err, errNoCardTemplate) ||
+		errors.Is(err, render.ErrEmptyCanvas) ||
+		errors.Is(err, render.ErrCanvasSize) ||
+		errors.Is(err, store.ErrNotFound) ||
+		errors.Is(err, blob.ErrNotFound)
+
+	retry := item.Attempts < store.MaxCardAttempts && !permanent
+	app.logger.Warnw("card generation failed", "job", item.JobID, "guest", item.GuestID, "attempt", item.Attempts, "retry", retry, "error", err)
+
+	var retryAt time.Time
+	if retry {
+		retryAt = time.Now().Add(10 * time.Second << (item.Attempts - 1))
+	}
+
+	if err := app.store.CardJobs.MarkFailed(ctx, item.ID, err.Error(), retryAt); err != nil {
+		app.logger.Errorw("error marking card failed", "job", item.JobID, "guest", item.GuestID, "error", err)
+	}
+}
+
+// generateCard renders the card of a guest unless the one they have is up to
+// date. The guest's card is rendered again in place, keeping its QR code,
+// and created when they have none or it was revoked.
+func (app *application) generateCard(ctx context.Context, item *store.CardJobItem) (string, int64, error) {
+	event, err := app.store.Events.GetByID(ctx, item.EventID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	guest, err := app.store.Guests.GetByID(ctx, item.GuestID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	tmpl, err := app.cardTemplate(ctx, event, item.CardTemplateID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var card *store.Card
+	if guest.CardID != 0 {
+		card, err = app.store.Cards.GetByID(ctx, guest.CardID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return "", 0, err
+		}
+
+		// a revoked card keeps its code, the guest gets a new one instead
+		if card != nil && card.RevokedAt != "" {
+			card = nil
+		}
+	}
+
+	if card != nil && !item.Force && app.cardUpToDate(ctx, card, tmpl, render.NewData(event, guest)) {
+		return store.CardItemSkipped, card.ID, nil
+	}
+
+	if card != nil {
+		if err := app.issueCard(ctx, event, guest, tmpl, card); err != nil {
+			return "", 0, err
+		}
+		return store.CardItemRendered, card.ID, nil
+	}
+
+	card, err = app.newCard(ctx, event, guest, tmpl, item.UserID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return store.CardItemRendered, card.ID, nil
+}
+
+// cardUpToDate reports whether card was rendered from tmpl and data and its
+// image is still stored.
+func (app *application) cardUpToDate(ctx context.Context, card *store.Card, tmpl *store.CardTemplate, data render.Data) bool {
+	if card.ImagePath == "" || card.Fingerprint != render.Fingerprint(tmpl, data) {
+		return false
+	}
+
+	_, err := app.blobs.Stat(ctx, card.ImagePath)
+	return err == nil
+}
+
+// cardJobsContextMiddleware loads a card job of the event in context.
+func (app *application) cardJobsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := getEventFromCtx(r)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "jobID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		job, err := app.store.CardJobs.GetByID(ctx, id)
+		if err == nil && job.EventID != event.ID {
+			err = fmt.Errorf("card job %d of event %d: %w", id, event.ID, store.ErrNotFound)
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, cardJobCtx, job)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getCardJobFromCtx(r *http.Request) *store.CardJob {
+	job, _ := r.Context().Value(cardJobCtx).(*store.CardJob)
+	return job
+}
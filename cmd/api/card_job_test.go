@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sikozonpc/social/internal/render"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+func TestCreateCardJob(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		eventID  string
+		body     string
+		expected int
+	}{
+		{"all guests", "1", `{"card_template_id": 1}`, http.StatusAccepted},
+		{"filtered guests", "1", `{"card_template_id": 1, "statuses": ["accepted"], "types": ["vip"], "search": "Am"}`, http.StatusAccepted},
+		{"event without template", "1", `{}`, http.StatusBadRequest},
+		{"unknown status", "1", `{"card_template_id": 1, "statuses": ["gone"]}`, http.StatusBadRequest},
+		{"job already running", "2", `{"card_template_id": 1}`, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/v1/events/"+tt.eventID+"/card-jobs", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+		})
+	}
+}
+
+func TestCardJobProgress(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]int{
+		"/v1/events/1/card-jobs":                       http.StatusOK,
+		"/v1/events/1/card-jobs/1":                     http.StatusOK,
+		"/v1/events/1/card-jobs/1/items?status=failed": http.StatusOK,
+		"/v1/events/1/card-jobs/1/items?status=gone":   http.StatusBadRequest,
+		"/v1/events/2/card-jobs/1":                     http.StatusNotFound,
+		"/v1/events/1/card-jobs/9":                     http.StatusNotFound,
+	} {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, expected, rr.Code)
+	}
+}
+
+func TestGenerateCards(t *testing.T) {
+	dir := t.TempDir()
+	app := newTestApplication(t, config{cards: cardsConfig{assetsDir: dir, workers: 2}})
+
+	ctx := context.Background()
+
+	// guest 3 has an up-to-date card, guest 1 none
+	event, _ := app.store.Events.GetByID(ctx, 1)
+	guest, _ := app.store.Guests.GetByID(ctx, 3)
+	tmpl, _ := app.store.CardTemplates.GetByID(ctx, 1)
+
+	app.store.Cards.(*store.MockCardStore).Fingerprints = map[int64]string{
+		3: render.Fingerprint(tmpl, render.NewData(event, guest)),
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "cards"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "cards", "3.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.generateCards(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	done := app.store.CardJobs.(*store.MockCardJobStore).Done
+	if done[1] != store.CardItemRendered || done[2] != store.CardItemSkipped {
+		t.Errorf("expected guest 1 rendered and guest 3 skipped, got %v", done)
+	}
+}
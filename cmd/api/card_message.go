@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/sikozonpc/social/internal/blob"
+	"github.com/sikozonpc/social/internal/mailer"
+	"github.com/sikozonpc/social/internal/messenger"
+	"github.com/sikozonpc/social/internal/render"
+	"github.com/sikozonpc/social/internal/sms"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+var (
+	errCardNotIssued = errors.New("the guest has no card yet")
+	errNoPhoneNumber = errors.New("the guest has no phone number")
+)
+
+// sendGuestCardHandler sends the guest's issued card image to their phone
+// with a caption and their RSVP link.
+func (app *application) sendGuestCardHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+	guest := getGuestFromCtx(r)
+
+	if guest.CardID == 0 {
+		app.conflictResponse(w, r, errCardNotIssued)
+		return
+	}
+
+	if guest.PhoneNumber == "" {
+		app.badRequestResponse(w, r, errNoPhoneNumber)
+		return
+	}
+
+	ctx := r.Context()
+
+	card, image, err := app.guestCardImage(ctx, guest)
+	if err != nil {
+		app.cardRenderError(w, r, err)
+		return
+	}
+
+	caption, err := messenger.Caption(messenger.CardInvitationTemplate, app.cardInvitationData(event, guest))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	msg := &messenger.CardMessage{
+		To:        guest.PhoneNumber,
+		Image:     image,
+		ImageName: path.Base(card.ImagePath),
+		Caption:   caption,
+	}
+
+	providerID, err := app.messenger.SendCard(msg, app.config.env != "production")
+	if err != nil {
+		switch {
+		case errors.Is(err, sms.ErrInvalidPhoneNumber):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	sent := &store.CardMessage{
+		CardID:     card.ID,
+		GuestID:    guest.ID,
+		GuestName:  guest.Name,
+		EventID:    event.ID,
+		Recipient:  guest.PhoneNumber,
+		ProviderID: providerID,
+	}
+
+	if err := app.store.CardMessages.Create(ctx, sent); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, sent); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getGuestCardMessagesHandler returns the card messages sent to a guest.
+func (app *application) getGuestCardMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	guest := getGuestFromCtx(r)
+
+	messages, err := app.store.CardMessages.GetByGuest(r.Context(), guest.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, messages); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getEventCardMessagesHandler returns the card messages sent to an event's
+// guests with their sent, delivered and read states.
+func (app *application) getEventCardMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+
+	messages, err := app.store.CardMessages.GetByEvent(r.Context(), event.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, messages); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// messageStatusHandler receives the status callbacks of the messenger
+// provider.
+func (app *application) messageStatusHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := app.messenger.ParseStatus(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, messenger.ErrInvalidSignature):
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.CardMessages.UpdateStatus(r.Context(), report.MessageID, report.Status, report.Error); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// guestCardImage loads the guest's issued card and its image.
+func (app *application) guestCardImage(ctx context.Context, guest *store.Guest) (*store.Card, []byte, error) {
+	card, err := app.store.Cards.GetByID(ctx, guest.CardID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	image, err := blob.ReadAll(ctx, app.blobs, card.ImagePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return card, image, nil
+}
+
+func (app *application) cardInvitationData(event *store.Event, guest *store.Guest) mailer.InvitationData {
+	return mailer.InvitationData{
+		GuestName: guest.Name,
+		EventName: event.Name,
+		Date:      render.FormatDate(event.Date, ""),
+		Location:  event.Location,
+		RSVPURL:   fmt.Sprintf("%s/rsvp/%s", app.config.frontendURL, guest.RSVPToken),
+	}
+}
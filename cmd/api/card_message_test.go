@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sikozonpc/social/internal/mailer"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+func TestSendGuestCard(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "cards"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "cards", "3.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApplication(t, config{cards: cardsConfig{assetsDir: dir}})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		guestID  string
+		expected int
+	}{
+		{"send the issued card", "3", http.StatusCreated},
+		{"guest without a card", "1", http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/v1/guests/"+tt.guestID+"/card/send", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+		})
+	}
+}
+
+func TestMessageStatus(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"read", `{"id": "sandbox-1", "status": "read"}`, http.StatusNoContent},
+		{"unknown message", `{"id": "other", "status": "delivered"}`, http.StatusNotFound},
+		{"unknown status", `{"id": "sandbox-1", "status": "seen"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/v1/messages/status", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+		})
+	}
+	t.Run("should not take unsigned reports in production", func(t *testing.T) {
+		mux := newTestApplication(t, config{env: "production"}).mount()
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/messages/status", strings.NewReader(tests[0].body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestEmailGuestCard(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "cards"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "cards", "3.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApplication(t, config{cards: cardsConfig{assetsDir: dir}})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/v1/guests/3/card/email", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+testToken)
+
+	rr := executeRequest(req, mux)
+
+	checkResponseCode(t, http.StatusAccepted, rr.Code)
+
+	queued := app.store.Outbox.(*store.MockOutboxStore).Queued
+	if len(queued) != 1 || queued[0].Template != mailer.GuestInvitationTemplate {
+		t.Fatalf("expected the invitation to be queued, got %+v", queued)
+	}
+
+	msg, err := decodeOutboxMail(&queued[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msg.Attachments) != 2 || !msg.Attachments[0].Inline || msg.Attachments[0].ContentID != cardContentID {
+		t.Errorf("expected the card inline and attached, got %+v", msg.Attachments)
+	}
+
+	if string(msg.Attachments[1].Content) != "png" {
+		t.Errorf("expected the card image to survive the outbox, got %q", msg.Attachments[1].Content)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sikozonpc/social/internal/store"
+)
+
+// reissueReason is recorded on a card revoked by a reissue without reason.
+const reissueReason = "reissued"
+
+type RevokeCardPayload struct {
+	Reason string `json:"reason" validate:"required,max=255"`
+}
+
+type ReissueCardPayload struct {
+	// Reason defaults to reissueReason.
+	Reason string `json:"reason" validate:"max=255"`
+	// CardTemplateID defaults to the template of the revoked card, then to
+	// the template of the event.
+	CardTemplateID int64 `json:"card_template_id"`
+}
+
+// revokeCardHandler invalidates a card, e.g. when it leaked. Its code is
+// refused at check-in and the guest is left without a card until one is
+// reissued.
+func (app *application) revokeCardHandler(w http.ResponseWriter, r *http.Request) {
+	card := getCardFromCtx(r)
+	user := getUserFromContext(r)
+
+	var payload RevokeCardPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Cards.Revoke(ctx, card.ID, payload.Reason, user.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrCardRevoked):
+			app.conflictResponse(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	revoked, err := app.store.Cards.GetByID(ctx, card.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, revoked); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// reissueCardHandler revokes a card, unless it was revoked already, and
+// issues the guest a new one with a new code.
+func (app *application) reissueCardHandler(w http.ResponseWriter, r *http.Request) {
+	card := getCardFromCtx(r)
+	event := getEventFromCtx(r)
+	user := getUserFromContext(r)
+
+	var payload ReissueCardPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	var tmpl *store.CardTemplate
+	var err error
+	if payload.CardTemplateID != 0 {
+		tmpl, err = app.availableCardTemplate(ctx, user, payload.CardTemplateID)
+		if err != nil {
+			app.eventCardTemplateError(w, r, err)
+			return
+		}
+	} else {
+		tmpl, err = app.cardTemplate(ctx, event, card.CardTemplateID)
+		if err != nil {
+			app.cardRenderError(w, r, err)
+			return
+		}
+	}
+
+	guest, err := app.store.Guests.GetByID(ctx, card.GuestID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	reason := payload.Reason
+	if reason == "" {
+		reason = reissueReason
+	}
+
+	// the leaked card is revoked first so it stops working whether or not
+	// the new one can be rendered
+	if err := app.store.Cards.Revoke(ctx, card.ID, reason, user.ID); err != nil && !errors.Is(err, store.ErrCardRevoked) {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	reissued, err := app.newCard(ctx, event, guest, tmpl, user.ID)
+	if err != nil {
+		app.cardRenderError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, reissued); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -1 +1,542 @@
 package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sikozonpc/social/internal/blob"
+	"github.com/sikozonpc/social/internal/render"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+const maxTemplateUploadSize = 10 << 20 // 10mb
+
+type cardTemplateKey string
+
+const cardTemplateCtx cardTemplateKey = "cardTemplate"
+
+var (
+	errNoTemplateImage     = errors.New("the image form field is required")
+	errLayoutOutOfBounds   = errors.New("the layout places text boxes or the QR code outside of the image")
+	errCardTemplateMissing = errors.New("card template not found")
+)
+
+// CardTemplateView is a template with the URLs of its image and thumbnail.
+// The download URLs are signed and expire, they can be used without a token
+// e.g. in img tags.
+type CardTemplateView struct {
+	*store.CardTemplate
+	ImageURL             string `json:"image_url"`
+	ThumbnailURL         string `json:"thumbnail_url"`
+	DownloadURL          string `json:"download_url,omitempty"`
+	ThumbnailDownloadURL string `json:"thumbnail_download_url,omitempty"`
+}
+
+func (app *application) cardTemplateView(ctx context.Context, tmpl *store.CardTemplate) CardTemplateView {
+	thumbnail := tmpl.ThumbnailPath
+	if thumbnail == "" {
+		thumbnail = tmpl.ImagePath
+	}
+
+	return CardTemplateView{
+		CardTemplate:         tmpl,
+		ImageURL:             fmt.Sprintf("/v1/card-templates/%d/image", tmpl.ID),
+		ThumbnailURL:         fmt.Sprintf("/v1/card-templates/%d/thumbnail", tmpl.ID),
+		DownloadURL:          app.signedBlobURL(ctx, tmpl.ImagePath),
+		ThumbnailDownloadURL: app.signedBlobURL(ctx, thumbnail),
+	}
+}
+
+type CreateCardTemplatePayload struct {
+	Name       string `validate:"required,max=255"`
+	Visibility string `validate:"required,oneof=personal shared system"`
+	Layout     store.CardLayout
+}
+
+type UpdateCardTemplatePayload struct {
+	Name       *string           `json:"name" validate:"omitempty,max=255"`
+	Visibility *string           `json:"visibility" validate:"omitempty,oneof=personal shared system"`
+	Layout     *store.CardLayout `json:"layout"`
+}
+
+// createCardTemplateHandler uploads a template image as the "image" form
+// field, with its "name", "visibility" (personal when empty) and "layout" as
+// JSON. System templates can only be created by admins.
+func (app *application) createCardTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxTemplateUploadSize)
+	if err := r.ParseMultipartForm(maxTemplateUploadSize); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payload := CreateCardTemplatePayload{
+		Name:       r.FormValue("name"),
+		Visibility: r.FormValue("visibility"),
+	}
+	if payload.Visibility == "" {
+		payload.Visibility = store.TemplatePersonal
+	}
+
+	if layout := r.FormValue("layout"); layout != "" {
+		if err := json.Unmarshal([]byte(layout), &payload.Layout); err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("layout: %w", err))
+			return
+		}
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	tmpl := &store.CardTemplate{
+		Name:       payload.Name,
+		UserID:     user.ID,
+		Visibility: payload.Visibility,
+		Layout:     payload.Layout,
+	}
+
+	if tmpl.Visibility == store.TemplateSystem {
+		allowed, err := app.checkRolePrecedence(ctx, user, "admin")
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.forbiddenResponse(w, r)
+			return
+		}
+
+		tmpl.UserID = 0
+	}
+
+	if err := app.readTemplateImage(r, tmpl); err != nil {
+		app.cardTemplateError(w, r, err)
+		return
+	}
+
+	if err := app.store.CardTemplates.Create(ctx, nil, tmpl); err != nil {
+		app.releaseTemplateImages(ctx, tmpl)
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, app.cardTemplateView(ctx, tmpl)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getCardTemplatesHandler lists the templates the user can use, narrowed
+// with ?visibility= and ?search= on names.
+func (app *application) getCardTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	visibility := r.URL.Query().Get("visibility")
+	switch visibility {
+	case "", store.TemplatePersonal, store.TemplateShared, store.TemplateSystem:
+	default:
+		app.badRequestResponse(w, r, errors.New("visibility must be personal, shared or system"))
+		return
+	}
+
+	fq := store.PaginatedFeedQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	fq, err := fq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	templates, err := app.store.CardTemplates.GetAvailable(r.Context(), getUserFromContext(r).ID, visibility, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	views := make([]CardTemplateView, len(templates))
+	for i := range templates {
+		views[i] = app.cardTemplateView(r.Context(), &templates[i])
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, views); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) getCardTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, app.cardTemplateView(r.Context(), getCardTemplateFromCtx(r))); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// updateCardTemplateHandler renames a template, changes its visibility or
+// its layout. Only admins can turn templates into system templates and
+// back.
+func (app *application) updateCardTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := getCardTemplateFromCtx(r)
+	user := getUserFromContext(r)
+
+	var payload UpdateCardTemplatePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if payload.Name != nil {
+		tmpl.Name = *payload.Name
+	}
+
+	if payload.Visibility != nil && *payload.Visibility != tmpl.Visibility {
+		if *payload.Visibility == store.TemplateSystem || tmpl.Visibility == store.TemplateSystem {
+			allowed, err := app.checkRolePrecedence(ctx, user, "admin")
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			if !allowed {
+				app.forbiddenResponse(w, r)
+				return
+			}
+		}
+
+		switch {
+		case *payload.Visibility == store.TemplateSystem:
+			tmpl.UserID = 0
+		case tmpl.Visibility == store.TemplateSystem:
+			// the admin taking a system template back owns it
+			tmpl.UserID = user.ID
+		}
+
+		tmpl.Visibility = *payload.Visibility
+	}
+
+	if payload.Layout != nil {
+		tmpl.Layout = *payload.Layout
+	}
+
+	if err := checkLayoutBounds(tmpl); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.CardTemplates.Update(ctx, nil, tmpl); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, app.cardTemplateView(ctx, tmpl)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// replaceCardTemplateImageHandler uploads a new image for a template as the
+// "image" form field. The layout must still fit the new image.
+func (app *application) replaceCardTemplateImageHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := getCardTemplateFromCtx(r)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxTemplateUploadSize)
+	if err := r.ParseMultipartForm(maxTemplateUploadSize); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	previous := *tmpl
+	if err := app.readTemplateImage(r, tmpl); err != nil {
+		app.cardTemplateError(w, r, err)
+		return
+	}
+
+	if err := app.store.CardTemplates.Update(ctx, nil, tmpl); err != nil {
+		app.releaseTemplateImages(ctx, tmpl)
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.releaseTemplateImages(ctx, &previous)
+
+	if err := app.jsonResponse(w, http.StatusOK, app.cardTemplateView(ctx, tmpl)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteCardTemplateHandler deletes a template and its images, unless
+// another template uses the same image. Events and cards using it keep their
+// rendered cards and lose the template.
+func (app *application) deleteCardTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := getCardTemplateFromCtx(r)
+	ctx := r.Context()
+
+	if err := app.store.CardTemplates.Delete(ctx, tmpl.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.releaseTemplateImages(ctx, tmpl)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getCardTemplateImageHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := getCardTemplateFromCtx(r)
+	app.serveTemplateAsset(w, r, tmpl.ImagePath, tmpl.ContentType)
+}
+
+// getCardTemplateThumbnailHandler serves the thumbnail, or the image of
+// templates created before thumbnails existed.
+func (app *application) getCardTemplateThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := getCardTemplateFromCtx(r)
+	if tmpl.ThumbnailPath == "" {
+		app.serveTemplateAsset(w, r, tmpl.ImagePath, tmpl.ContentType)
+		return
+	}
+
+	app.serveTemplateAsset(w, r, tmpl.ThumbnailPath, "image/png")
+}
+
+func (app *application) serveTemplateAsset(w http.ResponseWriter, r *http.Request, name, contentType string) {
+	if name == "" {
+		app.notFoundResponse(w, r, errors.New("the template has no image"))
+		return
+	}
+
+	app.serveBlob(w, r, name, contentType)
+}
+
+// readTemplateImage checks the uploaded "image" and the layout against it,
+// then stores the image and its thumbnail.
+func (app *application) readTemplateImage(r *http.Request, tmpl *store.CardTemplate) error {
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		return errNoTemplateImage
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	img, err := render.DecodeTemplateImage(data)
+	if err != nil {
+		return err
+	}
+
+	tmpl.ContentType = img.ContentType
+	tmpl.Width, tmpl.Height = img.Width, img.Height
+
+	if err := checkLayoutBounds(tmpl); err != nil {
+		return err
+	}
+
+	var thumb bytes.Buffer
+	if err := render.EncodePNG(&thumb, render.Thumbnail(img.Image, render.ThumbnailWidth)); err != nil {
+		return err
+	}
+
+	ctx := r.Context()
+
+	imagePath, err := blob.PutContent(ctx, app.blobs, "templates", data, img.ContentType)
+	if err != nil {
+		return err
+	}
+
+	thumbnailPath, err := blob.PutContent(ctx, app.blobs, "templates", thumb.Bytes(), "image/png")
+	if err != nil {
+		app.releaseBlobs(ctx, imagePath)
+		return err
+	}
+
+	tmpl.ImagePath, tmpl.ThumbnailPath = imagePath, thumbnailPath
+	return nil
+}
+
+// checkLayoutBounds makes sure the card can be rendered and every text box
+// and the QR code start inside it. The card is the size of the layout or of
+// the image.
+func checkLayoutBounds(tmpl *store.CardTemplate) error {
+	width, height := tmpl.Layout.Width, tmpl.Layout.Height
+	if width == 0 || height == 0 {
+		width, height = tmpl.Width, tmpl.Height
+	}
+
+	if width == 0 || height == 0 {
+		return nil
+	}
+
+	if err := render.CheckCanvasSize(width, height); err != nil {
+		return err
+	}
+
+	for _, box := range tmpl.Layout.TextBoxes {
+		if box.X >= width || box.Y >= height || box.X+box.Width > width {
+			return errLayoutOutOfBounds
+		}
+	}
+
+	if qr := tmpl.Layout.QRCode; qr != nil && (qr.X+qr.Size > width || qr.Y+qr.Size > height) {
+		return errLayoutOutOfBounds
+	}
+
+	return nil
+}
+
+// releaseTemplateImages deletes the image and thumbnail of a template
+// unless they are still in use.
+func (app *application) releaseTemplateImages(ctx context.Context, tmpl *store.CardTemplate) {
+	app.releaseBlobs(ctx, tmpl.ImagePath, tmpl.ThumbnailPath)
+}
+
+func (app *application) cardTemplateError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errNoTemplateImage), errors.Is(err, errLayoutOutOfBounds), errors.Is(err, render.ErrImageSize),
+		errors.Is(err, render.ErrCanvasSize):
+		app.badRequestResponse(w, r, err)
+	case errors.Is(err, render.ErrUnsupportedImage):
+		app.unsupportedMediaTypeResponse(w, r, err)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+// cardTemplateContextMiddleware loads the template of {templateID}. Personal
+// templates of other users are reported as not found, except to admins.
+func (app *application) cardTemplateContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "templateID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		tmpl, err := app.availableCardTemplate(ctx, getUserFromContext(r), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, errCardTemplateMissing):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, cardTemplateCtx, tmpl)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getCardTemplateFromCtx(r *http.Request) *store.CardTemplate {
+	tmpl, _ := r.Context().Value(cardTemplateCtx).(*store.CardTemplate)
+	return tmpl
+}
+
+// availableCardTemplate returns a template user can use, or
+// errCardTemplateMissing.
+func (app *application) availableCardTemplate(ctx context.Context, user *store.User, id int64) (*store.CardTemplate, error) {
+	tmpl, err := app.store.CardTemplates.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, errCardTemplateMissing
+		}
+		return nil, err
+	}
+
+	if tmpl.Visibility != store.TemplatePersonal || tmpl.UserID == user.ID {
+		return tmpl, nil
+	}
+
+	allowed, err := app.checkRolePrecedence(ctx, user, "admin")
+	if err != nil {
+		return nil, err
+	}
+
+	if !allowed {
+		return nil, errCardTemplateMissing
+	}
+
+	return tmpl, nil
+}
+
+// checkCardTemplateAccess rejects card template IDs of events the user cannot
+// use, e.g. the personal templates of others.
+func (app *application) checkCardTemplateAccess(ctx context.Context, user *store.User, templateID string) error {
+	if templateID == "" {
+		return nil
+	}
+
+	id, err := strconv.ParseInt(templateID, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	_, err = app.availableCardTemplate(ctx, user, id)
+	return err
+}
+
+// checkCardTemplateOwnership lets owners edit their personal and shared
+// templates; system templates and the templates of others are edited by
+// admins only.
+func (app *application) checkCardTemplateOwnership(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := getUserFromContext(r)
+		tmpl := getCardTemplateFromCtx(r)
+
+		if tmpl.Visibility != store.TemplateSystem && tmpl.UserID == user.ID {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, err := app.checkRolePrecedence(r.Context(), user, "admin")
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.forbiddenResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sikozonpc/social/internal/blob"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+func templateUpload(t *testing.T, fields map[string]string, image []byte) (*bytes.Buffer, string) {
+	t.Helper()
+
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if image != nil {
+		fw, err := mw.CreateFormFile("image", "template.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(image)
+	}
+
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return body, mw.FormDataContentType()
+}
+
+func TestCardTemplates(t *testing.T) {
+	assets := t.TempDir()
+	app := newTestApplication(t, config{cards: cardsConfig{assetsDir: assets}})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 800, 1000))); err != nil {
+		t.Fatal(err)
+	}
+
+	layout := `{"text_boxes": [{"field": "guest_name", "x": 40, "y": 600, "width": 720}], "qr_code": {"x": 600, "y": 800, "size": 160}}`
+
+	tests := []struct {
+		name     string
+		fields   map[string]string
+		image    []byte
+		expected int
+	}{
+		{"upload", map[string]string{"name": "Harusi", "layout": layout}, img.Bytes(), http.StatusCreated},
+		{"system template as admin", map[string]string{"name": "Classic", "visibility": "system"}, img.Bytes(), http.StatusCreated},
+		{"without image", map[string]string{"name": "Harusi"}, nil, http.StatusBadRequest},
+		{"without name", map[string]string{}, img.Bytes(), http.StatusBadRequest},
+		{"not an image", map[string]string{"name": "Harusi"}, []byte("<svg></svg>"), http.StatusUnsupportedMediaType},
+		{"layout outside the image", map[string]string{"name": "Harusi", "layout": `{"text_boxes": [{"field": "guest_name", "x": 900, "y": 10}]}`}, img.Bytes(), http.StatusBadRequest},
+		{"invalid layout", map[string]string{"name": "Harusi", "layout": `{"text_boxes": [{"field": "phone"}]}`}, img.Bytes(), http.StatusBadRequest},
+		{"oversized layout", map[string]string{"name": "Harusi", "layout": `{"width": 100000, "height": 100000}`}, img.Bytes(), http.StatusBadRequest},
+		{"layout too large to render", map[string]string{"name": "Harusi", "layout": `{"width": 6000, "height": 6000}`}, img.Bytes(), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := templateUpload(t, tt.fields, tt.image)
+
+			req, err := http.NewRequest(http.MethodPost, "/v1/card-templates", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Content-Type", contentType)
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+
+			if rr.Code == http.StatusCreated && !strings.Contains(rr.Body.String(), `"thumbnail_url":"/v1/card-templates/4/thumbnail"`) {
+				t.Errorf("expected the thumbnail url, got %s", rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("should store the image and its thumbnail once", func(t *testing.T) {
+		var sizes []image.Point
+		err := app.blobs.List(context.Background(), "templates/", func(info blob.Info) error {
+			data, err := blob.ReadAll(context.Background(), app.blobs, info.Key)
+			if err != nil {
+				return err
+			}
+
+			cfg, err := png.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+
+			sizes = append(sizes, image.Point{cfg.Width, cfg.Height})
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// both uploads are the same image
+		if len(sizes) != 2 {
+			t.Fatalf("expected the image and its thumbnail, got %v", sizes)
+		}
+
+		for _, size := range sizes {
+			if size != (image.Point{800, 1000}) && size != (image.Point{320, 400}) {
+				t.Errorf("unexpected image size %v", size)
+			}
+		}
+	})
+
+	t.Run("should keep images another template uses", func(t *testing.T) {
+		ctx := context.Background()
+		key, err := blob.PutContent(ctx, app.blobs, "templates", []byte("shared"), "image/png")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		app.store.Assets.(*store.MockAssetStore).Used = map[string]bool{key: true}
+		defer func() { app.store.Assets.(*store.MockAssetStore).Used = nil }()
+
+		app.releaseBlobs(ctx, key)
+		if _, err := app.blobs.Stat(ctx, key); err != nil {
+			t.Errorf("expected the image in use to be kept, got %v", err)
+		}
+
+		app.store.Assets.(*store.MockAssetStore).Used = nil
+		app.releaseBlobs(ctx, key)
+		if _, err := app.blobs.Stat(ctx, key); err != nil {
+			t.Errorf("expected a new image to be left to the sweep, got %v", err)
+		}
+
+		modTime := time.Now().Add(-2 * blobGracePeriod)
+		if err := os.Chtimes(filepath.Join(assets, filepath.FromSlash(key)), modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+
+		app.releaseBlobs(ctx, key)
+		if _, err := app.blobs.Stat(ctx, key); err != blob.ErrNotFound {
+			t.Errorf("expected the unused image to be deleted, got %v", err)
+		}
+	})
+
+	requests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		expected int
+	}{
+		{"list", http.MethodGet, "/v1/card-templates?visibility=shared&search=Temp", "", http.StatusOK},
+		{"unknown visibility", http.MethodGet, "/v1/card-templates?visibility=public", "", http.StatusBadRequest},
+		{"get", http.MethodGet, "/v1/card-templates/3", "", http.StatusOK},
+		{"rename", http.MethodPatch, "/v1/card-templates/3", `{"name": "Kitchen party"}`, http.StatusOK},
+		{"unknown visibility on update", http.MethodPatch, "/v1/card-templates/3", `{"visibility": "public"}`, http.StatusBadRequest},
+		{"layout outside the card", http.MethodPatch, "/v1/card-templates/1", `{"layout": {"width": 400, "height": 300, "qr_code": {"x": 380, "y": 0, "size": 64}}}`, http.StatusBadRequest},
+		{"image not uploaded", http.MethodGet, "/v1/card-templates/1/image", "", http.StatusNotFound},
+		{"delete", http.MethodDelete, "/v1/card-templates/2", "", http.StatusNoContent},
+	}
+
+	for _, tt := range requests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+		})
+	}
+
+	t.Run("should hide personal templates of others", func(t *testing.T) {
+		ctx := context.Background()
+		user := &store.User{ID: 5, Role: store.Role{Name: "user", Level: 1}}
+
+		if _, err := app.availableCardTemplate(ctx, user, 2); err != errCardTemplateMissing {
+			t.Errorf("expected the personal template of user 2 to be hidden, got %v", err)
+		}
+
+		for _, id := range []int64{1, 3} {
+			if _, err := app.availableCardTemplate(ctx, user, id); err != nil {
+				t.Errorf("expected template %d to be available, got %v", id, err)
+			}
+		}
+
+		if err := app.checkCardTemplateAccess(ctx, user, "2"); err != errCardTemplateMissing {
+			t.Errorf("expected events not to use hidden templates, got %v", err)
+		}
+	})
+}
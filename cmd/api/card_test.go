@@ -0,0 +1,239 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sikozonpc/social/internal/store"
+)
+
+func TestRenderGuestCard(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		expected    int
+		contentType string
+	}{
+		{"event without template", "", http.StatusBadRequest, "application/json"},
+		{"png", "?template_id=1", http.StatusOK, "image/png"},
+		{"pdf", "?template_id=1&format=pdf", http.StatusOK, "application/pdf"},
+		{"unsupported format", "?template_id=1&format=gif", http.StatusBadRequest, "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/v1/guests/1/card"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+
+			if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+				t.Errorf("expected content type %q, got %q", tt.contentType, ct)
+			}
+		})
+	}
+}
+
+func TestEventVerificationKey(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/v1/events/1/verification-key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+testToken)
+
+	rr := executeRequest(req, mux)
+
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	if !strings.Contains(rr.Body.String(), `"algorithm":"Ed25519"`) {
+		t.Errorf("expected an Ed25519 key, got %s", rr.Body.String())
+	}
+}
+
+func TestEventCards(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "cards"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "cards", "3.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApplication(t, config{cards: cardsConfig{assetsDir: dir}})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(t *testing.T, path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{"list", "/v1/events/1/cards?search=Am&limit=10", http.StatusOK},
+		{"invalid limit", "/v1/events/1/cards?limit=1000", http.StatusBadRequest},
+		{"image", "/v1/cards/3", http.StatusOK},
+		{"image missing", "/v1/cards/1", http.StatusNotFound},
+		{"invalid id", "/v1/cards/abc", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkResponseCode(t, tt.expected, get(t, tt.path).Code)
+		})
+	}
+
+	t.Run("should name downloads after the guest", func(t *testing.T) {
+		rr := get(t, "/v1/cards/3?download=true")
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if cd := rr.Header().Get("Content-Disposition"); cd != `attachment; filename=guest-3.png` {
+			t.Errorf("unexpected content disposition %q", cd)
+		}
+	})
+
+	t.Run("should bundle the cards in a zip", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(dir, "cards", "1.png"), []byte("png"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		rr := get(t, "/v1/events/1/cards/export")
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		zr, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+
+		// both guests of the mock are named Amani
+		if strings.Join(names, ",") != "Amani.png,Amani (2).png" {
+			t.Errorf("unexpected files %v", names)
+		}
+	})
+
+	t.Run("should not time out large bundles", func(t *testing.T) {
+		var hasDeadline bool
+		handler := app.timeoutMiddleware(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, hasDeadline = r.Context().Deadline()
+		}))
+
+		for path, expected := range map[string]bool{
+			"/v1/events/1/cards/export": false,
+			"/v1/events/1/cards":        true,
+		} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if hasDeadline != expected {
+				t.Errorf("expected a deadline on %s to be %v", path, expected)
+			}
+		}
+	})
+}
+
+func TestCardFileName(t *testing.T) {
+	tests := map[string]string{
+		"Neema & Baraka":   "Neema _ Baraka",
+		"  Zoë O'Neil ":    "Zoë O'Neil",
+		"../../etc/passwd": "_.._etc_passwd",
+		"":                 "guest-7",
+		"...":              "guest-7",
+		"Mr. Juma":         "Mr. Juma",
+	}
+
+	for name, expected := range tests {
+		if got := cardFileName(&store.Card{GuestID: 7, Guest: &store.Guest{Name: name}}); got != expected {
+			t.Errorf("cardFileName(%q) = %q, want %q", name, got, expected)
+		}
+	}
+}
+
+func TestRevokeCard(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		expected int
+	}{
+		{"revoke", "/v1/cards/3/revoke", `{"reason": "shared online"}`, http.StatusOK},
+		{"revoke without reason", "/v1/cards/3/revoke", `{}`, http.StatusBadRequest},
+		{"already revoked", "/v1/cards/4/revoke", `{"reason": "shared online"}`, http.StatusConflict},
+		{"reissue", "/v1/cards/3/reissue", `{"card_template_id": 1}`, http.StatusCreated},
+		{"reissue revoked card", "/v1/cards/4/reissue", `{"card_template_id": 1}`, http.StatusCreated},
+		{"reissue without template", "/v1/cards/3/reissue", `{}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+		})
+	}
+}
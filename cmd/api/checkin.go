@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sikozonpc/social/internal/live"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+var (
+	errInvalidCard    = errors.New("invalid card")
+	errCardOtherEvent = errors.New("card belongs to another event")
+)
+
+type CreateCheckInPayload struct {
+	Code string `json:"code" validate:"required,max=255"`
+	Gate string `json:"gate" validate:"max=100"`
+}
+
+type CheckInResponse struct {
+	CheckIn *store.CheckIn `json:"check_in"`
+	Guest   *store.Guest   `json:"guest"`
+}
+
+// createCheckInHandler checks in the guest of a scanned card code.
+func (app *application) createCheckInHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+	user := getUserFromContext(r)
+
+	var payload CreateCheckInPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	card, err := app.verifyCardCode(ctx, event, payload.Code)
+	if err != nil {
+		app.cardCodeError(w, r, err)
+		return
+	}
+
+	guest, err := app.store.Guests.GetByID(ctx, card.GuestID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	checkIn := &store.CheckIn{
+		EventID:  event.ID,
+		GuestID:  guest.ID,
+		CardID:   card.ID,
+		UserID:   user.ID,
+		Username: user.Username,
+		Gate:     payload.Gate,
+	}
+
+	if err := app.store.CheckIns.Create(ctx, checkIn); err != nil {
+		switch {
+		case errors.Is(err, store.ErrAlreadyCheckedIn):
+			app.alreadyCheckedInResponse(w, r, event, guest)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	response := CheckInResponse{CheckIn: checkIn, Guest: guest}
+	app.publishLive(ctx, live.TypeCheckIn, event.ID, response)
+
+	if err := app.jsonResponse(w, http.StatusCreated, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteCheckInHandler undoes a guest's check-in, e.g. after a wrong scan.
+func (app *application) deleteCheckInHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+	user := getUserFromContext(r)
+
+	guestID, err := strconv.ParseInt(chi.URLParam(r, "guestID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.CheckIns.Undo(r.Context(), event.ID, guestID, user.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.publishLive(r.Context(), live.TypeCheckInUndone, event.ID, CheckInUndone{GuestID: guestID, UndoneBy: user.Username})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verifyCardCode validates a scanned card code for event and loads the card
+// it refers to. Codes of revoked cards are refused with store.ErrCardRevoked.
+func (app *application) verifyCardCode(ctx context.Context, event *store.Event, code string) (*store.Card, error) {
+	claims, err := app.cardSigner.Verify(code)
+	if err != nil {
+		return nil, errInvalidCard
+	}
+
+	if claims.EventID != event.ID {
+		return nil, errCardOtherEvent
+	}
+
+	card, err := app.store.Cards.GetByID(ctx, claims.CardID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return nil, errInvalidCard
+		default:
+			return nil, err
+		}
+	}
+
+	if card.GuestID != claims.GuestID || card.EventID != claims.EventID {
+		return nil, errInvalidCard
+	}
+
+	if card.RevokedAt != "" {
+		return nil, store.ErrCardRevoked
+	}
+
+	return card, nil
+}
+
+func (app *application) cardCodeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errInvalidCard), errors.Is(err, errCardOtherEvent):
+		app.badRequestResponse(w, r, err)
+	case errors.Is(err, store.ErrCardRevoked):
+		app.goneResponse(w, r, err)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+// alreadyCheckedInResponse tells door staff when and by whom the guest was
+// checked in, at the local time of the event.
+func (app *application) alreadyCheckedInResponse(w http.ResponseWriter, r *http.Request, event *store.Event, guest *store.Guest) {
+	existing, err := app.store.CheckIns.GetByGuest(r.Context(), guest.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	at := existing.CheckedInAt
+	if t, err := time.Parse(time.RFC3339, at); err == nil {
+		at = t.In(eventLocation(event)).Format("15:04")
+	}
+
+	by := existing.Username
+	if by == "" {
+		by = "unknown"
+	}
+
+	app.conflictResponse(w, r, fmt.Errorf("%s already checked in at %s by %s", guest.Name, at, by))
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sikozonpc/social/internal/env"
+	"github.com/sikozonpc/social/internal/live"
+)
+
+const (
+	// feedKeepAlive is how often idle feeds are pinged so proxies do not
+	// close the connection.
+	feedKeepAlive = 25 * time.Second
+	// streamTicketTTL is how long a ticket can open a feed. Browsers
+	// reconnecting later need a new one.
+	streamTicketTTL = time.Minute
+)
+
+var feedUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || origin == env.GetString("CORS_ALLOWED_ORIGIN", "http://localhost:5174")
+	},
+}
+
+type StreamTicket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// createStreamTicketHandler issues a ticket opening the event's live feeds,
+// passed as ?ticket= where the bearer token cannot be sent.
+func (app *application) createStreamTicketHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+	user := getUserFromContext(r)
+
+	expiresAt := time.Now().Add(streamTicketTTL).UTC().Truncate(time.Second)
+
+	ticket, err := app.streamTickets.Sign(user.ID, event.ID, expiresAt)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, StreamTicket{Ticket: ticket, ExpiresAt: expiresAt}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type GuestStatusChange struct {
+	GuestID int64  `json:"guest_id"`
+	Status  string `json:"status"`
+}
+
+type GuestStatusesChange struct {
+	GuestIDs []int64 `json:"guest_ids"`
+	Status   string  `json:"status"`
+}
+
+type CheckInUndone struct {
+	GuestID  int64  `json:"guest_id"`
+	UndoneBy string `json:"undone_by"`
+}
+
+// publishLive sends a message to the event's live feed. Failures are only
+// logged, the change itself has already been stored.
+func (app *application) publishLive(ctx context.Context, typ string, eventID int64, data any) {
+	msg, err := live.NewMessage(typ, eventID, data)
+	if err == nil {
+		err = app.broker.Publish(ctx, msg)
+	}
+
+	if err != nil {
+		app.logger.Errorw("error publishing live message", "type", typ, "event", eventID, "error", err)
+	}
+}
+
+// checkInStreamHandler streams the event's check-in activity as
+// server-sent events.
+func (app *application) checkInStreamHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+
+	rc := http.NewResponseController(w)
+
+	// the stream outlives the server's write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	sub := app.broker.Subscribe(event.ID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	_, err := fmt.Fprint(w, "retry: 3000\n\n")
+	if err == nil {
+		err = rc.Flush()
+	}
+
+	ticker := time.NewTicker(feedKeepAlive)
+	defer ticker.Stop()
+
+	// a failed write means the client is gone
+	for err == nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		case msg, ok := <-sub.C:
+			if !ok {
+				return
+			}
+
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, msg.Data)
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+	}
+}
+
+// checkInWebSocketHandler streams the event's check-in activity over a
+// WebSocket. The feed is one way, messages from the client are discarded.
+func (app *application) checkInWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+
+	conn, err := feedUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already replied
+		return
+	}
+	defer conn.Close()
+
+	sub := app.broker.Subscribe(event.ID)
+	defer sub.Close()
+
+	// reading is needed to process pings and notice the client leaving
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(feedKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+		case msg, ok := <-sub.C:
+			if !ok {
+				return
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			err = conn.WriteJSON(msg)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
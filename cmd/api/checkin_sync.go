@@ -0,0 +1,254 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sikozonpc/social/internal/live"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+// Statuses of synced offline check-ins.
+const (
+	syncAccepted  = "accepted"
+	syncDuplicate = "duplicate"
+	syncRejected  = "rejected"
+)
+
+// checkInOpensBefore is how long before the start of an event its guests
+// can be scanned, e.g. at a pre-event reception. An earlier scan comes from
+// a device with a wrong clock and would otherwise win every merge.
+const checkInOpensBefore = 24 * time.Hour
+
+var errScanBeforeEvent = errors.New("scanned before the event opened for check-in")
+
+// CheckInSnapshotGuest is the state of a guest as known to a door scanner.
+type CheckInSnapshotGuest struct {
+	GuestID     int64  `json:"guest_id"`
+	CardID      int64  `json:"card_id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Status      string `json:"status"`
+	CheckedInAt string `json:"checked_in_at,omitempty"`
+	CheckedInBy string `json:"checked_in_by,omitempty"`
+	Gate        string `json:"gate,omitempty"`
+	DeviceID    string `json:"device_id,omitempty"`
+}
+
+// CheckInSnapshot is everything a scanner needs to check guests in while
+// offline: the key validating card codes and the current check-in state.
+type CheckInSnapshot struct {
+	EventID         int64                  `json:"event_id"`
+	GeneratedAt     time.Time              `json:"generated_at"`
+	ScannedCount    int64                  `json:"scanned_count"`
+	VerificationKey EventVerificationKey   `json:"verification_key"`
+	Guests          []CheckInSnapshotGuest `json:"guests"`
+}
+
+type OfflineCheckInPayload struct {
+	Code      string    `json:"code" validate:"required,max=255"`
+	ScannedAt time.Time `json:"scanned_at" validate:"required"`
+	Gate      string    `json:"gate" validate:"max=100"`
+}
+
+type SyncCheckInsPayload struct {
+	DeviceID string                  `json:"device_id" validate:"required,max=100"`
+	CheckIns []OfflineCheckInPayload `json:"check_ins" validate:"required,max=1000,dive"`
+}
+
+// SyncCheckInResult reports what happened to one uploaded check-in. For
+// duplicates the check-in fields describe the scan that was kept.
+type SyncCheckInResult struct {
+	Code        string `json:"code"`
+	Status      string `json:"status"`
+	Reason      string `json:"reason,omitempty"`
+	GuestID     int64  `json:"guest_id,omitempty"`
+	CardID      int64  `json:"card_id,omitempty"`
+	CheckedInAt string `json:"checked_in_at,omitempty"`
+	CheckedInBy string `json:"checked_in_by,omitempty"`
+	DeviceID    string `json:"device_id,omitempty"`
+}
+
+type SyncCheckInsResponse struct {
+	Results      []SyncCheckInResult `json:"results"`
+	ScannedCount int64               `json:"scanned_count"`
+	CheckIns     []store.CheckIn     `json:"check_ins"`
+}
+
+// getCheckInSnapshotHandler returns the event's guests and check-ins for a
+// scanner to download before going offline.
+func (app *application) getCheckInSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+	ctx := r.Context()
+
+	checkIns, err := app.store.CheckIns.GetByEvent(ctx, event.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	byGuest := make(map[int64]store.CheckIn, len(checkIns))
+	for _, ch := range checkIns {
+		byGuest[ch.GuestID] = ch
+	}
+
+	snapshot := CheckInSnapshot{
+		EventID:         event.ID,
+		GeneratedAt:     time.Now().UTC(),
+		ScannedCount:    event.ScannedCount,
+		VerificationKey: app.eventVerificationKey(event),
+		Guests:          []CheckInSnapshotGuest{},
+	}
+
+	err = app.store.Guests.ForEach(ctx, event.ID, "", func(guest *store.Guest) error {
+		g := CheckInSnapshotGuest{
+			GuestID: guest.ID,
+			CardID:  guest.CardID,
+			Name:    guest.Name,
+			Type:    guest.Type,
+			Status:  guest.Status,
+		}
+
+		if ch, ok := byGuest[guest.ID]; ok {
+			g.CheckedInAt = ch.CheckedInAt
+			g.CheckedInBy = ch.Username
+			g.Gate = ch.Gate
+			g.DeviceID = ch.DeviceID
+		}
+
+		snapshot.Guests = append(snapshot.Guests, g)
+		return nil
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, snapshot); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// syncCheckInsHandler uploads the check-ins a scanner made while offline.
+// When a card was scanned on several devices the earliest scan is kept, and
+// the merged check-in state of the event is returned for the scanner to
+// replace its local copy with.
+func (app *application) syncCheckInsHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+	user := getUserFromContext(r)
+
+	var payload SyncCheckInsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	now := time.Now().UTC()
+
+	var opensAt time.Time
+	if date, err := time.Parse(time.RFC3339, event.Date); err == nil {
+		opensAt = date.Add(-checkInOpensBefore)
+	}
+
+	results := make([]SyncCheckInResult, 0, len(payload.CheckIns))
+	for _, item := range payload.CheckIns {
+		result := SyncCheckInResult{Code: item.Code}
+
+		if item.ScannedAt.Before(opensAt) {
+			result.Status = syncRejected
+			result.Reason = errScanBeforeEvent.Error()
+			results = append(results, result)
+			continue
+		}
+
+		card, err := app.verifyCardCode(ctx, event, item.Code)
+		if err != nil {
+			if !errors.Is(err, errInvalidCard) && !errors.Is(err, errCardOtherEvent) && !errors.Is(err, store.ErrCardRevoked) {
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			result.Status = syncRejected
+			result.Reason = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		guest, err := app.store.Guests.GetByID(ctx, card.GuestID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		// device clocks drift, a scan cannot have happened after the upload
+		scannedAt := item.ScannedAt.UTC()
+		if scannedAt.After(now) {
+			scannedAt = now
+		}
+
+		checkIn := &store.CheckIn{
+			EventID:     event.ID,
+			GuestID:     guest.ID,
+			CardID:      card.ID,
+			UserID:      user.ID,
+			Username:    user.Username,
+			Gate:        item.Gate,
+			DeviceID:    payload.DeviceID,
+			CheckedInAt: scannedAt.Format(time.RFC3339),
+		}
+
+		result.Status = syncAccepted
+		created, err := app.store.CheckIns.Merge(ctx, checkIn)
+		switch {
+		case errors.Is(err, store.ErrAlreadyCheckedIn):
+			result.Status = syncDuplicate
+			result.Reason = err.Error()
+		case err != nil:
+			app.internalServerError(w, r, err)
+			return
+		case created:
+			app.publishLive(ctx, live.TypeCheckIn, event.ID, CheckInResponse{CheckIn: checkIn, Guest: guest})
+		default:
+			// an earlier scan replaced the guest's check-in, which followers
+			// must not count twice
+			app.publishLive(ctx, live.TypeCheckInUpdated, event.ID, CheckInResponse{CheckIn: checkIn, Guest: guest})
+		}
+
+		result.GuestID = checkIn.GuestID
+		result.CardID = checkIn.CardID
+		result.CheckedInAt = checkIn.CheckedInAt
+		result.CheckedInBy = checkIn.Username
+		result.DeviceID = checkIn.DeviceID
+		results = append(results, result)
+	}
+
+	// reload the event for the scanned count including other devices' syncs
+	event, err := app.store.Events.GetByID(ctx, event.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	checkIns, err := app.store.CheckIns.GetByEvent(ctx, event.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := SyncCheckInsResponse{
+		Results:      results,
+		ScannedCount: event.ScannedCount,
+		CheckIns:     checkIns,
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sikozonpc/social/internal/auth"
+	"github.com/sikozonpc/social/internal/live"
+)
+
+func TestCreateCheckIn(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(claims auth.CardClaims) string {
+		code, err := app.cardSigner.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		expected int
+		contains string
+	}{
+		{"valid card", sign(auth.CardClaims{CardID: 1, GuestID: 1, EventID: 1}), http.StatusCreated, `"gate":"main"`},
+		{"already checked in", sign(auth.CardClaims{CardID: 2, GuestID: 2, EventID: 1}), http.StatusConflict, "already checked in at 21:42 by mlinzi"},
+		{"card of another event", sign(auth.CardClaims{CardID: 1, GuestID: 1, EventID: 9}), http.StatusBadRequest, "another event"},
+		{"card and guest mismatch", sign(auth.CardClaims{CardID: 1, GuestID: 3, EventID: 1}), http.StatusBadRequest, "invalid card"},
+		{"revoked card", sign(auth.CardClaims{CardID: 4, GuestID: 4, EventID: 1}), http.StatusGone, "revoked"},
+		{"forged code", "AQEBAQ" + strings.Repeat("A", 86), http.StatusBadRequest, "invalid card"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.NewReader(`{"code": "` + tt.code + `", "gate": "main"}`)
+			req, err := http.NewRequest(http.MethodPost, "/v1/events/1/checkins", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+
+			if !strings.Contains(rr.Body.String(), tt.contains) {
+				t.Errorf("expected response to contain %q, got %s", tt.contains, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeleteCheckIn(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for guestID, expected := range map[string]int{"2": http.StatusNoContent, "3": http.StatusNotFound} {
+		req, err := http.NewRequest(http.MethodDelete, "/v1/events/1/checkins/"+guestID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, expected, rr.Code)
+	}
+}
+
+func TestSyncCheckIns(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(claims auth.CardClaims) string {
+		code, err := app.cardSigner.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	t.Run("should return the snapshot", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/events/1/checkins/snapshot", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if !strings.Contains(rr.Body.String(), `"algorithm":"Ed25519"`) {
+			t.Errorf("expected snapshot to contain the verification key, got %s", rr.Body.String())
+		}
+	})
+
+	tests := []struct {
+		name      string
+		code      string
+		scannedAt string
+		contains  string
+		published string
+	}{
+		{"new check-in", sign(auth.CardClaims{CardID: 1, GuestID: 1, EventID: 1}), "2024-12-21T18:30:00Z", `"status":"accepted"`, live.TypeCheckIn},
+		{"earlier scan wins", sign(auth.CardClaims{CardID: 2, GuestID: 2, EventID: 1}), "2024-12-21T18:40:00Z", `"status":"accepted"`, live.TypeCheckInUpdated},
+		{"later scan is a duplicate", sign(auth.CardClaims{CardID: 2, GuestID: 2, EventID: 1}), "2024-12-21T18:50:00Z", `"checked_in_by":"mlinzi"`, ""},
+		{"card of another event", sign(auth.CardClaims{CardID: 1, GuestID: 1, EventID: 9}), "2024-12-21T18:30:00Z", `"status":"rejected"`, ""},
+		{"revoked card", sign(auth.CardClaims{CardID: 4, GuestID: 4, EventID: 1}), "2024-12-21T18:30:00Z", `"reason":"card has been revoked"`, ""},
+		{"scan before the event", sign(auth.CardClaims{CardID: 1, GuestID: 1, EventID: 1}), "2024-12-20T12:00:00Z", `"reason":"scanned before the event opened for check-in"`, ""},
+		{"scan on the eve of the event", sign(auth.CardClaims{CardID: 1, GuestID: 1, EventID: 1}), "2024-12-20T20:00:00Z", `"status":"accepted"`, live.TypeCheckIn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := app.broker.Subscribe(1)
+			defer sub.Close()
+
+			body := strings.NewReader(`{"device_id": "gate-a", "check_ins": [{"code": "` + tt.code + `", "scanned_at": "` + tt.scannedAt + `"}]}`)
+			req, err := http.NewRequest(http.MethodPost, "/v1/events/1/checkins/sync", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, http.StatusOK, rr.Code)
+
+			if !strings.Contains(rr.Body.String(), tt.contains) {
+				t.Errorf("expected response to contain %q, got %s", tt.contains, rr.Body.String())
+			}
+
+			var published string
+			select {
+			case msg := <-sub.C:
+				published = msg.Type
+
+				var data CheckInResponse
+				if err := json.Unmarshal(msg.Data, &data); err != nil {
+					t.Fatal(err)
+				}
+
+				if data.Guest == nil || data.Guest.ID != data.CheckIn.GuestID || data.Guest.Status == "" {
+					t.Errorf("expected the guest of the check-in to be published, got %+v", data.Guest)
+				}
+			default:
+			}
+
+			if published != tt.published {
+				t.Errorf("expected %q to be published, got %q", tt.published, published)
+			}
+		})
+	}
+}
+
+func TestCheckInStream(t *testing.T) {
+	app := newTestApplication(t, config{})
+	srv := httptest.NewUnstartedServer(app.mount())
+	// the stream must outlive the write timeout
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ticketReq, err := http.NewRequest(http.MethodPost, "/v1/events/1/checkins/stream-ticket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ticketReq.Header.Set("Authorization", "Bearer "+testToken)
+
+	rr := executeRequest(ticketReq, app.mount())
+	checkResponseCode(t, http.StatusCreated, rr.Code)
+
+	var ticket struct {
+		Data StreamTicket `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &ticket); err != nil {
+		t.Fatal(err)
+	}
+
+	refused := []struct {
+		name string
+		path string
+	}{
+		{"bearer token in the url", "/v1/events/1/checkins/stream?access_token=" + testToken},
+		{"ticket of another event", "/v1/events/2/checkins/stream?ticket=" + ticket.Data.Ticket},
+	}
+
+	for _, tt := range refused {
+		t.Run("should refuse the "+tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Accept", "text/event-stream")
+
+			checkResponseCode(t, http.StatusUnauthorized, executeRequest(req, app.mount()).Code)
+		})
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/events/1/checkins/stream?ticket="+ticket.Data.Ticket, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	checkResponseCode(t, http.StatusOK, resp.StatusCode)
+
+	lines := bufio.NewScanner(resp.Body)
+	lines.Scan() // retry hint
+
+	time.Sleep(2 * srv.Config.WriteTimeout)
+
+	code, err := app.cardSigner.Sign(auth.CardClaims{CardID: 1, GuestID: 1, EventID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkIn, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/events/1/checkins", strings.NewReader(`{"code": "`+code+`"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkIn.Header.Set("Authorization", "Bearer "+testToken)
+
+	checkInResp, err := srv.Client().Do(checkIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkInResp.Body.Close()
+
+	for lines.Scan() {
+		if lines.Text() == "event: check_in" {
+			return
+		}
+	}
+
+	t.Errorf("expected a check_in event, stream ended with %v", lines.Err())
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sikozonpc/social/internal/mailer"
+)
+
+// getCapturedMailsHandler lists the mails kept by the capture mailer.
+func (app *application) getCapturedMailsHandler(w http.ResponseWriter, r *http.Request) {
+	mails, err := app.mailCapture.List()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, mails); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) getCapturedMailHandler(w http.ResponseWriter, r *http.Request) {
+	mail, ok := app.capturedMail(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, mail); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getCapturedMailHTMLHandler shows a captured mail as a browser would, with
+// its inline images.
+func (app *application) getCapturedMailHTMLHandler(w http.ResponseWriter, r *http.Request) {
+	mail, ok := app.capturedMail(w, r)
+	if !ok {
+		return
+	}
+
+	html := mail.HTML
+	for _, a := range mail.Attachments {
+		if a.Inline && a.ContentID != "" {
+			uri := "data:" + a.ContentType + ";base64," + base64.StdEncoding.EncodeToString(a.Content)
+			html = strings.ReplaceAll(html, "cid:"+a.ContentID, uri)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(html))
+}
+
+func (app *application) clearCapturedMailsHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.mailCapture.Clear(); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) capturedMail(w http.ResponseWriter, r *http.Request) (*mailer.CapturedMail, bool) {
+	mail, err := app.mailCapture.Get(chi.URLParam(r, "mailID"))
+	if err != nil {
+		switch {
+		case errors.Is(err, mailer.ErrMailNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return mail, true
+}
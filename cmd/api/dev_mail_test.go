@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sikozonpc/social/internal/mailer"
+)
+
+func TestCapturedMails(t *testing.T) {
+	capture, err := mailer.NewCaptureMailer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &mailer.Message{
+		Template: mailer.GuestInvitationTemplate,
+		Username: "Neema",
+		Email:    "neema@example.com",
+		Data:     mailer.InvitationData{GuestName: "Neema", EventName: "Harusi", CardCID: cardContentID},
+		Attachments: []mailer.Attachment{
+			{Filename: "card.png", ContentType: "image/png", Content: []byte("png"), Inline: true, ContentID: cardContentID},
+		},
+	}
+
+	if _, err := capture.SendMessage(msg, true); err != nil {
+		t.Fatal(err)
+	}
+
+	mails, err := capture.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApplication(t, config{})
+	app.mailer = capture
+	app.mailCapture = capture
+	mux := app.mount()
+
+	t.Run("should show a captured mail with its inline images", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/dev/mail/"+mails[0].ID+"/html", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if !strings.Contains(rr.Body.String(), `src="data:image/png;base64,`) {
+			t.Errorf("expected the inline card as a data uri, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("should not find unknown mails", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/dev/mail/unknown", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should not serve captured mails in production", func(t *testing.T) {
+		app := newTestApplication(t, config{env: "production"})
+		app.mailCapture = capture
+		mux := app.mount()
+
+		req, err := http.NewRequest(http.MethodGet, "/v1/dev/mail", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/sikozonpc/social/internal/store"
@@ -17,8 +19,10 @@ const eventCtx eventKey = "event"
 type CreateEventPayload struct {
 	Name           string `json:"title" validate:"required,max=100"`
 	Date           string `json:"date" validate:"required"`
+	Timezone       string `json:"timezone" validate:"omitempty,timezone"`
 	Location       string `json:"location"`
-	CardTemplateID string `json:"card_template_id"`
+	CardTemplateID string `json:"card_template_id" validate:"omitempty,numeric"`
+	RSVPDeadline   string `json:"rsvp_deadline" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
 }
 
 func (app *application) createEventHandler(w http.ResponseWriter, r *http.Request) {
@@ -30,18 +34,26 @@ func (app *application) createEventHandler(w http.ResponseWriter, r *http.Reques
 
 	if err := Validate.Struct(payload); err != nil {
 		app.badRequestResponse(w, r, err)
+		return
 	}
 
 	user := getUserFromContext(r)
+	ctx := r.Context()
 
-	event := &store.Event{
-		Name:     payload.Name,
-		Date:     payload.Date,
-		Location: payload.Location,
-		UserID:   user.ID,
+	if err := app.checkCardTemplateAccess(ctx, user, payload.CardTemplateID); err != nil {
+		app.eventCardTemplateError(w, r, err)
+		return
 	}
 
-	ctx := r.Context()
+	event := &store.Event{
+		Name:           payload.Name,
+		Date:           payload.Date,
+		Timezone:       payload.Timezone,
+		Location:       payload.Location,
+		CardTemplateID: payload.CardTemplateID,
+		RSVPDeadline:   payload.RSVPDeadline,
+		UserID:         user.ID,
+	}
 
 	if err := app.store.Events.Create(ctx, event); err != nil {
 		app.internalServerError(w, r, err)
@@ -96,6 +108,30 @@ func (app *application) getEventHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+type EventVerificationKey struct {
+	EventID   int64  `json:"event_id"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+}
+
+// getEventVerificationKeyHandler returns the public key door scanners use to
+// validate the event's card QR codes offline.
+func (app *application) getEventVerificationKeyHandler(w http.ResponseWriter, r *http.Request) {
+	key := app.eventVerificationKey(getEventFromCtx(r))
+
+	if err := app.jsonResponse(w, http.StatusOK, key); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) eventVerificationKey(event *store.Event) EventVerificationKey {
+	return EventVerificationKey{
+		EventID:   event.ID,
+		Algorithm: "Ed25519",
+		PublicKey: base64.RawURLEncoding.EncodeToString(app.cardSigner.PublicKey(event.ID)),
+	}
+}
+
 func (app *application) deleteEventHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "eventID")
 	id, err := strconv.ParseInt(idParam, 10, 64)
@@ -122,8 +158,10 @@ func (app *application) deleteEventHandler(w http.ResponseWriter, r *http.Reques
 type UpdateEventPayload struct {
 	Name           string `json:"name" validate:"omitempty"`
 	Date           string `json:"date"`
+	Timezone       string `json:"timezone" validate:"omitempty,timezone"`
 	Location       string `json:"location"`
-	CardTemplateID string `json:"card_template_id"`
+	CardTemplateID string `json:"card_template_id" validate:"omitempty,numeric"`
+	RSVPDeadline   string `json:"rsvp_deadline" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
 }
 
 func (app *application) updateEventHandler(w http.ResponseWriter, r *http.Request) {
@@ -146,17 +184,28 @@ func (app *application) updateEventHandler(w http.ResponseWriter, r *http.Reques
 	if payload.Date != "" {
 		event.Date = payload.Date
 	}
+	if payload.Timezone != "" {
+		event.Timezone = payload.Timezone
+	}
 	if payload.Location != "" {
 		event.Location = payload.Location
 	}
 	if payload.CardTemplateID != "" {
+		if err := app.checkCardTemplateAccess(r.Context(), getUserFromContext(r), payload.CardTemplateID); err != nil {
+			app.eventCardTemplateError(w, r, err)
+			return
+		}
 		event.CardTemplateID = payload.CardTemplateID
 	}
+	if payload.RSVPDeadline != "" {
+		event.RSVPDeadline = payload.RSVPDeadline
+	}
 
 	ctx := r.Context()
 
 	if err := app.updateEvent(ctx, event); err != nil {
 		app.internalServerError(w, r, err)
+		return
 	}
 
 	if err := app.jsonResponse(w, http.StatusOK, event); err != nil {
@@ -164,6 +213,15 @@ func (app *application) updateEventHandler(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+func (app *application) eventCardTemplateError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errCardTemplateMissing):
+		app.badRequestResponse(w, r, err)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
 func (app *application) eventsContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		idParam := chi.URLParam(r, "eventID")
@@ -196,6 +254,16 @@ func getEventFromCtx(r *http.Request) *store.Event {
 	return event
 }
 
+// eventLocation is the timezone of the event, UTC when it is not known.
+func eventLocation(event *store.Event) *time.Location {
+	loc, err := time.LoadLocation(event.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
 func (app *application) updateEvent(ctx context.Context, event *store.Event) error {
 	if err := app.store.Events.Update(ctx, event); err != nil {
 		return err
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/sikozonpc/social/internal/live"
 	"github.com/sikozonpc/social/internal/store"
 )
 
@@ -13,7 +17,95 @@ type guestKey string
 
 const guestCtx guestKey = "guest"
 
+type CreateGuestPayload struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	Email       string `json:"email" validate:"omitempty,email,max=255"`
+	PhoneNumber string `json:"phone_number" validate:"omitempty,phone"`
+	Status      string `json:"status" validate:"omitempty,oneof=pending accepted declined maybe no_response"`
+	Type        string `json:"type" validate:"omitempty,oneof=single double vip"`
+}
+
+// CreateEventGuests holds the guests of a create request, which may be sent
+// either as a single object or as an array of objects.
 type CreateEventGuests struct {
+	Guests []CreateGuestPayload `validate:"required,min=1,max=500,dive"`
+	isBulk bool
+}
+
+func (p *CreateEventGuests) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if len(data) > 0 && data[0] == '[' {
+		p.isBulk = true
+		return decoder.Decode(&p.Guests)
+	}
+
+	var guest CreateGuestPayload
+	if err := decoder.Decode(&guest); err != nil {
+		return err
+	}
+
+	p.Guests = []CreateGuestPayload{guest}
+	return nil
+}
+
+func (p CreateGuestPayload) toGuest(eventID int64) *store.Guest {
+	guest := &store.Guest{
+		Name:        p.Name,
+		Email:       p.Email,
+		PhoneNumber: p.PhoneNumber,
+		Status:      p.Status,
+		Type:        p.Type,
+		EventID:     eventID,
+	}
+
+	if guest.Status == "" {
+		guest.Status = store.GuestStatusPending
+	}
+	if guest.Type == "" {
+		guest.Type = store.GuestTypeSingle
+	}
+
+	return guest
+}
+
+func (app *application) createEventGuestsHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+
+	var payload CreateEventGuests
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	guests := make([]*store.Guest, len(payload.Guests))
+	for i, p := range payload.Guests {
+		guests[i] = p.toGuest(event.ID)
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Guests.CreateMany(ctx, guests); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	var data any = guests[0]
+	if payload.isBulk {
+		data = guests
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, data); err != nil {
+		app.internalServerError(w, r, err)
+	}
 }
 
 func (app *application) getEventGuestsHandler(w http.ResponseWriter, r *http.Request) {
@@ -55,17 +147,80 @@ func (app *application) getEventGuestsHandler(w http.ResponseWriter, r *http.Req
 	}
 }
 
-func (app *application) deleteGuestHandler(w http.ResponseWriter, r *http.Request) {
-	idParam := chi.URLParam(r, "guestID")
-	id, err := strconv.ParseInt(idParam, 10, 64)
-	if err != nil {
+func (app *application) getGuestHandler(w http.ResponseWriter, r *http.Request) {
+	guest := getGuestFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, guest); err != nil {
 		app.internalServerError(w, r, err)
+	}
+}
+
+type UpdateGuestPayload struct {
+	Name        string `json:"name" validate:"omitempty,max=255"`
+	Email       string `json:"email" validate:"omitempty,email,max=255"`
+	PhoneNumber string `json:"phone_number" validate:"omitempty,phone"`
+	Status      string `json:"status" validate:"omitempty,oneof=pending accepted declined maybe no_response"`
+	Type        string `json:"type" validate:"omitempty,oneof=single double vip"`
+}
+
+func (app *application) updateGuestHandler(w http.ResponseWriter, r *http.Request) {
+	guest := getGuestFromCtx(r)
+
+	var payload UpdateGuestPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Name != "" {
+		guest.Name = payload.Name
+	}
+	if payload.Email != "" {
+		guest.Email = payload.Email
+	}
+	if payload.PhoneNumber != "" {
+		guest.PhoneNumber = payload.PhoneNumber
+	}
+	statusChanged := payload.Status != "" && payload.Status != guest.Status
+	if payload.Status != "" {
+		guest.Status = payload.Status
+	}
+	if payload.Type != "" {
+		guest.Type = payload.Type
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Guests.Update(ctx, nil, guest); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
+	if statusChanged {
+		app.publishLive(ctx, live.TypeGuestStatus, guest.EventID, GuestStatusChange{GuestID: guest.ID, Status: guest.Status})
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, guest); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) deleteGuestHandler(w http.ResponseWriter, r *http.Request) {
+	guest := getGuestFromCtx(r)
+
 	ctx := r.Context()
 
-	if err := app.store.Guests.Delete(ctx, id); err != nil {
+	if err := app.store.Guests.Delete(ctx, guest.ID); err != nil {
 		switch {
 		case errors.Is(err, store.ErrNotFound):
 			app.notFoundResponse(w, r, err)
@@ -77,3 +232,44 @@ func (app *application) deleteGuestHandler(w http.ResponseWriter, r *http.Reques
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// guestsContextMiddleware loads the guest together with its event so the
+// event ownership checks can be applied to guest routes.
+func (app *application) guestsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "guestID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		guest, err := app.store.Guests.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		event, err := app.store.Events.GetByID(ctx, guest.EventID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, guestCtx, guest)
+		ctx = context.WithValue(ctx, eventCtx, event)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getGuestFromCtx(r *http.Request) *store.Guest {
+	guest, _ := r.Context().Value(guestCtx).(*store.Guest)
+	return guest
+}
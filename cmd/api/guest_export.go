@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/sikozonpc/social/internal/guestlist"
+)
+
+// exportGuestsHandler streams every guest of the event matching the optional
+// ?search= filter as a csv, xlsx or pdf document, selected with ?format=.
+func (app *application) exportGuestsHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = string(guestlist.FormatCSV)
+	}
+
+	f, err := guestlist.ParseFormat(format, "")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	search := r.URL.Query().Get("search")
+	if len(search) > 100 {
+		app.badRequestResponse(w, r, fmt.Errorf("search must be at most 100 characters"))
+		return
+	}
+
+	w.Header().Set("Content-Type", f.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="event-%d-guests.%s"`, event.ID, f))
+
+	ew, err := guestlist.NewWriter(w, f, fmt.Sprintf("%s - Guest List", event.Name))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Guests.ForEach(ctx, event.ID, search, ew.Write); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := ew.Close(); err != nil {
+		app.logger.Errorw("error writing guest export", "event", event.ID, "format", f, "error", err)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sikozonpc/social/internal/guestlist"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+const maxImportSize = 10 << 20 // 10mb
+
+type ImportRowError struct {
+	Line   int      `json:"line"`
+	Errors []string `json:"errors"`
+}
+
+type ImportDuplicate struct {
+	Line   int    `json:"line"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+type GuestImportReport struct {
+	DryRun     bool                 `json:"dry_run"`
+	Total      int                  `json:"total"`
+	Valid      int                  `json:"valid"`
+	Imported   int                  `json:"imported"`
+	Errors     []ImportRowError     `json:"errors"`
+	Duplicates []ImportDuplicate    `json:"duplicates"`
+	Preview    []CreateGuestPayload `json:"preview,omitempty"`
+}
+
+// importGuestsHandler reads a CSV or XLSX guest list uploaded as the "file"
+// form field. Rows that fail validation or duplicate an existing guest are
+// reported and skipped; with ?dry_run=true nothing is written.
+func (app *application) importGuestsHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	format, err := guestlist.ParseFormat(r.URL.Query().Get("format"), header.Filename)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	rows, err := guestlist.Read(file, format)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	// contacts already on the event, mapped to a description for the report
+	seen := make(map[string]string)
+	err = app.store.Guests.ForEach(ctx, event.ID, "", func(g *store.Guest) error {
+		for _, key := range contactKeys(g.Email, g.PhoneNumber) {
+			seen[key] = fmt.Sprintf("already invited as %s", g.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	report := GuestImportReport{
+		DryRun:     dryRun,
+		Total:      len(rows),
+		Errors:     []ImportRowError{},
+		Duplicates: []ImportDuplicate{},
+	}
+
+	var guests []*store.Guest
+	for _, row := range rows {
+		payload := CreateGuestPayload{
+			Name:        row.Guest.Name,
+			Email:       row.Guest.Email,
+			PhoneNumber: row.Guest.PhoneNumber,
+			Status:      row.Guest.Status,
+			Type:        row.Guest.Type,
+		}
+
+		if err := Validate.Struct(payload); err != nil {
+			report.Errors = append(report.Errors, ImportRowError{
+				Line:   row.Line,
+				Errors: validationMessages(err),
+			})
+			continue
+		}
+
+		keys := contactKeys(payload.Email, payload.PhoneNumber)
+		if reason, ok := firstSeen(seen, keys); ok {
+			report.Duplicates = append(report.Duplicates, ImportDuplicate{
+				Line:   row.Line,
+				Name:   payload.Name,
+				Reason: reason,
+			})
+			continue
+		}
+
+		for _, key := range keys {
+			seen[key] = fmt.Sprintf("same contact as line %d", row.Line)
+		}
+
+		guests = append(guests, payload.toGuest(event.ID))
+		if dryRun {
+			report.Preview = append(report.Preview, payload)
+		}
+	}
+
+	report.Valid = len(guests)
+
+	if dryRun || len(guests) == 0 {
+		if err := app.jsonResponse(w, http.StatusOK, report); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.Guests.Import(ctx, guests); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	report.Imported = len(guests)
+
+	if err := app.jsonResponse(w, http.StatusCreated, report); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// contactKeys returns the normalised email and phone number used to detect
+// the same person appearing twice in a guest list.
+func contactKeys(email, phone string) []string {
+	var keys []string
+
+	if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+		keys = append(keys, "email:"+email)
+	}
+
+	phone = strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+	if phone != "" {
+		keys = append(keys, "phone:"+phone)
+	}
+
+	return keys
+}
+
+func firstSeen(seen map[string]string, keys []string) (string, bool) {
+	for _, key := range keys {
+		if reason, ok := seen[key]; ok {
+			return reason, true
+		}
+	}
+
+	return "", false
+}
+
+func validationMessages(err error) []string {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return []string{err.Error()}
+	}
+
+	messages := make([]string, len(verrs))
+	for i, fe := range verrs {
+		messages[i] = fmt.Sprintf("invalid %s: failed on %q", fe.Field(), fe.Tag())
+	}
+
+	return messages
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCreateEventGuests(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"single guest", `{"name": "Jane Doe", "phone_number": "+255712345678"}`, http.StatusCreated},
+		{"bulk guests", `[{"name": "Jane Doe"}, {"name": "John Doe", "type": "double"}]`, http.StatusCreated},
+		{"invalid email", `{"name": "Jane Doe", "email": "not-an-email"}`, http.StatusBadRequest},
+		{"invalid phone", `{"name": "Jane Doe", "phone_number": "call me"}`, http.StatusBadRequest},
+		{"invalid status", `{"name": "Jane Doe", "status": "sleeping"}`, http.StatusBadRequest},
+		{"invalid type in bulk", `[{"name": "Jane Doe"}, {"name": "John Doe", "type": "triple"}]`, http.StatusBadRequest},
+		{"unknown field", `{"name": "Jane Doe", "age": 30}`, http.StatusBadRequest},
+		{"empty bulk", `[]`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/v1/events/1/guests", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+		})
+	}
+}
+
+func TestUpdateGuest(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should update the guest status", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPatch, "/v1/guests/1", strings.NewReader(`{"status": "accepted"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if !strings.Contains(rr.Body.String(), `"status":"accepted"`) {
+			t.Errorf("expected updated status in response, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("should reject an unknown type", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPatch, "/v1/guests/1", strings.NewReader(`{"type": "triple"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestImportGuests(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csv := "Full Name;Phone Number;Email;Type\n" +
+		"Jane Doe;+255 712 345 678;jane@example.com;single\n" +
+		"John Doe;0754 000 111;;double\n" +
+		"Jane Again;+255712345678;;single\n" +
+		";0700 000 000;;single\n" +
+		"Bad Mail;;nope;single\n"
+
+	newRequest := func(t *testing.T, url string) *http.Request {
+		body := new(bytes.Buffer)
+		mw := multipart.NewWriter(body)
+
+		fw, err := mw.CreateFormFile("file", "guests.csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(csv))
+		mw.Close()
+
+		req, err := http.NewRequest(http.MethodPost, url, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return req
+	}
+
+	t.Run("dry run reports errors and duplicates", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "/v1/events/1/guests/import?dry_run=true"), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var resp struct {
+			Data GuestImportReport `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+
+		report := resp.Data
+		if report.Total != 5 || report.Valid != 2 || report.Imported != 0 {
+			t.Errorf("unexpected totals: %+v", report)
+		}
+
+		if len(report.Duplicates) != 1 || report.Duplicates[0].Line != 4 {
+			t.Errorf("expected a duplicate on line 4, got %+v", report.Duplicates)
+		}
+
+		if len(report.Errors) != 2 || report.Errors[0].Line != 5 || report.Errors[1].Line != 6 {
+			t.Errorf("expected errors on lines 5 and 6, got %+v", report.Errors)
+		}
+	})
+
+	t.Run("commit imports the valid rows", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "/v1/events/1/guests/import"), mux)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		if !strings.Contains(rr.Body.String(), `"imported":2`) {
+			t.Errorf("expected two imported guests, got %s", rr.Body.String())
+		}
+	})
+}
+
+func TestExportGuests(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		format      string
+		expected    int
+		contentType string
+		prefix      string
+	}{
+		{"csv", http.StatusOK, "text/csv; charset=utf-8", "Name,Phone Number"},
+		{"xlsx", http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "PK"},
+		{"pdf", http.StatusOK, "application/pdf", "%PDF"},
+		{"docx", http.StatusBadRequest, "application/json", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/v1/events/1/guests/export?format="+tt.format, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+
+			if ct := rr.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("expected content type %q, got %q", tt.contentType, ct)
+			}
+
+			if !strings.HasPrefix(rr.Body.String(), tt.prefix) {
+				t.Errorf("expected body to start with %q", tt.prefix)
+			}
+		})
+	}
+}
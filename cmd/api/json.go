@@ -3,14 +3,21 @@ package main
 import (
 	"encoding/json"
 	"net/http"
+	"regexp"
 
 	"github.com/go-playground/validator/v10"
 )
 
 var Validate *validator.Validate
 
+var phoneRX = regexp.MustCompile(`^\+?[0-9][0-9 ()-]{6,19}$`)
+
 func init() {
 	Validate = validator.New(validator.WithRequiredStructEnabled())
+
+	Validate.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		return phoneRX.MatchString(fl.Field().String())
+	})
 }
 
 func writeJSON(w http.ResponseWriter, status int, data any) error {
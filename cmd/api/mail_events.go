@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sikozonpc/social/internal/mailer"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+// mailEventsHandler receives the event webhook of the mail provider and
+// suppresses the addresses that bounced, were dropped, reported mail as spam
+// or unsubscribed. Other events are acknowledged and ignored.
+func (app *application) mailEventsHandler(w http.ResponseWriter, r *http.Request) {
+	events, err := app.mailEvents.ParseEvents(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, mailer.ErrInvalidSignature):
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	for _, event := range events {
+		if event.Email == "" || !event.Suppresses() {
+			continue
+		}
+
+		suppression := &store.Suppression{
+			Email:  event.Email,
+			Reason: event.Event,
+			Detail: event.Reason,
+		}
+
+		if err := app.store.Suppressions.Suppress(r.Context(), suppression); err != nil {
+			// the provider retries the whole batch, suppressing is idempotent
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		app.logger.Infow("email suppressed", "email", event.Email, "event", event.Event, "reason", event.Reason)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isEmailSuppressed is used by the mailer before every mail sent.
+func (app *application) isEmailSuppressed(email string) (bool, error) {
+	return app.store.Suppressions.IsSuppressed(context.Background(), strings.ToLower(email))
+}
+
+// getSuppressionsHandler lists the suppressed addresses, filtered with
+// ?search=.
+func (app *application) getSuppressionsHandler(w http.ResponseWriter, r *http.Request) {
+	fq := store.PaginatedFeedQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	fq, err := fq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	suppressions, err := app.store.Suppressions.GetAll(r.Context(), fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, suppressions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteSuppressionHandler lets mail be sent to an address again.
+func (app *application) deleteSuppressionHandler(w http.ResponseWriter, r *http.Request) {
+	email := strings.ToLower(chi.URLParam(r, "email"))
+
+	if err := app.store.Suppressions.Delete(r.Context(), email); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
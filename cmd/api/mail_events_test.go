@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sikozonpc/social/internal/mailer"
+)
+
+func TestMailEvents(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `[
+		{"email": "Neema@Example.com", "event": "bounce", "type": "bounce", "reason": "550 5.1.1 unknown user"},
+		{"email": "amani@example.com", "event": "bounce", "type": "blocked"},
+		{"email": "baraka@example.com", "event": "spamreport"},
+		{"email": "amani@example.com", "event": "delivered"}
+	]`
+
+	req, err := http.NewRequest(http.MethodPost, "/v1/mail/events", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := executeRequest(req, mux)
+
+	checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+	for email, want := range map[string]bool{
+		"neema@example.com":  true,
+		"baraka@example.com": true,
+		"amani@example.com":  false,
+	} {
+		suppressed, err := app.isEmailSuppressed(email)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if suppressed != want {
+			t.Errorf("expected %s suppressed: %v", email, want)
+		}
+	}
+
+	t.Run("should skip suppressed addresses", func(t *testing.T) {
+		mock := &mailer.MockClient{}
+		client := mailer.WithSuppressions(mock, app.isEmailSuppressed)
+
+		if _, err := client.Send(mailer.ReminderTemplate, "Neema", "NEEMA@example.com", nil, true); err != mailer.ErrSuppressed {
+			t.Errorf("expected the mail to be suppressed, got %v", err)
+		}
+
+		if len(mock.Sent) != 0 {
+			t.Errorf("expected no mail to be sent, got %+v", mock.Sent)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		expected int
+	}{
+		{"malformed events", http.MethodPost, "/v1/mail/events", `{"email": "neema@example.com"}`, http.StatusBadRequest},
+		{"list suppressions", http.MethodGet, "/v1/suppressions", "", http.StatusOK},
+		{"lift a suppression", http.MethodDelete, "/v1/suppressions/neema@example.com", "", http.StatusNoContent},
+		{"lift a missing suppression", http.MethodDelete, "/v1/suppressions/amani@example.com", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sikozonpc/social/internal/mailer"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+var (
+	errInvalidLocale = errors.New("locale must be a language tag such as en or sw-TZ")
+
+	localeRegexp = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+)
+
+// MailTemplateSummary is an embedded template with the locales it is
+// overridden in.
+type MailTemplateSummary struct {
+	Name      string                 `json:"name"`
+	Overrides []MailTemplateOverride `json:"overrides"`
+}
+
+type MailTemplateOverride struct {
+	Locale    string `json:"locale"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// MailTemplateView is the template used for a locale, the override when
+// there is one.
+type MailTemplateView struct {
+	Name       string        `json:"name"`
+	Locale     string        `json:"locale"`
+	Overridden bool          `json:"overridden"`
+	UpdatedAt  string        `json:"updated_at,omitempty"`
+	Source     mailer.Source `json:"source"`
+}
+
+type MailTemplatePayload struct {
+	Subject string `json:"subject" validate:"required,max=1000"`
+	HTML    string `json:"html" validate:"required,max=100000"`
+	Text    string `json:"text" validate:"max=100000"`
+}
+
+type MailTemplateValidation struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// getMailTemplatesHandler lists the mail templates and their overrides.
+func (app *application) getMailTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	names, err := mailer.Templates()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	overrides, err := app.store.MailTemplates.GetAll(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	byName := make(map[string][]MailTemplateOverride)
+	for _, t := range overrides {
+		byName[t.Name] = append(byName[t.Name], MailTemplateOverride{Locale: t.Locale, UpdatedAt: t.UpdatedAt})
+	}
+
+	summaries := make([]MailTemplateSummary, 0, len(names))
+	for _, name := range names {
+		summary := MailTemplateSummary{Name: name, Overrides: byName[name]}
+		if summary.Overrides == nil {
+			summary.Overrides = []MailTemplateOverride{}
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, summaries); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getMailTemplateHandler returns the template used for ?locale=, the
+// embedded one when it is not overridden.
+func (app *application) getMailTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	name, locale, ok := app.mailTemplateParams(w, r)
+	if !ok {
+		return
+	}
+
+	view, err := app.mailTemplateView(r, name, locale)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, view); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// updateMailTemplateHandler overrides the template for ?locale= once the
+// edit parses and renders with the template's sample data.
+func (app *application) updateMailTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	name, locale, ok := app.mailTemplateParams(w, r)
+	if !ok {
+		return
+	}
+
+	src, ok := app.readMailTemplatePayload(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := src.Render(mailTemplateSample(name)); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	t := &store.MailTemplate{
+		Name:      name,
+		Locale:    locale,
+		Subject:   src.Subject,
+		HTML:      src.HTML,
+		Text:      src.Text,
+		UpdatedBy: getUserFromContext(r).ID,
+	}
+
+	if err := app.store.MailTemplates.Save(r.Context(), t); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, t); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteMailTemplateHandler removes the override for ?locale=.
+func (app *application) deleteMailTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	name, locale, ok := app.mailTemplateParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.store.MailTemplates.Delete(r.Context(), name, locale); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateMailTemplateHandler reports whether an edit parses and renders
+// with the template's sample data, without saving it.
+func (app *application) validateMailTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	name, _, ok := app.mailTemplateParams(w, r)
+	if !ok {
+		return
+	}
+
+	src, ok := app.readMailTemplatePayload(w, r)
+	if !ok {
+		return
+	}
+
+	validation := MailTemplateValidation{Valid: true}
+	if _, err := src.Render(mailTemplateSample(name)); err != nil {
+		validation = MailTemplateValidation{Error: err.Error()}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, validation); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// previewMailTemplateHandler renders a template with its sample data: the
+// edit in the body when given, the template used for ?locale= otherwise.
+// ?format=html returns the HTML body for a browser to show.
+func (app *application) previewMailTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	name, locale, ok := app.mailTemplateParams(w, r)
+	if !ok {
+		return
+	}
+
+	var src *mailer.Source
+	if r.ContentLength != 0 {
+		if src, ok = app.readMailTemplatePayload(w, r); !ok {
+			return
+		}
+	} else {
+		view, err := app.mailTemplateView(r, name, locale)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		src = &view.Source
+	}
+
+	rendered, err := src.Render(mailTemplateSample(name))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(rendered.HTML))
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, rendered); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// mailTemplateOverride finds the override of a template for a locale,
+// falling back to the default locale. It is used by the mailer for every
+// mail sent.
+func (app *application) mailTemplateOverride(templateFile, locale string) (*mailer.Source, error) {
+	ctx := context.Background()
+
+	locales := []string{locale}
+	if locale != mailer.DefaultLocale {
+		locales = append(locales, mailer.DefaultLocale)
+	}
+
+	for _, l := range locales {
+		t, err := app.store.MailTemplates.Get(ctx, templateFile, l)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		return &mailer.Source{Subject: t.Subject, HTML: t.HTML, Text: t.Text}, nil
+	}
+
+	return nil, nil
+}
+
+func (app *application) mailTemplateView(r *http.Request, name, locale string) (*MailTemplateView, error) {
+	view := &MailTemplateView{Name: name, Locale: locale}
+
+	t, err := app.store.MailTemplates.Get(r.Context(), name, locale)
+	switch {
+	case err == nil:
+		view.Overridden = true
+		view.UpdatedAt = t.UpdatedAt
+		view.Source = mailer.Source{Subject: t.Subject, HTML: t.HTML, Text: t.Text}
+		return view, nil
+	case !errors.Is(err, store.ErrNotFound):
+		return nil, err
+	}
+
+	src, err := mailer.EmbeddedSource(name)
+	if err != nil {
+		return nil, err
+	}
+
+	view.Source = *src
+	return view, nil
+}
+
+// mailTemplateParams reads the template name, which must be an embedded
+// template, and ?locale=, the default locale when not given.
+func (app *application) mailTemplateParams(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	name := chi.URLParam(r, "name")
+	if _, ok := mailer.SampleData(name); !ok {
+		app.notFoundResponse(w, r, mailer.ErrUnknownTemplate)
+		return "", "", false
+	}
+
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = mailer.DefaultLocale
+	}
+
+	if !localeRegexp.MatchString(locale) {
+		app.badRequestResponse(w, r, errInvalidLocale)
+		return "", "", false
+	}
+
+	return name, locale, true
+}
+
+// mailTemplateSample is the data edits of a template are validated and
+// previewed with. Executing against the real data types catches references
+// to fields the template will never get.
+func mailTemplateSample(name string) any {
+	data, _ := mailer.SampleData(name)
+	return data
+}
+
+func (app *application) readMailTemplatePayload(w http.ResponseWriter, r *http.Request) (*mailer.Source, bool) {
+	var payload MailTemplatePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	return &mailer.Source{Subject: payload.Subject, HTML: payload.HTML, Text: payload.Text}, true
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMailTemplates(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		expected int
+		contains string
+	}{
+		{"list", http.MethodGet, "/v1/mail-templates", "", http.StatusOK, `"locale":"sw"`},
+		{"embedded", http.MethodGet, "/v1/mail-templates/event_reminder.tmpl", "", http.StatusOK, `"overridden":false`},
+		{"overridden", http.MethodGet, "/v1/mail-templates/event_reminder.tmpl?locale=sw", "", http.StatusOK, `"overridden":true`},
+		{"unknown template", http.MethodGet, "/v1/mail-templates/missing.tmpl", "", http.StatusNotFound, ""},
+		{"invalid locale", http.MethodGet, "/v1/mail-templates/event_reminder.tmpl?locale=../en", "", http.StatusBadRequest, ""},
+		{"save", http.MethodPut, "/v1/mail-templates/event_reminder.tmpl?locale=sw", `{"subject": "Kumbusho: {{.EventName}}", "html": "<p>Habari {{.GuestName}}</p>"}`, http.StatusOK, `"locale":"sw"`},
+		{"save unknown field", http.MethodPut, "/v1/mail-templates/event_reminder.tmpl", `{"subject": "{{.Nope}}", "html": "<p>hi</p>"}`, http.StatusBadRequest, ""},
+		{"save broken syntax", http.MethodPut, "/v1/mail-templates/event_reminder.tmpl", `{"subject": "Hi", "html": "<p>{{.GuestName</p>"}`, http.StatusBadRequest, ""},
+		{"validate", http.MethodPost, "/v1/mail-templates/user_invitation.tmpl/validate", `{"subject": "Hi", "html": "<p>{{.Missing}}</p>"}`, http.StatusOK, `"valid":false`},
+		{"preview edit", http.MethodPost, "/v1/mail-templates/event_reminder.tmpl/preview", `{"subject": "Reminder: {{.EventName}}", "html": "<p>{{.GuestName}}</p>"}`, http.StatusOK, "Reminder: Harusi"},
+		{"preview stored", http.MethodPost, "/v1/mail-templates/event_reminder.tmpl/preview?format=html", "", http.StatusOK, "Amani"},
+		{"delete", http.MethodDelete, "/v1/mail-templates/event_reminder.tmpl?locale=sw", "", http.StatusNoContent, ""},
+		{"delete missing", http.MethodDelete, "/v1/mail-templates/event_reminder.tmpl?locale=fr", "", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+
+			if !strings.Contains(rr.Body.String(), tt.contains) {
+				t.Errorf("expected response to contain %q, got %s", tt.contains, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("should fall back to the default locale", func(t *testing.T) {
+		src, err := app.mailTemplateOverride("event_reminder.tmpl", "sw")
+		if err != nil || src == nil {
+			t.Fatalf("expected the sw override, got %v %v", src, err)
+		}
+
+		if src, err := app.mailTemplateOverride("event_reminder.tmpl", "fr"); err != nil || src != nil {
+			t.Errorf("expected no override, got %v %v", src, err)
+		}
+	})
+}
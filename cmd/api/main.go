@@ -1,16 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"expvar"
+	"fmt"
+	"os"
 	"runtime"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/sikozonpc/social/internal/auth"
+	"github.com/sikozonpc/social/internal/blob"
 	"github.com/sikozonpc/social/internal/db"
 	"github.com/sikozonpc/social/internal/env"
+	"github.com/sikozonpc/social/internal/live"
 	"github.com/sikozonpc/social/internal/mailer"
+	"github.com/sikozonpc/social/internal/messenger"
 	"github.com/sikozonpc/social/internal/ratelimiter"
+	"github.com/sikozonpc/social/internal/render"
+	"github.com/sikozonpc/social/internal/sms"
 	"github.com/sikozonpc/social/internal/store"
 	"github.com/sikozonpc/social/internal/store/cache"
 	"go.uber.org/zap"
@@ -18,6 +27,10 @@ import (
 
 const version = "1.1.0"
 
+// devSecret is the default of the secrets signing links and card codes, only
+// accepted outside production.
+const devSecret = "example"
+
 //	@title			GopherSocial API
 //	@description	API for GopherSocial, a social network for gohpers
 //	@termsOfService	http://swagger.io/terms/
@@ -55,9 +68,22 @@ func main() {
 		env: env.GetString("ENV", "development"),
 		mail: mailConfig{
 			exp:       time.Hour * 24 * 3, // 3 days
+			mailer:    env.GetString("MAILER", "capture"),
 			fromEmail: env.GetString("FROM_EMAIL", ""),
+			smtp: mailer.SMTPConfig{
+				Host:               env.GetString("SMTP_HOST", "localhost"),
+				Port:               env.GetInt("SMTP_PORT", 1025),
+				Username:           env.GetString("SMTP_USERNAME", ""),
+				Password:           env.GetString("SMTP_PASSWORD", ""),
+				TLS:                env.GetString("SMTP_TLS", mailer.TLSStartTLS),
+				InsecureSkipVerify: env.GetBool("SMTP_INSECURE_SKIP_VERIFY", false),
+			},
+			capture: captureConfig{
+				dir: env.GetString("MAIL_CAPTURE_DIR", ""),
+			},
 			sendGrid: sendGridConfig{
-				apiKey: env.GetString("SENDGRID_API_KEY", ""),
+				apiKey:     env.GetString("SENDGRID_API_KEY", ""),
+				webhookKey: env.GetString("SENDGRID_WEBHOOK_PUBLIC_KEY", ""),
 			},
 			mailTrap: mailTrapConfig{
 				apiKey: env.GetString("MAILTRAP_API_KEY", ""),
@@ -73,18 +99,71 @@ func main() {
 				exp:    time.Hour * 24 * 3, // 3 days
 				iss:    "gophersocial",
 			},
+			card: cardSigningConfig{
+				secret: env.GetString("CARD_SIGNING_SECRET", devSecret),
+			},
 		},
 		rateLimiter: ratelimiter.Config{
 			RequestsPerTimeFrame: env.GetInt("RATELIMITER_REQUESTS_COUNT", 20),
 			TimeFrame:            time.Second * 5,
 			Enabled:              env.GetBool("RATE_LIMITER_ENABLED", true),
 		},
+		cards: cardsConfig{
+			storage:   env.GetString("CARD_STORAGE", "local"),
+			assetsDir: env.GetString("CARD_ASSETS_DIR", "./data"),
+			urlSecret: env.GetString("CARD_URL_SECRET", devSecret),
+			blobsURL:  env.GetString("CARD_BLOBS_URL", "http://localhost:8080/v1/blobs"),
+			urlExpiry: time.Hour,
+			workers:   env.GetInt("CARD_WORKERS", 4),
+			s3: blob.S3Config{
+				Endpoint:  env.GetString("S3_ENDPOINT", "localhost:9000"),
+				Region:    env.GetString("S3_REGION", ""),
+				Bucket:    env.GetString("S3_BUCKET", "cards"),
+				AccessKey: env.GetString("S3_ACCESS_KEY", "minioadmin"),
+				SecretKey: env.GetString("S3_SECRET_KEY", "minioadmin"),
+				UseSSL:    env.GetBool("S3_USE_SSL", false),
+			},
+		},
+		scheduler: schedulerConfig{
+			rsvpInterval:     time.Minute,
+			reminderInterval: time.Minute,
+			mailInterval:     5 * time.Second,
+			cardInterval:     5 * time.Second,
+			blobInterval:     time.Hour,
+		},
+		sms: smsConfig{
+			provider:    env.GetString("SMS_PROVIDER", "stdout"),
+			countryCode: env.GetString("SMS_DEFAULT_COUNTRY_CODE", "255"),
+			file:        env.GetString("SMS_SANDBOX_FILE", "./data/sms.log"),
+			http: sms.HTTPConfig{
+				URL:            env.GetString("SMS_API_URL", "http://localhost:8025/messages"),
+				APIKey:         env.GetString("SMS_API_KEY", ""),
+				From:           env.GetString("SMS_FROM", ""),
+				CallbackURL:    env.GetString("SMS_CALLBACK_URL", ""),
+				CallbackSecret: env.GetString("SMS_CALLBACK_SECRET", ""),
+			},
+		},
+		messenger: messengerConfig{
+			provider:  env.GetString("MESSENGER_PROVIDER", "stdout"),
+			imagesDir: env.GetString("MESSENGER_SANDBOX_DIR", ""),
+			http: messenger.HTTPConfig{
+				URL:            env.GetString("MESSENGER_API_URL", "http://localhost:8026/messages"),
+				APIKey:         env.GetString("MESSENGER_API_KEY", ""),
+				From:           env.GetString("MESSENGER_FROM", ""),
+				CallbackURL:    env.GetString("MESSENGER_CALLBACK_URL", ""),
+				CallbackSecret: env.GetString("MESSENGER_CALLBACK_SECRET", ""),
+			},
+		},
 	}
 
 	// Logger
 	logger := zap.Must(zap.NewProduction()).Sugar()
 	defer logger.Sync()
 
+	if err := checkProductionConfig(cfg); err != nil {
+		logger.Fatal(err)
+	}
+
 	// Main Database
 	db, err := db.New(
 		cfg.db.addr,
@@ -108,6 +187,18 @@ func main() {
 		defer rdb.Close()
 	}
 
+	// Live check-in feed, shared between instances through redis when enabled
+	var broker live.Broker = live.NewMemoryBroker()
+	if cfg.redisCfg.enabled {
+		redisBroker, err := live.NewRedisBroker(context.Background(), rdb)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		defer redisBroker.Close()
+		broker = redisBroker
+	}
+
 	// Rate limiter
 	rateLimiter := ratelimiter.NewFixedWindowLimiter(
 		cfg.rateLimiter.RequestsPerTimeFrame,
@@ -115,8 +206,32 @@ func main() {
 	)
 
 	// Mailer
-	// mailer := mailer.NewSendgrid(cfg.mail.sendGrid.apiKey, cfg.mail.fromEmail)
-	mailtrap, err := mailer.NewMailTrapClient(cfg.mail.mailTrap.apiKey, cfg.mail.fromEmail)
+	mailClient, mailCapture, err := newMailer(cfg.mail)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infow("mailer selected", "mailer", cfg.mail.mailer)
+
+	// Bounces and complaints, unsigned events are only accepted outside
+	// production
+	var mailEvents *mailer.EventWebhook
+	if cfg.mail.sendGrid.webhookKey != "" || cfg.env != "production" {
+		mailEvents, err = mailer.NewEventWebhook(cfg.mail.sendGrid.webhookKey)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	} else {
+		logger.Warn("mail event webhook disabled, SENDGRID_WEBHOOK_PUBLIC_KEY is not set")
+	}
+
+	// SMS
+	smsClient, err := newSMSClient(cfg.sms)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	// Card messages to the guests' phones
+	messengerClient, err := newMessengerClient(cfg.messenger, cfg.sms.countryCode)
 	if err != nil {
 		logger.Fatal(err)
 	}
@@ -128,6 +243,22 @@ func main() {
 		cfg.auth.token.iss,
 	)
 
+	// Card QR code signer
+	cardSigner := auth.NewCardSigner(cfg.auth.card.secret)
+
+	// Live feed tickets
+	streamTickets := auth.NewTicketSigner(cfg.auth.token.secret)
+
+	// Template images and rendered cards
+	blobs, err := newBlobStore(cfg.cards)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infow("card storage selected", "storage", cfg.cards.storage)
+
+	// Card renderer
+	renderer := render.New(blob.FS(context.Background(), blobs))
+
 	store := store.NewStorage(db)
 	cacheStorage := cache.NewRedisStorage(rdb)
 
@@ -136,11 +267,27 @@ func main() {
 		store:         store,
 		cacheStorage:  cacheStorage,
 		logger:        logger,
-		mailer:        mailtrap,
+		mailer:        mailClient,
+		mailCapture:   mailCapture,
+		mailEvents:    mailEvents,
 		authenticator: jwtAuthenticator,
 		rateLimiter:   rateLimiter,
+		renderer:      renderer,
+		cardSigner:    cardSigner,
+		streamTickets: streamTickets,
+		broker:        broker,
+		sms:           smsClient,
+		messenger:     messengerClient,
+		blobs:         blobs,
 	}
 
+	// mail templates edited by organisers replace the embedded ones and
+	// suppressed addresses are skipped
+	app.mailer = mailer.WithSuppressions(
+		mailer.WithOverrides(mailClient, app.mailTemplateOverride),
+		app.isEmailSuppressed,
+	)
+
 	// Metrics collected
 	expvar.NewString("version").Set(version)
 	expvar.Publish("database", expvar.Func(func() any {
@@ -154,3 +301,100 @@ func main() {
 
 	logger.Fatal(app.run(mux))
 }
+
+// checkProductionConfig refuses the development defaults in production,
+// where the sandbox providers would silently drop mails and messages and
+// the default secrets would let anyone sign download links and check-in
+// codes.
+func checkProductionConfig(cfg config) error {
+	if cfg.env != "production" {
+		return nil
+	}
+
+	switch {
+	case cfg.mail.mailer == "capture":
+		return errors.New("MAILER must be set to a mail provider in production")
+	case cfg.sms.provider != "http":
+		return errors.New("SMS_PROVIDER must be http in production")
+	case cfg.messenger.provider != "http":
+		return errors.New("MESSENGER_PROVIDER must be http in production")
+	case cfg.cards.storage == "local" && (cfg.cards.urlSecret == devSecret || cfg.cards.urlSecret == ""):
+		return errors.New("CARD_URL_SECRET must be set in production")
+	case cfg.auth.card.secret == devSecret || cfg.auth.card.secret == "":
+		return errors.New("CARD_SIGNING_SECRET must be set in production")
+	}
+
+	return nil
+}
+
+// newSMSClient returns the SMS client of the configured provider. Messages
+// are printed to stdout when no provider is set up.
+func newSMSClient(cfg smsConfig) (sms.Client, error) {
+	switch cfg.provider {
+	case "http":
+		cfg.http.CountryCode = cfg.countryCode
+		return sms.NewHTTPClient(cfg.http)
+	case "file":
+		return sms.NewFileSandboxClient(cfg.file, cfg.countryCode)
+	case "stdout":
+		return sms.NewSandboxClient(os.Stdout, cfg.countryCode), nil
+	default:
+		return nil, fmt.Errorf("unknown sms provider %q", cfg.provider)
+	}
+}
+
+// newMessengerClient returns the messenger client of the configured
+// provider. Messages are printed to stdout when no provider is set up.
+func newMessengerClient(cfg messengerConfig, countryCode string) (messenger.Client, error) {
+	switch cfg.provider {
+	case "http":
+		cfg.http.CountryCode = countryCode
+		return messenger.NewHTTPClient(cfg.http)
+	case "stdout":
+		return messenger.NewSandboxClient(os.Stdout, cfg.imagesDir, countryCode), nil
+	default:
+		return nil, fmt.Errorf("unknown messenger provider %q", cfg.provider)
+	}
+}
+
+// newBlobStore returns the storage of template images and rendered cards.
+// Files of the local storage can be copied to a bucket as they are, the keys
+// are the same.
+func newBlobStore(cfg cardsConfig) (blob.Store, error) {
+	switch cfg.storage {
+	case "local":
+		return blob.NewLocalStore(blob.LocalConfig{
+			Dir:     cfg.assetsDir,
+			BaseURL: cfg.blobsURL,
+			Secret:  cfg.urlSecret,
+		})
+	case "s3":
+		return blob.NewS3Store(context.Background(), cfg.s3)
+	default:
+		return nil, fmt.Errorf("unknown card storage %q", cfg.storage)
+	}
+}
+
+// newMailer returns the configured mailer. The capture mailer, the default
+// in development, is also returned as such for its mails to be served.
+func newMailer(cfg mailConfig) (mailer.Client, *mailer.CaptureMailer, error) {
+	switch cfg.mailer {
+	case "capture":
+		capture, err := mailer.NewCaptureMailer(cfg.capture.dir)
+		return capture, capture, err
+	case "smtp":
+		cfg.smtp.FromEmail = cfg.fromEmail
+		client, err := mailer.NewSMTPClient(cfg.smtp)
+		return client, nil, err
+	case "mailtrap":
+		client, err := mailer.NewMailTrapClient(cfg.mailTrap.apiKey, cfg.fromEmail)
+		return client, nil, err
+	case "sendgrid":
+		if cfg.sendGrid.apiKey == "" {
+			return nil, nil, fmt.Errorf("sendgrid api key is required")
+		}
+		return mailer.NewSendgrid(cfg.sendGrid.apiKey, cfg.fromEmail), nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown mailer %q", cfg.mailer)
+	}
+}
@@ -7,26 +7,22 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/sikozonpc/social/internal/store"
 )
 
 func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			app.unauthorizedErrorResponse(w, r, fmt.Errorf("authorization header is missing"))
-			return
-		}
-
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			app.unauthorizedErrorResponse(w, r, fmt.Errorf("authorization header is malformed"))
+		token, err := bearerToken(r)
+		if err != nil {
+			app.unauthorizedErrorResponse(w, r, err)
 			return
 		}
 
-		token := parts[1]
 		jwtToken, err := app.authenticator.ValidateToken(token)
 		if err != nil {
 			app.unauthorizedErrorResponse(w, r, err)
@@ -54,6 +50,71 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// bearerToken reads the token from the Authorization header.
+func bearerToken(r *http.Request) (string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return "", fmt.Errorf("authorization header is missing")
+	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", fmt.Errorf("authorization header is malformed")
+	}
+
+	return parts[1], nil
+}
+
+// streamAuthMiddleware authenticates the live feeds of {eventID} with a
+// ?ticket= from createStreamTicketHandler, as browsers cannot set headers
+// on EventSource and WebSocket connections. Other clients can still send
+// their bearer token.
+func (app *application) streamAuthMiddleware(next http.Handler) http.Handler {
+	withToken := app.AuthTokenMiddleware(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ticket := r.URL.Query().Get("ticket")
+		if ticket == "" || r.Header.Get("Authorization") != "" {
+			withToken.ServeHTTP(w, r)
+			return
+		}
+
+		eventID, err := strconv.ParseInt(chi.URLParam(r, "eventID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		userID, err := app.streamTickets.Verify(ticket, eventID)
+		if err != nil {
+			app.unauthorizedErrorResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		user, err := app.getUser(ctx, userID)
+		if err != nil {
+			app.unauthorizedErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, userCtx, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func isStreamRequest(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream") ||
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// isCardExportRequest matches the zip of an event's cards, which is written
+// as it is built and can outlast the request timeout.
+func isCardExportRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet && strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/cards/export")
+}
+
 func (app *application) BasicAuthMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -98,12 +159,7 @@ func (app *application) checkEventOwnership(requiredRole string, next http.Handl
 		user := getUserFromContext(r)
 		event := getEventFromCtx(r)
 
-		if event.UserID == user.ID {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		allowed, err := app.checkRolePrecedence(r.Context(), user, requiredRole)
+		allowed, err := app.canManageEvent(r.Context(), user, event, requiredRole)
 		if err != nil {
 			app.internalServerError(w, r, err)
 			return
@@ -118,6 +174,36 @@ func (app *application) checkEventOwnership(requiredRole string, next http.Handl
 	})
 }
 
+// checkRole lets through users holding at least requiredRole.
+func (app *application) checkRole(requiredRole string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, err := app.checkRolePrecedence(r.Context(), getUserFromContext(r), requiredRole)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			if !allowed {
+				app.forbiddenResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// canManageEvent reports whether user owns event or holds at least
+// requiredRole.
+func (app *application) canManageEvent(ctx context.Context, user *store.User, event *store.Event, requiredRole string) (bool, error) {
+	if event.UserID == user.ID {
+		return true, nil
+	}
+
+	return app.checkRolePrecedence(ctx, user, requiredRole)
+}
+
 func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
 	role, err := app.store.Roles.GetByName(ctx, roleName)
 	if err != nil {
@@ -151,6 +237,21 @@ func (app *application) getUser(ctx context.Context, userID int64) (*store.User,
 	return user, nil
 }
 
+func (app *application) timeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withTimeout := middleware.Timeout(timeout)(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isStreamRequest(r) || isCardExportRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			withTimeout.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (app *application) RateLimiterMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if app.config.rateLimiter.Enabled {
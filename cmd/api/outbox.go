@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sikozonpc/social/internal/mailer"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+const (
+	maxMailAttempts = 8
+	mailBatchSize   = 50
+	// mailLease is how long a claimed mail is left to its worker before it
+	// is handed out again.
+	mailLease = 5 * time.Minute
+	// mailBackoff is the wait after the first failed attempt, doubled on
+	// every following one.
+	mailBackoff = 30 * time.Second
+)
+
+// mailPayload is the part of a mailer.Message kept in the outbox payload.
+type mailPayload struct {
+	Data        json.RawMessage     `json:"data"`
+	ReplyTo     string              `json:"reply_to,omitempty"`
+	Headers     map[string]string   `json:"headers,omitempty"`
+	Attachments []mailer.Attachment `json:"attachments,omitempty"`
+	Locale      string              `json:"locale,omitempty"`
+}
+
+// newOutboxMail encodes msg for the outbox. The template data is stored as
+// JSON and seen by the template as a map when the mail is sent, so its
+// fields must not be renamed by json tags.
+func newOutboxMail(msg *mailer.Message) (*store.OutboxMail, error) {
+	data, err := json.Marshal(msg.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(mailPayload{
+		Data:        data,
+		ReplyTo:     msg.ReplyTo,
+		Headers:     msg.Headers,
+		Attachments: msg.Attachments,
+		Locale:      msg.Locale,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &store.OutboxMail{
+		Template:       msg.Template,
+		RecipientName:  msg.Username,
+		RecipientEmail: msg.Email,
+		Payload:        payload,
+	}, nil
+}
+
+// queueMail adds msg to the outbox, within tx when given, for the mail
+// worker to send.
+func (app *application) queueMail(ctx context.Context, tx *sql.Tx, msg *mailer.Message) (*store.OutboxMail, error) {
+	mail, err := newOutboxMail(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := app.store.Outbox.Enqueue(ctx, tx, mail); err != nil {
+		return nil, err
+	}
+
+	return mail, nil
+}
+
+func decodeOutboxMail(mail *store.OutboxMail) (*mailer.Message, error) {
+	var payload mailPayload
+	if err := json.Unmarshal(mail.Payload, &payload); err != nil {
+		return nil, err
+	}
+
+	msg := &mailer.Message{
+		Template:    mail.Template,
+		Username:    mail.RecipientName,
+		Email:       mail.RecipientEmail,
+		ReplyTo:     payload.ReplyTo,
+		Headers:     payload.Headers,
+		Attachments: payload.Attachments,
+		Locale:      payload.Locale,
+	}
+
+	if len(payload.Data) > 0 {
+		if err := json.Unmarshal(payload.Data, &msg.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	return msg, nil
+}
+
+// deliverMail sends a batch of due mails from the outbox. Failed mails are
+// retried with an exponential backoff and dead-lettered after
+// maxMailAttempts.
+func (app *application) deliverMail(ctx context.Context) error {
+	mails, err := app.store.Outbox.Claim(ctx, mailBatchSize, mailLease)
+	if err != nil {
+		return err
+	}
+
+	isSandbox := app.config.env != "production"
+	for i := range mails {
+		mail := &mails[i]
+
+		msg, err := decodeOutboxMail(mail)
+		if err != nil {
+			// a payload that cannot be read will not get better
+			app.failMail(ctx, mail, err, false)
+			continue
+		}
+
+		status, err := app.mailer.SendMessage(msg, isSandbox)
+		if err != nil {
+			// suppressed addresses are dead-lettered straight away
+			app.failMail(ctx, mail, err, mail.Attempts < maxMailAttempts && !errors.Is(err, mailer.ErrSuppressed))
+			continue
+		}
+
+		app.logger.Infow("Email sent", "mail", mail.ID, "template", mail.Template, "status code", status)
+
+		if err := app.store.Outbox.MarkSent(ctx, mail.ID); err != nil {
+			app.logger.Errorw("error marking mail sent", "mail", mail.ID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (app *application) failMail(ctx context.Context, mail *store.OutboxMail, reason error, retry bool) {
+	app.logger.Warnw("mail delivery failed", "mail", mail.ID, "attempt", mail.Attempts, "retry", retry, "error", reason)
+
+	var retryAt time.Time
+	if retry {
+		retryAt = time.Now().Add(mailBackoff << (mail.Attempts - 1))
+	}
+
+	if err := app.store.Outbox.MarkFailed(ctx, mail.ID, reason.Error(), retryAt); err != nil {
+		app.logger.Errorw("error marking mail failed", "mail", mail.ID, "error", err)
+	}
+}
+
+// getOutboxHandler lists the outbox, filtered with ?status=pending, sent or
+// dead.
+func (app *application) getOutboxHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", store.OutboxPending, store.OutboxSent, store.OutboxDead:
+	default:
+		app.badRequestResponse(w, r, errors.New("status must be pending, sent or dead"))
+		return
+	}
+
+	fq := store.PaginatedFeedQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	fq, err := fq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	mails, err := app.store.Outbox.GetByStatus(r.Context(), status, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, mails); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) getOutboxMailHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "mailID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	mail, err := app.store.Outbox.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, mail); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// redriveMailHandler sends a dead mail again. Mails that are not dead are
+// reported as not found.
+func (app *application) redriveMailHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "mailID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Outbox.Redrive(ctx, id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	mail, err := app.store.Outbox.GetByID(ctx, id)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, mail); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type RedriveResponse struct {
+	Redriven int `json:"redriven"`
+}
+
+// redriveAllMailHandler sends every dead mail again.
+func (app *application) redriveAllMailHandler(w http.ResponseWriter, r *http.Request) {
+	n, err := app.store.Outbox.RedriveAll(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, RedriveResponse{Redriven: n}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
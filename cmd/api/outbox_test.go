@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/sikozonpc/social/internal/mailer"
+)
+
+func TestOutbox(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		expected int
+	}{
+		{"list dead mails", http.MethodGet, "/v1/outbox?status=dead", http.StatusOK},
+		{"unknown status", http.MethodGet, "/v1/outbox?status=lost", http.StatusBadRequest},
+		{"inspect a mail", http.MethodGet, "/v1/outbox/1", http.StatusOK},
+		{"re-drive a dead mail", http.MethodPost, "/v1/outbox/1/redrive", http.StatusOK},
+		{"re-drive a mail that is not dead", http.MethodPost, "/v1/outbox/2/redrive", http.StatusNotFound},
+		{"re-drive all dead mails", http.MethodPost, "/v1/outbox/redrive", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+		})
+	}
+
+	t.Run("should deliver queued mails", func(t *testing.T) {
+		ctx := context.Background()
+
+		msg := &mailer.Message{
+			Template: mailer.ReminderTemplate,
+			Username: "Neema",
+			Email:    "neema@example.com",
+			Data:     mailer.ReminderData{GuestName: "Neema", EventName: "Harusi", When: "tomorrow"},
+			ReplyTo:  "host@example.com",
+		}
+
+		if _, err := app.queueMail(ctx, nil, msg); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := app.deliverMail(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		sent := app.mailer.(*mailer.MockClient).Sent
+		if len(sent) != 1 || sent[0].Email != msg.Email || sent[0].ReplyTo != msg.ReplyTo {
+			t.Fatalf("expected the queued mail to be sent, got %+v", sent)
+		}
+
+		rendered, err := mailer.Render(sent[0].Template, sent[0].Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rendered.Subject != "Reminder: Harusi is tomorrow" {
+			t.Errorf("unexpected subject %q", rendered.Subject)
+		}
+	})
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sikozonpc/social/internal/live"
+	"github.com/sikozonpc/social/internal/render"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+var errRSVPClosed = errors.New("the RSVP deadline for this event has passed")
+
+type InvitationEvent struct {
+	Name     string `json:"name"`
+	Date     string `json:"date"`
+	Location string `json:"location"`
+}
+
+// Invitation is what a guest sees when opening their RSVP link.
+type Invitation struct {
+	GuestName    string          `json:"guest_name"`
+	GuestType    string          `json:"guest_type"`
+	Status       string          `json:"status"`
+	PlusOnes     int             `json:"plus_ones"`
+	Note         string          `json:"note"`
+	RespondedAt  string          `json:"responded_at,omitempty"`
+	RSVPDeadline string          `json:"rsvp_deadline,omitempty"`
+	RSVPOpen     bool            `json:"rsvp_open"`
+	Event        InvitationEvent `json:"event"`
+	CardImageURL string          `json:"card_image_url"`
+}
+
+type RSVPPayload struct {
+	Status   string `json:"status" validate:"required,oneof=accepted declined maybe"`
+	PlusOnes int    `json:"plus_ones" validate:"min=0,max=10"`
+	Note     string `json:"note" validate:"max=500"`
+}
+
+// getInvitationHandler shows the invitation behind an RSVP link.
+func (app *application) getInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	invitation := app.invitation(getEventFromCtx(r), getGuestFromCtx(r))
+
+	if err := app.jsonResponse(w, http.StatusOK, invitation); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// respondRSVPHandler records the guest's answer to the invitation.
+func (app *application) respondRSVPHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+	guest := getGuestFromCtx(r)
+
+	if rsvpClosed(event, time.Now()) {
+		app.conflictResponse(w, r, errRSVPClosed)
+		return
+	}
+
+	var payload RSVPPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// nobody comes along with a guest who is not coming
+	if payload.Status == store.GuestStatusDeclined {
+		payload.PlusOnes = 0
+	}
+
+	rsvp := &store.RSVP{
+		GuestID:  guest.ID,
+		Status:   payload.Status,
+		PlusOnes: payload.PlusOnes,
+		Note:     payload.Note,
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.RSVPs.Create(ctx, rsvp); err != nil {
+		switch {
+		case errors.Is(err, store.ErrRSVPClosed):
+			// closed since the check above
+			app.conflictResponse(w, r, errRSVPClosed)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	statusChanged := guest.Status != rsvp.Status
+
+	guest.Status = rsvp.Status
+	guest.PlusOnes = rsvp.PlusOnes
+	guest.RSVPNote = rsvp.Note
+	guest.RespondedAt = rsvp.CreatedAt
+
+	if statusChanged {
+		app.publishLive(ctx, live.TypeGuestStatus, event.ID, GuestStatusChange{GuestID: guest.ID, Status: guest.Status})
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, app.invitation(event, guest)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getInvitationCardHandler serves the guest's card image. Until a card has
+// been issued a preview without QR code is rendered from the event template.
+func (app *application) getInvitationCardHandler(w http.ResponseWriter, r *http.Request) {
+	event := getEventFromCtx(r)
+	guest := getGuestFromCtx(r)
+
+	ctx := r.Context()
+
+	if guest.CardID != 0 {
+		app.serveCardImage(ctx, w, r, guest.CardID)
+		return
+	}
+
+	tmpl, err := app.cardTemplate(ctx, event, 0)
+	if err != nil {
+		if errors.Is(err, errNoCardTemplate) {
+			app.notFoundResponse(w, r, err)
+			return
+		}
+
+		app.cardRenderError(w, r, err)
+		return
+	}
+
+	img, err := app.renderer.Render(tmpl, render.NewData(event, guest))
+	if err != nil {
+		app.cardRenderError(w, r, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := render.EncodePNG(&buf, img); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func (app *application) serveCardImage(ctx context.Context, w http.ResponseWriter, r *http.Request, cardID int64) {
+	card, err := app.store.Cards.GetByID(ctx, cardID)
+	if err != nil {
+		app.cardRenderError(w, r, err)
+		return
+	}
+
+	app.serveBlob(w, r, card.ImagePath, "image/png")
+}
+
+// getGuestRSVPsHandler returns the history of a guest's responses.
+func (app *application) getGuestRSVPsHandler(w http.ResponseWriter, r *http.Request) {
+	guest := getGuestFromCtx(r)
+
+	responses, err := app.store.RSVPs.GetByGuest(r.Context(), guest.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) invitation(event *store.Event, guest *store.Guest) Invitation {
+	return Invitation{
+		GuestName:    guest.Name,
+		GuestType:    guest.Type,
+		Status:       guest.Status,
+		PlusOnes:     guest.PlusOnes,
+		Note:         guest.RSVPNote,
+		RespondedAt:  guest.RespondedAt,
+		RSVPDeadline: event.RSVPDeadline,
+		RSVPOpen:     !rsvpClosed(event, time.Now()),
+		Event: InvitationEvent{
+			Name:     event.Name,
+			Date:     event.Date,
+			Location: event.Location,
+		},
+		CardImageURL: "/v1/rsvp/" + guest.RSVPToken + "/card",
+	}
+}
+
+// rsvpClosed reports whether the event's RSVP deadline has passed at now.
+func rsvpClosed(event *store.Event, now time.Time) bool {
+	if event.RSVPDeadline == "" {
+		return false
+	}
+
+	deadline, err := time.Parse(time.RFC3339, event.RSVPDeadline)
+	if err != nil {
+		return false
+	}
+
+	return !now.Before(deadline)
+}
+
+// rsvpContextMiddleware loads the guest an RSVP token was issued to and its
+// event. Unknown tokens are reported as not found.
+func (app *application) rsvpContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		guest, err := app.store.Guests.GetByRSVPToken(ctx, chi.URLParam(r, "token"))
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		event, err := app.store.Events.GetByID(ctx, guest.EventID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, guestCtx, guest)
+		ctx = context.WithValue(ctx, eventCtx, event)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sikozonpc/social/internal/live"
+	"github.com/sikozonpc/social/internal/mailer"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+// closeDueRSVPs closes the RSVPs of the events past their deadline and mails
+// the answers to each event owner. Followers of the events are told about
+// the guests moved to no_response.
+func (app *application) closeDueRSVPs(ctx context.Context) error {
+	events, err := app.store.RSVPs.GetDueEvents(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for i := range events {
+		event := &events[i]
+
+		summary, err := app.store.RSVPs.Close(ctx, event.ID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				// closed in the meantime
+				continue
+			}
+
+			app.logger.Errorw("error closing rsvps", "event", event.ID, "error", err)
+			continue
+		}
+
+		app.logger.Infow("rsvps closed", "event", event.ID, "expired", summary.Expired)
+
+		// one message for all the guests rather than one each, which would
+		// overflow the buffer of the followers of large events
+		if len(summary.ExpiredGuestIDs) > 0 {
+			app.publishLive(ctx, live.TypeGuestStatuses, event.ID, GuestStatusesChange{
+				GuestIDs: summary.ExpiredGuestIDs,
+				Status:   store.GuestStatusNoResponse,
+			})
+		}
+
+		if err := app.sendRSVPSummary(ctx, event, summary); err != nil {
+			app.logger.Errorw("error sending rsvp summary", "event", event.ID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (app *application) sendRSVPSummary(ctx context.Context, event *store.Event, summary *store.RSVPSummary) error {
+	owner, err := app.store.Users.GetByID(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+
+	vars := mailer.RSVPSummaryData{
+		Username:  owner.Username,
+		EventName: event.Name,
+		GuestsURL: fmt.Sprintf("%s/events/%d/guests", app.config.frontendURL, event.ID),
+		Summary: mailer.RSVPCounts{
+			Accepted:   summary.Accepted,
+			Declined:   summary.Declined,
+			Maybe:      summary.Maybe,
+			NoResponse: summary.NoResponse,
+			PlusOnes:   summary.PlusOnes,
+			Expired:    summary.Expired,
+		},
+	}
+
+	_, err = app.queueMail(ctx, nil, &mailer.Message{
+		Template: mailer.RSVPSummaryTemplate,
+		Username: owner.Username,
+		Email:    owner.Email,
+		Data:     vars,
+	})
+	return err
+}
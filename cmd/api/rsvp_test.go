@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sikozonpc/social/internal/live"
+	"github.com/sikozonpc/social/internal/mailer"
+)
+
+func TestRSVP(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	t.Run("should show the invitation without authentication", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/rsvp/rsvp-token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if !strings.Contains(rr.Body.String(), `"card_image_url":"/v1/rsvp/rsvp-token/card"`) {
+			t.Errorf("expected the card image url, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("should not find unknown tokens", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/rsvp/guessed", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+		contains string
+	}{
+		{"accept with a plus-one", `{"status": "accepted", "plus_ones": 1, "note": "See you there"}`, http.StatusOK, `"plus_ones":1`},
+		{"decline drops plus-ones", `{"status": "declined", "plus_ones": 2}`, http.StatusOK, `"plus_ones":0`},
+		{"unknown status", `{"status": "pending"}`, http.StatusBadRequest, "error"},
+		{"too many plus-ones", `{"status": "accepted", "plus_ones": 11}`, http.StatusBadRequest, "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/v1/rsvp/rsvp-token", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+
+			if !strings.Contains(rr.Body.String(), tt.contains) {
+				t.Errorf("expected response to contain %q, got %s", tt.contains, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestRSVPDeadline(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	t.Run("should reject responses after the deadline", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/v1/rsvp/closed-token", strings.NewReader(`{"status": "accepted"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("should reject responses racing the close", func(t *testing.T) {
+		sub := app.broker.Subscribe(1)
+		defer sub.Close()
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/rsvp/late-token", strings.NewReader(`{"status": "accepted"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+
+		select {
+		case msg := <-sub.C:
+			t.Errorf("expected nothing to be published, got %s", msg.Type)
+		default:
+		}
+	})
+
+	t.Run("should show the invitation as closed", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/rsvp/closed-token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if !strings.Contains(rr.Body.String(), `"rsvp_open":false`) {
+			t.Errorf("expected the invitation to be closed, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("should mail a summary when closing due events", func(t *testing.T) {
+		sub := app.broker.Subscribe(1)
+		defer sub.Close()
+
+		if err := app.closeDueRSVPs(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case msg := <-sub.C:
+			if msg.Type != live.TypeGuestStatuses || string(msg.Data) != `{"guest_ids":[5,6,7],"status":"no_response"}` {
+				t.Errorf("unexpected live message %s %s", msg.Type, msg.Data)
+			}
+		default:
+			t.Error("expected the expired guests to be published")
+		}
+
+		if err := app.deliverMail(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		sent := app.mailer.(*mailer.MockClient).Sent
+		if len(sent) != 1 || sent[0].Template != mailer.RSVPSummaryTemplate {
+			t.Fatalf("expected one rsvp summary mail, got %+v", sent)
+		}
+
+		if _, err := mailer.Render(sent[0].Template, sent[0].Data); err != nil {
+			t.Errorf("expected the queued summary to render, got %v", err)
+		}
+	})
+}
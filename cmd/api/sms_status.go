@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sikozonpc/social/internal/sms"
+	"github.com/sikozonpc/social/internal/store"
+)
+
+// smsStatusHandler receives the delivery status callbacks of the SMS
+// provider and records them on the campaign delivery of the message.
+func (app *application) smsStatusHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := app.sms.ParseStatus(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, sms.ErrInvalidSignature):
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	status := store.DeliverySent
+	switch report.Status {
+	case sms.StatusDelivered:
+		status = store.DeliveryDelivered
+	case sms.StatusFailed:
+		status = store.DeliveryFailed
+	}
+
+	if err := app.store.Campaigns.UpdateStatus(r.Context(), report.MessageID, status, report.Error); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/sikozonpc/social/internal/auth"
+	"github.com/sikozonpc/social/internal/blob"
+	"github.com/sikozonpc/social/internal/live"
+	"github.com/sikozonpc/social/internal/mailer"
+	"github.com/sikozonpc/social/internal/messenger"
 	"github.com/sikozonpc/social/internal/ratelimiter"
+	"github.com/sikozonpc/social/internal/render"
+	"github.com/sikozonpc/social/internal/sms"
 	"github.com/sikozonpc/social/internal/store"
 	"github.com/sikozonpc/social/internal/store/cache"
 	"go.uber.org/zap"
@@ -29,6 +38,28 @@ func newTestApplication(t *testing.T, cfg config) *application {
 		cfg.rateLimiter.TimeFrame,
 	)
 
+	mailEvents, err := mailer.NewEventWebhook("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.cards.assetsDir == "" {
+		cfg.cards.assetsDir = t.TempDir()
+	}
+
+	blobs, err := blob.NewLocalStore(blob.LocalConfig{
+		Dir:     cfg.cards.assetsDir,
+		BaseURL: "/v1/blobs",
+		Secret:  "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.cards.urlExpiry == 0 {
+		cfg.cards.urlExpiry = time.Minute
+	}
+
 	return &application{
 		logger:        logger,
 		store:         mockStore,
@@ -36,6 +67,15 @@ func newTestApplication(t *testing.T, cfg config) *application {
 		authenticator: testAuth,
 		config:        cfg,
 		rateLimiter:   rateLimiter,
+		renderer:      render.New(blob.FS(context.Background(), blobs)),
+		cardSigner:    auth.NewCardSigner("test"),
+		streamTickets: auth.NewTicketSigner("test"),
+		broker:        live.NewMemoryBroker(),
+		mailer:        &mailer.MockClient{},
+		sms:           sms.NewSandboxClient(io.Discard, "255"),
+		messenger:     messenger.NewSandboxClient(io.Discard, "", "255"),
+		mailEvents:    mailEvents,
+		blobs:         blobs,
 	}
 }
 
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// startWorkers starts the background jobs of the API. They stop when ctx is
+// cancelled.
+func (app *application) startWorkers(ctx context.Context) {
+	go app.runEvery(ctx, "rsvp deadlines", app.config.scheduler.rsvpInterval, app.closeDueRSVPs)
+	go app.runEvery(ctx, "reminders", app.config.scheduler.reminderInterval, app.dispatchReminders)
+	go app.runEvery(ctx, "mail outbox", app.config.scheduler.mailInterval, app.deliverMail)
+	go app.runEvery(ctx, "card jobs", app.config.scheduler.cardInterval, app.generateCards)
+	go app.runEvery(ctx, "blob cleanup", app.config.scheduler.blobInterval, app.cleanupBlobs)
+}
+
+// runEvery runs job right away and then every interval until ctx is done.
+// Errors are logged and the job is tried again on the next tick.
+func (app *application) runEvery(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	if interval <= 0 {
+		app.logger.Infow("worker disabled", "worker", name)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(ctx); err != nil {
+			app.logger.Errorw("worker failed", "worker", name, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
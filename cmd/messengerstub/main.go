@@ -0,0 +1,193 @@
+// Command messengerstub is a local chat messaging provider for development.
+// It accepts the card messages of messenger.HTTPClient, lists them at
+// GET /messages with their images at GET /messages/{id}/image, and reports
+// each one as delivered and then read to its callback URL.
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sikozonpc/social/internal/env"
+	"github.com/sikozonpc/social/internal/messenger"
+	"github.com/sikozonpc/social/internal/sms"
+	"go.uber.org/zap"
+)
+
+const maxImageSize = 10 << 20
+
+type message struct {
+	ID          string    `json:"id"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Caption     string    `json:"caption"`
+	ImageName   string    `json:"image_name"`
+	ImageURL    string    `json:"image_url"`
+	CallbackURL string    `json:"callback_url,omitempty"`
+	Sandbox     bool      `json:"sandbox"`
+	Status      string    `json:"status"`
+	ReceivedAt  time.Time `json:"received_at"`
+	image       []byte
+}
+
+type stub struct {
+	apiKey string
+	secret string
+	delay  time.Duration
+	logger *zap.SugaredLogger
+
+	mu       sync.Mutex
+	messages []*message
+}
+
+func main() {
+	logger := zap.Must(zap.NewDevelopment()).Sugar()
+	defer logger.Sync()
+
+	s := &stub{
+		apiKey: env.GetString("MESSENGER_API_KEY", ""),
+		secret: env.GetString("MESSENGER_CALLBACK_SECRET", ""),
+		delay:  2 * time.Second,
+		logger: logger,
+	}
+
+	addr := env.GetString("MESSENGER_STUB_ADDR", ":8026")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /messages", s.listHandler)
+	mux.HandleFunc("POST /messages", s.sendHandler)
+	mux.HandleFunc("GET /messages/{id}/image", s.imageHandler)
+
+	logger.Infow("messenger stub listening", "addr", addr)
+	logger.Fatal(http.ListenAndServe(addr, mux))
+}
+
+func (s *stub) listHandler(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.messages)
+}
+
+func (s *stub) imageHandler(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, msg := range s.messages {
+		if msg.ID == r.PathValue("id") {
+			w.Header().Set("Content-Type", http.DetectContentType(msg.image))
+			w.Write(msg.image)
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
+
+func (s *stub) sendHandler(w http.ResponseWriter, r *http.Request) {
+	if s.apiKey != "" && r.Header.Get("Authorization") != "Bearer "+s.apiKey {
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseMultipartForm(maxImageSize); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f, header, err := r.FormFile("image")
+	if err != nil {
+		http.Error(w, "image is required", http.StatusBadRequest)
+		return
+	}
+	defer f.Close()
+
+	image, err := io.ReadAll(f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	msg := &message{
+		From:        r.FormValue("from"),
+		To:          r.FormValue("to"),
+		Caption:     r.FormValue("caption"),
+		ImageName:   header.Filename,
+		CallbackURL: r.FormValue("callback_url"),
+		Sandbox:     r.FormValue("sandbox") == "true",
+		Status:      messenger.StatusSent,
+		ReceivedAt:  time.Now(),
+		image:       image,
+	}
+
+	if !strings.HasPrefix(msg.To, "+") {
+		http.Error(w, "to must be an E.164 number", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	msg.ID = fmt.Sprintf("stub-%d", len(s.messages)+1)
+	msg.ImageURL = "/messages/" + msg.ID + "/image"
+	s.messages = append(s.messages, msg)
+	s.mu.Unlock()
+
+	s.logger.Infow("message received", "id", msg.ID, "to", msg.To, "image", msg.ImageName, "caption", msg.Caption)
+
+	if msg.CallbackURL != "" {
+		go s.report(msg)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"id": msg.ID})
+}
+
+// report walks the message through delivered and read, telling its
+// callback URL about each step.
+func (s *stub) report(msg *message) {
+	for _, status := range []string{messenger.StatusDelivered, messenger.StatusRead} {
+		time.Sleep(s.delay)
+
+		if err := s.callback(msg, status); err != nil {
+			s.logger.Errorw("error reporting status", "id", msg.ID, "status", status, "error", err)
+			return
+		}
+
+		s.mu.Lock()
+		msg.Status = status
+		s.mu.Unlock()
+	}
+}
+
+func (s *stub) callback(msg *message, status string) error {
+	body, err := json.Marshal(messenger.StatusReport{MessageID: msg.ID, Status: status})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, msg.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if s.secret != "" {
+		req.Header.Set(sms.SignatureHeader, hex.EncodeToString(sms.Sign(body, s.secret)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	s.logger.Infow("status reported", "id", msg.ID, "status", status, "callback_status", resp.StatusCode)
+	return nil
+}
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS posts (
+  id bigserial PRIMARY KEY,
+  title text NOT NULL,
+  user_id bigint NOT NULL,
+  content text NOT NULL,
+  tags VARCHAR(100) [],
+  version INT DEFAULT 0,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS comments (
+  id bigserial PRIMARY KEY,
+  post_id bigserial NOT NULL,
+  user_id bigserial NOT NULL,
+  content TEXT NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_content ON comments USING gin (content gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_posts_title ON posts USING gin (title gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_posts_tags ON posts USING gin (tags);
+
+CREATE INDEX IF NOT EXISTS idx_posts_user_id ON posts (user_id);
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments (post_id);
@@ -0,0 +1,5 @@
+-- The event domain replaced posts and comments, drop them together with
+-- the indexes created in 000008.
+DROP TABLE IF EXISTS comments;
+
+DROP TABLE IF EXISTS posts;
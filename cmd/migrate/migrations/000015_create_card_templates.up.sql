@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS card_templates (
+  id bigserial PRIMARY KEY,
+  image_path text NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS events (
+  id bigserial PRIMARY KEY,
+  name varchar(100) NOT NULL,
+  date timestamp(0) with time zone NOT NULL,
+  location text NOT NULL DEFAULT '',
+  scanned_count bigint NOT NULL DEFAULT 0,
+  card_template_id bigint,
+  user_id bigint NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (card_template_id) REFERENCES card_templates (id) ON DELETE SET NULL,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS guests (
+  id bigserial PRIMARY KEY,
+  name varchar(255) NOT NULL,
+  email citext NOT NULL DEFAULT '',
+  phone_number varchar(32) NOT NULL DEFAULT '',
+  status varchar(32) NOT NULL DEFAULT 'pending',
+  type varchar(32) NOT NULL DEFAULT 'single',
+  card_id bigint,
+  event_id bigint NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE
+);
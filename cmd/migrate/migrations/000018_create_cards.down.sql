@@ -0,0 +1,4 @@
+ALTER TABLE
+  IF EXISTS guests DROP CONSTRAINT IF EXISTS fk_guests_card;
+
+DROP TABLE IF EXISTS cards;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS cards (
+  id bigserial PRIMARY KEY,
+  image_path text NOT NULL DEFAULT '',
+  event_id bigint NOT NULL,
+  guest_id bigint NOT NULL,
+  card_template_id bigint,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE,
+  FOREIGN KEY (guest_id) REFERENCES guests (id) ON DELETE CASCADE,
+  FOREIGN KEY (card_template_id) REFERENCES card_templates (id) ON DELETE SET NULL
+);
+
+-- guests and cards reference each other, so the guest side of the
+-- relation can only be added once both tables exist.
+ALTER TABLE
+  guests
+ADD
+  CONSTRAINT fk_guests_card FOREIGN KEY (card_id) REFERENCES cards (id) ON DELETE SET NULL;
@@ -0,0 +1,19 @@
+DROP INDEX IF EXISTS idx_events_name;
+
+DROP INDEX IF EXISTS idx_events_location;
+
+DROP INDEX IF EXISTS idx_users_username_trgm;
+
+DROP INDEX IF EXISTS idx_guests_name;
+
+DROP INDEX IF EXISTS idx_guests_phone_number;
+
+DROP INDEX IF EXISTS idx_events_user_id;
+
+DROP INDEX IF EXISTS idx_guests_event_id;
+
+DROP INDEX IF EXISTS idx_guests_card_id;
+
+DROP INDEX IF EXISTS idx_cards_event_id;
+
+DROP INDEX IF EXISTS idx_cards_guest_id;
@@ -0,0 +1,22 @@
+-- Trigram indexes back the ILIKE searches in GetAllEvents, GetGuests and GetCards
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_events_name ON events USING gin (name gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_events_location ON events USING gin (location gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING gin (username gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_guests_name ON guests USING gin (name gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_guests_phone_number ON guests USING gin (phone_number gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_events_user_id ON events (user_id);
+
+CREATE INDEX IF NOT EXISTS idx_guests_event_id ON guests (event_id);
+
+CREATE INDEX IF NOT EXISTS idx_guests_card_id ON guests (card_id);
+
+CREATE INDEX IF NOT EXISTS idx_cards_event_id ON cards (event_id);
+
+CREATE INDEX IF NOT EXISTS idx_cards_guest_id ON cards (guest_id);
@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS trg_cards_updated_at ON cards;
+
+DROP TRIGGER IF EXISTS trg_guests_updated_at ON guests;
+
+DROP TRIGGER IF EXISTS trg_events_updated_at ON events;
+
+DROP TRIGGER IF EXISTS trg_card_templates_updated_at ON card_templates;
+
+DROP FUNCTION IF EXISTS set_updated_at();
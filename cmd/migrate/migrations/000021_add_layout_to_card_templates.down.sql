@@ -0,0 +1,2 @@
+ALTER TABLE
+  card_templates DROP COLUMN layout;
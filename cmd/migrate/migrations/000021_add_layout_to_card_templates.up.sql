@@ -0,0 +1,4 @@
+ALTER TABLE
+  card_templates
+ADD
+  COLUMN layout jsonb NOT NULL DEFAULT '{}';
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS checkins (
+  id bigserial PRIMARY KEY,
+  event_id bigint NOT NULL,
+  guest_id bigint NOT NULL,
+  card_id bigint,
+  user_id bigint,
+  gate varchar(100) NOT NULL DEFAULT '',
+  checked_in_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  undone_at timestamp(0) with time zone,
+  undone_by bigint,
+
+  FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE,
+  FOREIGN KEY (guest_id) REFERENCES guests (id) ON DELETE CASCADE,
+  FOREIGN KEY (card_id) REFERENCES cards (id) ON DELETE SET NULL,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL,
+  FOREIGN KEY (undone_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+-- a guest can only be checked in once, undone check-ins are kept as history
+CREATE UNIQUE INDEX IF NOT EXISTS idx_checkins_active_guest_id ON checkins (guest_id) WHERE undone_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_checkins_event_id ON checkins (event_id);
@@ -0,0 +1,5 @@
+ALTER TABLE
+  checkins DROP COLUMN synced_at;
+
+ALTER TABLE
+  checkins DROP COLUMN device_id;
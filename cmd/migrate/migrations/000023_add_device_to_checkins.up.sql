@@ -0,0 +1,11 @@
+ALTER TABLE
+  checkins
+ADD
+  COLUMN device_id varchar(100) NOT NULL DEFAULT '';
+
+-- checked_in_at is the scanner's clock for offline check-ins, synced_at is
+-- when the server received it
+ALTER TABLE
+  checkins
+ADD
+  COLUMN synced_at timestamp(0) with time zone NOT NULL DEFAULT NOW();
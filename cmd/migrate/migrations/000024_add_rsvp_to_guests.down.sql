@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS rsvp_responses;
+
+DROP INDEX IF EXISTS idx_guests_rsvp_token;
+
+ALTER TABLE guests
+  DROP COLUMN IF EXISTS rsvp_token,
+  DROP COLUMN IF EXISTS plus_ones,
+  DROP COLUMN IF EXISTS rsvp_note,
+  DROP COLUMN IF EXISTS responded_at;
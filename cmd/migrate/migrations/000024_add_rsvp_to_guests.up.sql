@@ -0,0 +1,23 @@
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+-- the volatile default gives every existing guest its own token
+ALTER TABLE guests
+  ADD COLUMN IF NOT EXISTS rsvp_token varchar(64) NOT NULL DEFAULT encode(gen_random_bytes(24), 'hex'),
+  ADD COLUMN IF NOT EXISTS plus_ones int NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS rsvp_note text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS responded_at timestamp(0) with time zone;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_guests_rsvp_token ON guests (rsvp_token);
+
+CREATE TABLE IF NOT EXISTS rsvp_responses (
+  id bigserial PRIMARY KEY,
+  guest_id bigint NOT NULL,
+  status varchar(32) NOT NULL,
+  plus_ones int NOT NULL DEFAULT 0,
+  note text NOT NULL DEFAULT '',
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (guest_id) REFERENCES guests (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_rsvp_responses_guest_id ON rsvp_responses (guest_id);
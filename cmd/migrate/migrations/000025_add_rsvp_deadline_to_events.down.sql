@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_events_rsvp_deadline;
+
+ALTER TABLE events
+  DROP COLUMN IF EXISTS rsvp_deadline,
+  DROP COLUMN IF EXISTS rsvp_closed_at;
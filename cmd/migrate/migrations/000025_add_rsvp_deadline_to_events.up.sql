@@ -0,0 +1,5 @@
+ALTER TABLE events
+  ADD COLUMN IF NOT EXISTS rsvp_deadline timestamp(0) with time zone,
+  ADD COLUMN IF NOT EXISTS rsvp_closed_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_events_rsvp_deadline ON events (rsvp_deadline) WHERE rsvp_closed_at IS NULL;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS campaign_deliveries;
+
+DROP TRIGGER IF EXISTS trg_campaigns_updated_at ON campaigns;
+
+DROP TABLE IF EXISTS campaigns;
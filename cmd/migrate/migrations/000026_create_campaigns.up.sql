@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS campaigns (
+  id bigserial PRIMARY KEY,
+  event_id bigint NOT NULL,
+  name varchar(100) NOT NULL,
+  channel varchar(16) NOT NULL,
+  -- sent days_before the event date, at at_time on that day when set
+  days_before int NOT NULL DEFAULT 0,
+  at_time time,
+  statuses text[] NOT NULL DEFAULT '{}',
+  types text[] NOT NULL DEFAULT '{}',
+  status varchar(16) NOT NULL DEFAULT 'scheduled',
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_event_id ON campaigns (event_id);
+CREATE INDEX IF NOT EXISTS idx_campaigns_scheduled ON campaigns (status) WHERE status = 'scheduled';
+
+CREATE TRIGGER trg_campaigns_updated_at BEFORE UPDATE ON campaigns
+  FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE IF NOT EXISTS campaign_deliveries (
+  id bigserial PRIMARY KEY,
+  campaign_id bigint NOT NULL,
+  guest_id bigint NOT NULL,
+  recipient varchar(255) NOT NULL,
+  status varchar(16) NOT NULL DEFAULT 'pending',
+  attempts int NOT NULL DEFAULT 0,
+  last_error text NOT NULL DEFAULT '',
+  next_attempt_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  sent_at timestamp(0) with time zone,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (campaign_id) REFERENCES campaigns (id) ON DELETE CASCADE,
+  FOREIGN KEY (guest_id) REFERENCES guests (id) ON DELETE CASCADE,
+  UNIQUE (campaign_id, guest_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaign_deliveries_pending ON campaign_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_campaign_deliveries_provider_id;
+
+ALTER TABLE campaign_deliveries
+  DROP COLUMN IF EXISTS provider_id,
+  DROP COLUMN IF EXISTS delivered_at;
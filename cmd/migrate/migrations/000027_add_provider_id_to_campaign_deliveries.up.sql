@@ -0,0 +1,5 @@
+ALTER TABLE campaign_deliveries
+  ADD COLUMN IF NOT EXISTS provider_id varchar(255) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS delivered_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_campaign_deliveries_provider_id ON campaign_deliveries (provider_id) WHERE provider_id <> '';
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS card_messages (
+  id bigserial PRIMARY KEY,
+  card_id bigint NOT NULL,
+  guest_id bigint NOT NULL,
+  event_id bigint NOT NULL,
+  recipient varchar(255) NOT NULL,
+  provider_id varchar(255) NOT NULL,
+  status varchar(16) NOT NULL DEFAULT 'sent',
+  error text NOT NULL DEFAULT '',
+  sent_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  delivered_at timestamp(0) with time zone,
+  read_at timestamp(0) with time zone,
+
+  FOREIGN KEY (card_id) REFERENCES cards (id) ON DELETE CASCADE,
+  FOREIGN KEY (guest_id) REFERENCES guests (id) ON DELETE CASCADE,
+  FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_messages_card_id ON card_messages (card_id);
+CREATE INDEX IF NOT EXISTS idx_card_messages_event_id ON card_messages (event_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_card_messages_provider_id ON card_messages (provider_id);
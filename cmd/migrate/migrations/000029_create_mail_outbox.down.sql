@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_mail_outbox_updated_at ON mail_outbox;
+
+DROP TABLE IF EXISTS mail_outbox;
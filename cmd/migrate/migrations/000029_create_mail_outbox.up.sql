@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS mail_outbox (
+  id bigserial PRIMARY KEY,
+  template varchar(100) NOT NULL,
+  recipient_name varchar(255) NOT NULL DEFAULT '',
+  recipient_email varchar(255) NOT NULL,
+  -- template data, reply-to, headers and attachments of the mail
+  payload jsonb NOT NULL DEFAULT '{}',
+  status varchar(16) NOT NULL DEFAULT 'pending',
+  attempts int NOT NULL DEFAULT 0,
+  last_error text NOT NULL DEFAULT '',
+  next_attempt_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  sent_at timestamp(0) with time zone,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mail_outbox_pending ON mail_outbox (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_mail_outbox_status ON mail_outbox (status, created_at);
+
+CREATE TRIGGER trg_mail_outbox_updated_at BEFORE UPDATE ON mail_outbox
+  FOR EACH ROW EXECUTE FUNCTION set_updated_at();
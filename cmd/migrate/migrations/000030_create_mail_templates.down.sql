@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_mail_templates_updated_at ON mail_templates;
+
+DROP TABLE IF EXISTS mail_templates;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS mail_templates (
+  id bigserial PRIMARY KEY,
+  -- name of the embedded template it overrides, e.g. guest_invitation.tmpl
+  name varchar(100) NOT NULL,
+  locale varchar(16) NOT NULL DEFAULT 'en',
+  subject text NOT NULL,
+  html text NOT NULL,
+  text text NOT NULL DEFAULT '',
+  updated_by bigint,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (updated_by) REFERENCES users (id) ON DELETE SET NULL,
+  UNIQUE (name, locale)
+);
+
+CREATE TRIGGER trg_mail_templates_updated_at BEFORE UPDATE ON mail_templates
+  FOR EACH ROW EXECUTE FUNCTION set_updated_at();
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS email_suppressions (
+  email citext PRIMARY KEY,
+  -- event that suppressed the address: bounce, dropped, spamreport or unsubscribe
+  reason varchar(20) NOT NULL,
+  detail text NOT NULL DEFAULT '',
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
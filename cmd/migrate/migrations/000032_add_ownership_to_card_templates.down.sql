@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_card_templates_user_id;
+
+ALTER TABLE card_templates
+  DROP COLUMN IF EXISTS name,
+  DROP COLUMN IF EXISTS user_id,
+  DROP COLUMN IF EXISTS visibility,
+  DROP COLUMN IF EXISTS content_type,
+  DROP COLUMN IF EXISTS width,
+  DROP COLUMN IF EXISTS height,
+  DROP COLUMN IF EXISTS thumbnail_path;
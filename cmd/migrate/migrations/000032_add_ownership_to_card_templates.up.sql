@@ -0,0 +1,14 @@
+ALTER TABLE card_templates
+  ADD COLUMN name varchar(255) NOT NULL DEFAULT '',
+  -- owner of personal and shared templates, NULL for system templates
+  ADD COLUMN user_id bigint REFERENCES users (id) ON DELETE CASCADE,
+  -- templates inserted by hand before uploads existed are system templates
+  ADD COLUMN visibility varchar(20) NOT NULL DEFAULT 'system',
+  ADD COLUMN content_type varchar(50) NOT NULL DEFAULT '',
+  ADD COLUMN width int NOT NULL DEFAULT 0,
+  ADD COLUMN height int NOT NULL DEFAULT 0,
+  ADD COLUMN thumbnail_path text NOT NULL DEFAULT '';
+
+ALTER TABLE card_templates ALTER COLUMN visibility SET DEFAULT 'personal';
+
+CREATE INDEX IF NOT EXISTS idx_card_templates_user_id ON card_templates (user_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_card_templates_thumbnail_path;
+DROP INDEX IF EXISTS idx_card_templates_image_path;
+DROP INDEX IF EXISTS idx_cards_image_path;
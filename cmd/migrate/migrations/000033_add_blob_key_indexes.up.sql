@@ -0,0 +1,4 @@
+-- blobs are kept while a card or template refers to them
+CREATE INDEX IF NOT EXISTS idx_cards_image_path ON cards (image_path);
+CREATE INDEX IF NOT EXISTS idx_card_templates_image_path ON card_templates (image_path);
+CREATE INDEX IF NOT EXISTS idx_card_templates_thumbnail_path ON card_templates (thumbnail_path);
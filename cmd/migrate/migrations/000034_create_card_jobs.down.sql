@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS card_job_items;
+
+DROP TRIGGER IF EXISTS trg_card_jobs_updated_at ON card_jobs;
+
+DROP TABLE IF EXISTS card_jobs;
+
+ALTER TABLE cards DROP COLUMN IF EXISTS fingerprint;
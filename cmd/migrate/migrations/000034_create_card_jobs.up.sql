@@ -0,0 +1,48 @@
+-- what the card looked like when rendered, see render.Fingerprint
+ALTER TABLE cards ADD COLUMN fingerprint text NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS card_jobs (
+  id bigserial PRIMARY KEY,
+  event_id bigint NOT NULL,
+  user_id bigint,
+  card_template_id bigint,
+  -- the guests of the event matching every filter set
+  guest_ids bigint[] NOT NULL DEFAULT '{}',
+  search varchar(100) NOT NULL DEFAULT '',
+  statuses text[] NOT NULL DEFAULT '{}',
+  types text[] NOT NULL DEFAULT '{}',
+  force boolean NOT NULL DEFAULT false,
+  status varchar(16) NOT NULL DEFAULT 'running',
+  completed_at timestamp(0) with time zone,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL,
+  FOREIGN KEY (card_template_id) REFERENCES card_templates (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_jobs_event_id ON card_jobs (event_id);
+-- one job at a time per event
+CREATE UNIQUE INDEX IF NOT EXISTS idx_card_jobs_running ON card_jobs (event_id) WHERE status = 'running';
+
+CREATE TRIGGER trg_card_jobs_updated_at BEFORE UPDATE ON card_jobs
+  FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE IF NOT EXISTS card_job_items (
+  id bigserial PRIMARY KEY,
+  job_id bigint NOT NULL,
+  guest_id bigint NOT NULL,
+  status varchar(16) NOT NULL DEFAULT 'pending',
+  attempts int NOT NULL DEFAULT 0,
+  card_id bigint,
+  last_error text NOT NULL DEFAULT '',
+  next_attempt_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (job_id) REFERENCES card_jobs (id) ON DELETE CASCADE,
+  FOREIGN KEY (guest_id) REFERENCES guests (id) ON DELETE CASCADE,
+  FOREIGN KEY (card_id) REFERENCES cards (id) ON DELETE SET NULL,
+  UNIQUE (job_id, guest_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_job_items_pending ON card_job_items (next_attempt_at) WHERE status = 'pending';
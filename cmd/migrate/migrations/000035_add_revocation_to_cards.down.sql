@@ -0,0 +1,3 @@
+ALTER TABLE cards DROP COLUMN IF EXISTS revoked_by;
+ALTER TABLE cards DROP COLUMN IF EXISTS revoked_reason;
+ALTER TABLE cards DROP COLUMN IF EXISTS revoked_at;
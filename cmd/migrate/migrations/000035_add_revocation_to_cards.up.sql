@@ -0,0 +1,4 @@
+-- a revoked card is no longer accepted at check-in
+ALTER TABLE cards ADD COLUMN revoked_at timestamp(0) with time zone;
+ALTER TABLE cards ADD COLUMN revoked_reason varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE cards ADD COLUMN revoked_by bigint REFERENCES users (id) ON DELETE SET NULL;
@@ -0,0 +1,3 @@
+-- IANA name of the timezone the event takes place in, reminders sent at a
+-- time of day follow its wall clock
+ALTER TABLE events ADD COLUMN IF NOT EXISTS timezone text NOT NULL DEFAULT 'UTC';
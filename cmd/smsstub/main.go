@@ -0,0 +1,140 @@
+// Command smsstub is a local SMS provider for development. It accepts the
+// messages of sms.HTTPClient, lists them at GET /messages and reports each
+// one as delivered to its callback URL shortly after.
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sikozonpc/social/internal/env"
+	"github.com/sikozonpc/social/internal/sms"
+	"go.uber.org/zap"
+)
+
+type message struct {
+	ID          string    `json:"id"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Text        string    `json:"text"`
+	CallbackURL string    `json:"callback_url,omitempty"`
+	Sandbox     bool      `json:"sandbox"`
+	Status      string    `json:"status"`
+	ReceivedAt  time.Time `json:"received_at"`
+}
+
+type stub struct {
+	apiKey string
+	secret string
+	delay  time.Duration
+	logger *zap.SugaredLogger
+
+	mu       sync.Mutex
+	messages []*message
+}
+
+func main() {
+	logger := zap.Must(zap.NewDevelopment()).Sugar()
+	defer logger.Sync()
+
+	s := &stub{
+		apiKey: env.GetString("SMS_API_KEY", ""),
+		secret: env.GetString("SMS_CALLBACK_SECRET", ""),
+		delay:  2 * time.Second,
+		logger: logger,
+	}
+
+	addr := env.GetString("SMS_STUB_ADDR", ":8025")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/messages", s.messagesHandler)
+
+	logger.Infow("sms stub listening", "addr", addr)
+	logger.Fatal(http.ListenAndServe(addr, mux))
+}
+
+func (s *stub) messagesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.messages)
+	case http.MethodPost:
+		s.send(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *stub) send(w http.ResponseWriter, r *http.Request) {
+	if s.apiKey != "" && r.Header.Get("Authorization") != "Bearer "+s.apiKey {
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+		return
+	}
+
+	var msg message
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !strings.HasPrefix(msg.To, "+") || msg.Text == "" {
+		http.Error(w, "to must be an E.164 number and text is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	msg.ID = fmt.Sprintf("stub-%d", len(s.messages)+1)
+	msg.Status = sms.StatusSent
+	msg.ReceivedAt = time.Now()
+	s.messages = append(s.messages, &msg)
+	s.mu.Unlock()
+
+	s.logger.Infow("message received", "id", msg.ID, "to", msg.To, "text", msg.Text)
+
+	if msg.CallbackURL != "" {
+		go s.deliver(&msg)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"id": msg.ID})
+}
+
+// deliver reports the message as delivered to its callback URL.
+func (s *stub) deliver(msg *message) {
+	time.Sleep(s.delay)
+
+	body, _ := json.Marshal(sms.StatusReport{MessageID: msg.ID, Status: sms.StatusDelivered})
+
+	req, err := http.NewRequest(http.MethodPost, msg.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		s.logger.Errorw("invalid callback url", "id", msg.ID, "error", err)
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if s.secret != "" {
+		req.Header.Set(sms.SignatureHeader, hex.EncodeToString(sms.Sign(body, s.secret)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		s.logger.Errorw("error reporting delivery", "id", msg.ID, "error", err)
+		return
+	}
+	resp.Body.Close()
+
+	s.mu.Lock()
+	msg.Status = sms.StatusDelivered
+	s.mu.Unlock()
+
+	s.logger.Infow("delivery reported", "id", msg.ID, "callback_status", resp.StatusCode)
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strconv"
+)
+
+const cardTokenVersion = 1
+
+var ErrInvalidCardToken = errors.New("invalid card token")
+
+// CardClaims identify the card encoded in an invitation QR code.
+type CardClaims struct {
+	CardID  int64 `json:"card_id"`
+	GuestID int64 `json:"guest_id"`
+	EventID int64 `json:"event_id"`
+}
+
+// CardSigner issues the tokens printed as QR codes on invitation cards.
+//
+// Every event gets its own Ed25519 key pair derived from the signer secret,
+// so door scanners only need the event's public key to validate a card and
+// no keys have to be stored.
+type CardSigner struct {
+	secret []byte
+}
+
+func NewCardSigner(secret string) *CardSigner {
+	return &CardSigner{secret: []byte(secret)}
+}
+
+func (s *CardSigner) privateKey(eventID int64) ed25519.PrivateKey {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("card-signing-key:" + strconv.FormatInt(eventID, 10)))
+
+	return ed25519.NewKeyFromSeed(mac.Sum(nil))
+}
+
+// PublicKey returns the key used to verify the cards of an event.
+func (s *CardSigner) PublicKey(eventID int64) ed25519.PublicKey {
+	return s.privateKey(eventID).Public().(ed25519.PublicKey)
+}
+
+// Sign encodes the claims as a compact token: a version byte, the three IDs
+// as uvarints and an Ed25519 signature, base64url encoded without padding.
+func (s *CardSigner) Sign(claims CardClaims) (string, error) {
+	if claims.CardID <= 0 || claims.GuestID <= 0 || claims.EventID <= 0 {
+		return "", ErrInvalidCardToken
+	}
+
+	payload := make([]byte, 1, 1+3*binary.MaxVarintLen64+ed25519.SignatureSize)
+	payload[0] = cardTokenVersion
+	payload = binary.AppendUvarint(payload, uint64(claims.CardID))
+	payload = binary.AppendUvarint(payload, uint64(claims.GuestID))
+	payload = binary.AppendUvarint(payload, uint64(claims.EventID))
+
+	sig := ed25519.Sign(s.privateKey(claims.EventID), payload)
+
+	return base64.RawURLEncoding.EncodeToString(append(payload, sig...)), nil
+}
+
+// Verify checks a token against the key of the event it claims to belong to.
+func (s *CardSigner) Verify(token string) (*CardClaims, error) {
+	claims, _, _, err := decodeCardToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return VerifyCardToken(token, s.PublicKey(claims.EventID))
+}
+
+// VerifyCardToken validates a card token with an event public key alone, so
+// it can run on a scanner without access to the database or the secret.
+func VerifyCardToken(token string, publicKey ed25519.PublicKey) (*CardClaims, error) {
+	claims, payload, sig, err := decodeCardToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(publicKey) != ed25519.PublicKeySize || !ed25519.Verify(publicKey, payload, sig) {
+		return nil, ErrInvalidCardToken
+	}
+
+	return claims, nil
+}
+
+func decodeCardToken(token string) (*CardClaims, []byte, []byte, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) < 1+3+ed25519.SignatureSize {
+		return nil, nil, nil, ErrInvalidCardToken
+	}
+
+	payload, sig := raw[:len(raw)-ed25519.SignatureSize], raw[len(raw)-ed25519.SignatureSize:]
+	if payload[0] != cardTokenVersion {
+		return nil, nil, nil, ErrInvalidCardToken
+	}
+
+	var ids [3]int64
+	rest := payload[1:]
+	for i := range ids {
+		v, n := binary.Uvarint(rest)
+		if n <= 0 || v == 0 || v > 1<<63-1 {
+			return nil, nil, nil, ErrInvalidCardToken
+		}
+
+		ids[i] = int64(v)
+		rest = rest[n:]
+	}
+
+	if len(rest) != 0 {
+		return nil, nil, nil, ErrInvalidCardToken
+	}
+
+	return &CardClaims{CardID: ids[0], GuestID: ids[1], EventID: ids[2]}, payload, sig, nil
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestCardToken(t *testing.T) {
+	signer := NewCardSigner("test-secret")
+	claims := CardClaims{CardID: 42, GuestID: 7, EventID: 3}
+
+	token, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("verifies offline with the event public key", func(t *testing.T) {
+		got, err := VerifyCardToken(token, signer.PublicKey(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if *got != claims {
+			t.Errorf("expected %+v, got %+v", claims, *got)
+		}
+	})
+
+	t.Run("verifies with the signer", func(t *testing.T) {
+		if _, err := signer.Verify(token); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("rejects the key of another event", func(t *testing.T) {
+		if _, err := VerifyCardToken(token, signer.PublicKey(4)); err != ErrInvalidCardToken {
+			t.Errorf("expected ErrInvalidCardToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects tokens from another secret", func(t *testing.T) {
+		if _, err := NewCardSigner("other-secret").Verify(token); err != ErrInvalidCardToken {
+			t.Errorf("expected ErrInvalidCardToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects tampered tokens", func(t *testing.T) {
+		tampered := []byte(token)
+		tampered[2] ^= 'A' ^ 'B'
+
+		if _, err := signer.Verify(string(tampered)); err != ErrInvalidCardToken {
+			t.Errorf("expected ErrInvalidCardToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects garbage", func(t *testing.T) {
+		for _, token := range []string{"", "not-a-token", "AQ"} {
+			if _, err := signer.Verify(token); err != ErrInvalidCardToken {
+				t.Errorf("%q: expected ErrInvalidCardToken, got %v", token, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreamTicket(t *testing.T) {
+	signer := NewTicketSigner("test-secret")
+
+	ticket, err := signer.Sign(7, 3, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("returns the user", func(t *testing.T) {
+		userID, err := signer.Verify(ticket, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if userID != 7 {
+			t.Errorf("expected user 7, got %d", userID)
+		}
+	})
+
+	t.Run("rejects the feeds of another event", func(t *testing.T) {
+		if _, err := signer.Verify(ticket, 4); err != ErrInvalidTicket {
+			t.Errorf("expected ErrInvalidTicket, got %v", err)
+		}
+	})
+
+	t.Run("rejects tickets from another secret", func(t *testing.T) {
+		if _, err := NewTicketSigner("other-secret").Verify(ticket, 3); err != ErrInvalidTicket {
+			t.Errorf("expected ErrInvalidTicket, got %v", err)
+		}
+	})
+
+	t.Run("rejects expired tickets", func(t *testing.T) {
+		expired, err := signer.Sign(7, 3, time.Now().Add(-time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := signer.Verify(expired, 3); err != ErrInvalidTicket {
+			t.Errorf("expected ErrInvalidTicket, got %v", err)
+		}
+	})
+
+	t.Run("rejects tampered tickets", func(t *testing.T) {
+		tampered := []byte(ticket)
+		tampered[2] ^= 'A' ^ 'B'
+
+		if _, err := signer.Verify(string(tampered), 3); err != ErrInvalidTicket {
+			t.Errorf("expected ErrInvalidTicket, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+// Package blob stores card images and template assets on the local disk or
+// in an S3-compatible bucket, behind the same Store interface.
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Info describes a stored blob.
+type Info struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Store keeps blobs under slash separated keys such as
+// "templates/ab/ab12….png".
+type Store interface {
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	// Get returns the content of a blob, ErrNotFound when there is none. The
+	// caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, *Info, error)
+	Stat(ctx context.Context, key string) (*Info, error)
+	// Touch sets the modification time of a blob to now, ErrNotFound when
+	// there is none.
+	Touch(ctx context.Context, key string) error
+	// Delete removes a blob. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+	// List calls fn for every blob under prefix.
+	List(ctx context.Context, prefix string, fn func(Info) error) error
+	// SignedURL returns a URL downloading the blob until it expires.
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// PutContent stores data under a key derived from its SHA-256, so that the
+// same image uploaded twice is stored once. Content already stored is not
+// uploaded again but touched, so that it is as new as an upload to the
+// sweeps of unused blobs.
+func PutContent(ctx context.Context, s Store, prefix string, data []byte, contentType string) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	key := path.Join(prefix, hash[:2], hash+extension(contentType))
+
+	if err := s.Touch(ctx, key); err == nil {
+		return key, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+
+	if err := s.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// ReadAll returns the content of a blob.
+func ReadAll(ctx context.Context, s Store, key string) ([]byte, error) {
+	r, _, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+func extension(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "application/pdf":
+		return ".pdf"
+	default:
+		return ""
+	}
+}
+
+// checkKey rejects keys that would escape the store, e.g. "../secrets".
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return ErrInvalidKey
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testStore runs the behaviour every Store shares.
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	key, err := PutContent(ctx, s, "cards", []byte("card"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(key, "cards/") || !strings.HasSuffix(key, ".png") {
+		t.Errorf("unexpected key %q", key)
+	}
+
+	again, err := PutContent(ctx, s, "cards", []byte("card"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if again != key {
+		t.Errorf("expected the same content to be stored once, got %q and %q", key, again)
+	}
+
+	if err := s.Touch(ctx, "cards/00/missing.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected touching a missing blob to fail with ErrNotFound, got %v", err)
+	}
+
+	data, err := ReadAll(ctx, s, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "card" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Size != 4 || info.ContentType != "image/png" {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	var listed []string
+	err = s.List(ctx, "cards/", func(info Info) error {
+		listed = append(listed, info.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(listed) != 1 || listed[0] != key {
+		t.Errorf("expected only %q to be listed, got %v", key, listed)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a deleted blob to be missing, got %v", err)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("expected deleting a missing blob to succeed, got %v", err)
+	}
+
+	if _, err := s.Stat(ctx, "../secrets"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected keys leaving the store to be rejected, got %v", err)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	s, err := NewLocalStore(LocalConfig{Dir: t.TempDir(), BaseURL: "http://localhost:8080/v1/blobs/", Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStore(t, s)
+
+	t.Run("signed urls", func(t *testing.T) {
+		signed, err := s.SignedURL(context.Background(), "cards/ab/ab.png", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		key, query, ok := strings.Cut(strings.TrimPrefix(signed, "http://localhost:8080/v1/blobs/"), "?")
+		if !ok || key != "cards/ab/ab.png" {
+			t.Fatalf("unexpected url %q", signed)
+		}
+
+		values := parseQuery(t, query)
+		if err := s.Verify(key, values); err != nil {
+			t.Errorf("expected the url to verify, got %v", err)
+		}
+
+		if err := s.Verify("cards/ab/other.png", values); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("expected the signature to be bound to the key, got %v", err)
+		}
+
+		expired, _ := s.SignedURL(context.Background(), key, -time.Minute)
+		_, query, _ = strings.Cut(expired, "?")
+		if err := s.Verify(key, parseQuery(t, query)); !errors.Is(err, ErrExpired) {
+			t.Errorf("expected an expired url to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("touches content stored again", func(t *testing.T) {
+		ctx := context.Background()
+		key, err := PutContent(ctx, s, "cards", []byte("old"), "image/png")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		old := time.Now().Add(-24 * time.Hour)
+		if err := os.Chtimes(filepath.Join(s.cfg.Dir, filepath.FromSlash(key)), old, old); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := PutContent(ctx, s, "cards", []byte("old"), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := s.Stat(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if time.Since(info.ModTime) > time.Minute {
+			t.Errorf("expected the blob to be touched, got %v", info.ModTime)
+		}
+	})
+
+	t.Run("fs", func(t *testing.T) {
+		key, err := PutContent(context.Background(), s, "templates", []byte("template"), "image/jpeg")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := fs.ReadFile(FS(context.Background(), s), key)
+		if err != nil || string(data) != "template" {
+			t.Errorf("unexpected content %q, %v", data, err)
+		}
+
+		if _, err := fs.ReadFile(FS(context.Background(), s), "templates/missing.png"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected fs.ErrNotExist, got %v", err)
+		}
+	})
+}
+
+// TestS3Store runs against an S3-compatible server such as the MinIO of the
+// docker compose file when MINIO_ENDPOINT is set.
+func TestS3Store(t *testing.T) {
+	endpoint := os.Getenv("MINIO_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("MINIO_ENDPOINT is not set")
+	}
+
+	s, err := NewS3Store(context.Background(), S3Config{
+		Endpoint:  endpoint,
+		Bucket:    "blob-test",
+		AccessKey: os.Getenv("MINIO_ACCESS_KEY"),
+		SecretKey: os.Getenv("MINIO_SECRET_KEY"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStore(t, s)
+}
+
+func parseQuery(t *testing.T, query string) url.Values {
+	t.Helper()
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return values
+}
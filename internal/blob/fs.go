@@ -0,0 +1,53 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// FS exposes a store as a read-only fs.FS, e.g. for the card renderer to
+// read template images from.
+func FS(ctx context.Context, s Store) fs.FS {
+	return &storeFS{ctx: ctx, store: s}
+}
+
+type storeFS struct {
+	ctx   context.Context
+	store Store
+}
+
+func (f *storeFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	r, info, err := f.store.Get(f.ctx, name)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			err = fs.ErrNotExist
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &file{ReadCloser: r, info: info}, nil
+}
+
+type file struct {
+	io.ReadCloser
+	info *Info
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return fileInfo{f.info}, nil }
+
+type fileInfo struct{ info *Info }
+
+func (i fileInfo) Name() string       { return path.Base(i.info.Key) }
+func (i fileInfo) Size() int64        { return i.info.Size }
+func (i fileInfo) Mode() fs.FileMode  { return 0o444 }
+func (i fileInfo) ModTime() time.Time { return i.info.ModTime }
+func (i fileInfo) IsDir() bool        { return false }
+func (i fileInfo) Sys() any           { return nil }
@@ -0,0 +1,251 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid blob url signature")
+	ErrExpired          = errors.New("blob url expired")
+)
+
+// LocalConfig configures a store on the local disk.
+type LocalConfig struct {
+	Dir string
+	// BaseURL is where the API serves signed downloads, e.g.
+	// "http://localhost:8080/v1/blobs".
+	BaseURL string
+	// Secret signs download URLs.
+	Secret string
+}
+
+// LocalStore keeps blobs as files under a directory. Download URLs point to
+// the API, which checks them with Verify before serving the file.
+type LocalStore struct {
+	cfg LocalConfig
+}
+
+func NewLocalStore(cfg LocalConfig) (*LocalStore, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("blob directory is required")
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &LocalStore{cfg: cfg}, nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(s.cfg.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes to a temporary file renamed into place, so readers never see a
+// partial blob.
+func (s *LocalStore) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, *Info, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, localError(err)
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return f, localInfo(key, stat), nil
+}
+
+func (s *LocalStore) Stat(ctx context.Context, key string) (*Info, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(name)
+	if err != nil {
+		return nil, localError(err)
+	}
+
+	return localInfo(key, stat), nil
+}
+
+func (s *LocalStore) Touch(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return localError(os.Chtimes(name, now, now))
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *LocalStore) List(ctx context.Context, prefix string, fn func(Info) error) error {
+	root := s.cfg.Dir
+	if prefix = strings.TrimSuffix(prefix, "/"); prefix != "" {
+		var err error
+		if root, err = s.path(prefix); err != nil {
+			return err
+		}
+	}
+
+	err := filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.cfg.Dir, name)
+		if err != nil {
+			return err
+		}
+
+		stat, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		return fn(*localInfo(filepath.ToSlash(rel), stat))
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// SignedURL returns BaseURL/key?expires=…&signature=… where the signature
+// is the hex HMAC-SHA256 of the key and expiry.
+func (s *LocalStore) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+
+	exp := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+
+	q := url.Values{}
+	q.Set("expires", exp)
+	q.Set("signature", s.sign(key, exp))
+
+	return strings.TrimSuffix(s.cfg.BaseURL, "/") + "/" + key + "?" + q.Encode(), nil
+}
+
+// Verify checks the expiry and signature of a download URL for key.
+func (s *LocalStore) Verify(key string, query url.Values) error {
+	exp := query.Get("expires")
+
+	expiresAt, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	sig, err := hex.DecodeString(query.Get("signature"))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	want, _ := hex.DecodeString(s.sign(key, exp))
+	if !hmac.Equal(sig, want) {
+		return ErrInvalidSignature
+	}
+
+	if time.Now().Unix() > expiresAt {
+		return ErrExpired
+	}
+
+	return nil
+}
+
+func (s *LocalStore) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.Secret))
+	mac.Write([]byte(key + "\n" + expires))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func localInfo(key string, stat fs.FileInfo) *Info {
+	return &Info{
+		Key:         key,
+		Size:        stat.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		ModTime:     stat.ModTime(),
+	}
+}
+
+func localError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,231 @@
+package guestlist
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/sikozonpc/social/internal/store"
+	"github.com/xuri/excelize/v2"
+)
+
+var exportHeader = []string{"Name", "Phone Number", "Email", "Status", "Type", "Card ID", "Checked In At"}
+
+// formulaPrefixes start a formula, or a DDE command, in spreadsheet apps.
+const formulaPrefixes = "=+-@\t\r"
+
+// Writer writes guests one at a time in an export format. Close must be
+// called to flush the document to the underlying writer.
+type Writer interface {
+	Write(guest *store.Guest) error
+	Close() error
+}
+
+// NewWriter returns a Writer for format. The title is used as the heading of
+// printable formats.
+func NewWriter(w io.Writer, format Format, title string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	case FormatPDF:
+		return newPDFWriter(w, title), nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func exportRecord(guest *store.Guest) []string {
+	cardID := ""
+	if guest.CardID != 0 {
+		cardID = strconv.FormatInt(guest.CardID, 10)
+	}
+
+	return []string{
+		guest.Name,
+		guest.PhoneNumber,
+		guest.Email,
+		guest.Status,
+		guest.Type,
+		cardID,
+		guest.CheckedInAt,
+	}
+}
+
+// csvRecord is the exportRecord of guest with every cell that a spreadsheet
+// opening the file would run as a formula, e.g. a guest named
+// "=HYPERLINK(...)", prefixed with a quote to be shown as text. XLSX cells
+// are written as text and never run.
+func csvRecord(guest *store.Guest) []string {
+	record := exportRecord(guest)
+	for i, v := range record {
+		record[i] = escapeFormula(v)
+	}
+
+	return record
+}
+
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) && !isNumeric(value) {
+		return "'" + value
+	}
+
+	return value
+}
+
+// isNumeric reports whether value is a number or a phone number, e.g.
+// "+255 712-345-678", which is left as is: it cannot call anything.
+func isNumeric(value string) bool {
+	digits := 0
+	for i, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case i == 0 && (r == '+' || r == '-'):
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return false
+		}
+	}
+
+	return digits > 0
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	if err := cw.w.Write(exportHeader); err != nil {
+		return nil, err
+	}
+
+	return cw, nil
+}
+
+func (cw *csvWriter) Write(guest *store.Guest) error {
+	return cw.w.Write(csvRecord(guest))
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type xlsxWriter struct {
+	out  io.Writer
+	file *excelize.File
+	sw   *excelize.StreamWriter
+	row  int
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+
+	sw, err := file.NewStreamWriter("Sheet1")
+	if err != nil {
+		return nil, err
+	}
+
+	xw := &xlsxWriter{out: w, file: file, sw: sw, row: 1}
+	if err := xw.writeRow(exportHeader); err != nil {
+		return nil, err
+	}
+
+	return xw, nil
+}
+
+func (xw *xlsxWriter) writeRow(record []string) error {
+	cell, err := excelize.CoordinatesToCellName(1, xw.row)
+	if err != nil {
+		return err
+	}
+
+	values := make([]any, len(record))
+	for i, v := range record {
+		values[i] = v
+	}
+
+	xw.row++
+	return xw.sw.SetRow(cell, values)
+}
+
+func (xw *xlsxWriter) Write(guest *store.Guest) error {
+	return xw.writeRow(exportRecord(guest))
+}
+
+func (xw *xlsxWriter) Close() error {
+	defer xw.file.Close()
+
+	if err := xw.sw.Flush(); err != nil {
+		return err
+	}
+
+	return xw.file.Write(xw.out)
+}
+
+// widths of the exportHeader columns on a landscape A4 page, in mm
+var pdfColumnWidths = []float64{62, 35, 62, 24, 20, 20, 54}
+
+type pdfWriter struct {
+	out   io.Writer
+	pdf   *fpdf.Fpdf
+	tr    func(string) string
+	count int
+}
+
+func newPDFWriter(w io.Writer, title string) *pdfWriter {
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.SetAutoPageBreak(true, 15)
+
+	pw := &pdfWriter{out: w, pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(0, 10, pw.tr(title), "", 1, "L", false, 0, "")
+
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(230, 230, 230)
+		for i, h := range exportHeader {
+			pdf.CellFormat(pdfColumnWidths[i], 7, h, "1", 0, "L", true, 0, "")
+		}
+		pdf.Ln(-1)
+	})
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 8)
+		footer := fmt.Sprintf("Page %d - printed %s", pdf.PageNo(), time.Now().Format(time.DateTime))
+		pdf.CellFormat(0, 8, footer, "", 0, "R", false, 0, "")
+	})
+
+	pdf.AddPage()
+
+	return pw
+}
+
+func (pw *pdfWriter) Write(guest *store.Guest) error {
+	pw.pdf.SetFont("Helvetica", "", 9)
+
+	for i, v := range exportRecord(guest) {
+		pw.pdf.CellFormat(pdfColumnWidths[i], 6, pw.tr(v), "1", 0, "L", false, 0, "")
+	}
+	pw.pdf.Ln(-1)
+
+	pw.count++
+	return pw.pdf.Error()
+}
+
+func (pw *pdfWriter) Close() error {
+	pw.pdf.Ln(4)
+	pw.pdf.SetFont("Helvetica", "B", 9)
+	pw.pdf.CellFormat(0, 6, fmt.Sprintf("Total guests: %d", pw.count), "", 1, "L", false, 0, "")
+
+	return pw.pdf.Output(pw.out)
+}
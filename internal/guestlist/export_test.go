@@ -0,0 +1,162 @@
+package guestlist
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/sikozonpc/social/internal/store"
+	"github.com/xuri/excelize/v2"
+)
+
+var exportGuests = []store.Guest{
+	{Name: "=HYPERLINK(\"http://evil.example\",\"Juma\")", PhoneNumber: "+255712345678", Email: "juma@example.com", Status: "confirmed", Type: "single", CardID: 3},
+	{Name: "@SUM(1+1)", PhoneNumber: "-1", Email: "asha@example.com", Status: "pending", Type: "double"},
+	{Name: "Neema Mushi", PhoneNumber: "0712345678", Email: "", Status: "declined", Type: "single", CheckedInAt: "2024-12-21T18:05:00Z"},
+}
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"Juma", "Juma"},
+		{"=1+2", "'=1+2"},
+		{"+255712345678", "+255712345678"},
+		{"+255 712-345-678", "+255 712-345-678"},
+		{"+1 (415) 555-0100", "+1 (415) 555-0100"},
+		{"-1", "-1"},
+		{"-2.5", "-2.5"},
+		{"+", "'+"},
+		{"-2+3", "'-2+3"},
+		{"+1+cmd|' /C calc'!A0", "'+1+cmd|' /C calc'!A0"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"a=b", "a=b"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeFormula(tt.in); got != tt.want {
+			t.Errorf("escapeFormula(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+
+		if got := unescapeFormula(escapeFormula(tt.in)); got != tt.in {
+			t.Errorf("unescapeFormula(escapeFormula(%q)) = %q", tt.in, got)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		format Format
+		// record is what a guest is expected to be written as
+		record func(guest *store.Guest) []string
+		name   string
+		rows   func(t *testing.T, b []byte) [][]string
+	}{
+		{FormatCSV, csvRecord, `'=HYPERLINK("http://evil.example","Juma")`, func(t *testing.T, b []byte) [][]string {
+			rows, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return rows
+		}},
+		{FormatXLSX, exportRecord, `=HYPERLINK("http://evil.example","Juma")`, func(t *testing.T, b []byte) [][]string {
+			f, err := excelize.OpenReader(bytes.NewReader(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			rows, err := f.GetRows("Sheet1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// a cell written as a formula would not be a plain value
+			for i := range exportGuests {
+				cell, _ := excelize.CoordinatesToCellName(1, i+2)
+				if formula, _ := f.GetCellFormula("Sheet1", cell); formula != "" {
+					t.Errorf("expected %s to be text, got formula %q", cell, formula)
+				}
+			}
+			return rows
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			b := writeGuests(t, tt.format)
+
+			rows := tt.rows(t, b)
+			if len(rows) != len(exportGuests)+1 {
+				t.Fatalf("expected a header and %d guests, got %d rows", len(exportGuests), len(rows))
+			}
+
+			for i, guest := range exportGuests {
+				want := tt.record(&guest)
+				for j, cell := range rows[i+1] {
+					if cell != want[j] {
+						t.Errorf("row %d column %q = %q, want %q", i+1, exportHeader[j], cell, want[j])
+					}
+				}
+			}
+
+			if rows[1][0] != tt.name || rows[1][1] != "+255712345678" {
+				t.Errorf("expected name %q and the phone number as is, got %q", tt.name, rows[1])
+			}
+
+			imported, err := Read(bytes.NewReader(b), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, row := range imported {
+				guest := exportGuests[i]
+				if row.Guest.Name != guest.Name || row.Guest.PhoneNumber != guest.PhoneNumber {
+					t.Errorf("expected an exported list to import unchanged, got %+v for %+v", row.Guest, guest)
+				}
+			}
+		})
+	}
+
+	t.Run("pdf", func(t *testing.T) {
+		b := writeGuests(t, FormatPDF)
+
+		if !bytes.HasPrefix(b, []byte("%PDF-")) {
+			t.Fatalf("expected a PDF document, got %q", b[:min(len(b), 16)])
+		}
+
+		// printed lists are not run by spreadsheets and show names as given
+		if !bytes.Contains(b, []byte("(@SUM\\(1+1\\))")) {
+			t.Error("expected the guest name unescaped in the PDF")
+		}
+	})
+}
+
+func writeGuests(t *testing.T, format Format) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, "Harusi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pw, ok := w.(*pdfWriter); ok {
+		pw.pdf.SetCompression(false)
+	}
+
+	for i := range exportGuests {
+		if err := w.Write(&exportGuests[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
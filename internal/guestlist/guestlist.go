@@ -0,0 +1,45 @@
+package guestlist
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+	FormatPDF  Format = "pdf"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported guest list format")
+
+// ParseFormat resolves a format from an explicit value (e.g. a query
+// parameter) or, when that is empty, from the extension of filename.
+func ParseFormat(value, filename string) (Format, error) {
+	if value == "" {
+		value = strings.TrimPrefix(filepath.Ext(filename), ".")
+	}
+
+	switch f := Format(strings.ToLower(value)); f {
+	case FormatCSV, FormatXLSX, FormatPDF:
+		return f, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatPDF:
+		return "application/pdf"
+	default:
+		return "application/octet-stream"
+	}
+}
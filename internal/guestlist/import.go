@@ -0,0 +1,237 @@
+package guestlist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sikozonpc/social/internal/store"
+	"github.com/xuri/excelize/v2"
+)
+
+// MaxRows caps the number of guests accepted from a single file.
+const MaxRows = 5000
+
+var (
+	ErrMissingNameColumn = errors.New("guest list has no name column")
+	ErrTooManyRows       = fmt.Errorf("guest list has more than %d rows", MaxRows)
+)
+
+// Row is a guest read from a spreadsheet, along with the line it was found
+// on so validation errors can point the organiser back to the file.
+type Row struct {
+	Line  int         `json:"line"`
+	Guest store.Guest `json:"guest"`
+}
+
+type column int
+
+const (
+	columnUnknown column = iota
+	columnName
+	columnEmail
+	columnPhone
+	columnStatus
+	columnType
+)
+
+var headerAliases = map[string]column{
+	"name":          columnName,
+	"full name":     columnName,
+	"guest":         columnName,
+	"guest name":    columnName,
+	"jina":          columnName,
+	"email":         columnEmail,
+	"e mail":        columnEmail,
+	"email address": columnEmail,
+	"phone":         columnPhone,
+	"phone number":  columnPhone,
+	"mobile":        columnPhone,
+	"mobile number": columnPhone,
+	"simu":          columnPhone,
+	"status":        columnStatus,
+	"rsvp":          columnStatus,
+	"type":          columnType,
+	"guest type":    columnType,
+	"card type":     columnType,
+}
+
+// Read parses a guest list in the given format. The first non-empty row is
+// treated as the header and mapped to guest fields by name.
+func Read(r io.Reader, format Format) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(r)
+	case FormatXLSX:
+		return readXLSX(r)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func readCSV(r io.Reader) ([]Row, error) {
+	br := bufio.NewReader(r)
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	// Spreadsheets exported with a non-English locale use ';' as separator
+	buffered, _ := br.Peek(br.Size())
+	header := firstLine(buffered)
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	p := &parser{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.add(line, record); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.result()
+}
+
+func readXLSX(r io.Reader) ([]Row, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, ErrMissingNameColumn
+	}
+
+	rows, err := f.Rows(sheets[0])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	p := &parser{}
+	for line := 1; rows.Next(); line++ {
+		record, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.add(line, record); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := rows.Error(); err != nil {
+		return nil, err
+	}
+
+	return p.result()
+}
+
+type parser struct {
+	columns []column
+	rows    []Row
+}
+
+func (p *parser) add(line int, record []string) error {
+	if isBlank(record) {
+		return nil
+	}
+
+	if p.columns == nil {
+		p.columns = mapHeader(record)
+		return nil
+	}
+
+	if len(p.rows) == MaxRows {
+		return ErrTooManyRows
+	}
+
+	row := Row{Line: line}
+	for i, value := range record {
+		if i >= len(p.columns) {
+			break
+		}
+
+		value = unescapeFormula(strings.TrimSpace(value))
+		switch p.columns[i] {
+		case columnName:
+			row.Guest.Name = value
+		case columnEmail:
+			row.Guest.Email = value
+		case columnPhone:
+			row.Guest.PhoneNumber = value
+		case columnStatus:
+			row.Guest.Status = strings.ToLower(value)
+		case columnType:
+			row.Guest.Type = strings.ToLower(value)
+		}
+	}
+
+	p.rows = append(p.rows, row)
+	return nil
+}
+
+func (p *parser) result() ([]Row, error) {
+	for _, c := range p.columns {
+		if c == columnName {
+			return p.rows, nil
+		}
+	}
+
+	return nil, ErrMissingNameColumn
+}
+
+// unescapeFormula drops the quote escapeFormula adds, so exported guest
+// lists can be imported again.
+func unescapeFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+
+	return value
+}
+
+func mapHeader(record []string) []column {
+	columns := make([]column, len(record))
+	for i, h := range record {
+		h = strings.TrimPrefix(h, "\ufeff")
+		h = strings.NewReplacer("_", " ", "-", " ").Replace(strings.ToLower(strings.TrimSpace(h)))
+
+		columns[i] = headerAliases[h]
+	}
+
+	return columns
+}
+
+func isBlank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func firstLine(b []byte) []byte {
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		return b[:i]
+	}
+
+	return b
+}
@@ -0,0 +1,59 @@
+// Package live fans out check-in activity of an event to the clients
+// following it, e.g. dashboards and door scanners.
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Message types.
+const (
+	TypeCheckIn        = "check_in"
+	TypeCheckInUpdated = "check_in_updated"
+	TypeCheckInUndone  = "check_in_undone"
+	TypeGuestStatus    = "guest_status"
+	TypeGuestStatuses  = "guest_statuses" // the same status for many guests
+)
+
+// subscriberBuffer is the number of messages a subscriber can fall behind
+// before it misses messages.
+const subscriberBuffer = 64
+
+type Message struct {
+	Type    string          `json:"type"`
+	EventID int64           `json:"event_id"`
+	Data    json.RawMessage `json:"data"`
+	At      time.Time       `json:"at"`
+}
+
+func NewMessage(typ string, eventID int64, data any) (Message, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Type:    typ,
+		EventID: eventID,
+		Data:    raw,
+		At:      time.Now().UTC(),
+	}, nil
+}
+
+type Broker interface {
+	Publish(ctx context.Context, msg Message) error
+	Subscribe(eventID int64) *Subscription
+}
+
+// Subscription receives the messages of one event until it is closed.
+type Subscription struct {
+	C <-chan Message
+
+	close func()
+}
+
+func (s *Subscription) Close() {
+	s.close()
+}
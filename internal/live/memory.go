@@ -0,0 +1,60 @@
+package live
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBroker delivers messages to the subscribers of this process.
+type MemoryBroker struct {
+	mu   sync.RWMutex
+	subs map[int64]map[chan Message]struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subs: make(map[int64]map[chan Message]struct{})}
+}
+
+// Publish never blocks: a subscriber that is not keeping up misses the
+// message rather than holding up the publisher.
+func (b *MemoryBroker) Publish(ctx context.Context, msg Message) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subs[msg.EventID] {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(eventID int64) *Subscription {
+	ch := make(chan Message, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subs[eventID] == nil {
+		b.subs[eventID] = make(map[chan Message]struct{})
+	}
+	b.subs[eventID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return &Subscription{
+		C: ch,
+		close: func() {
+			once.Do(func() {
+				b.mu.Lock()
+				defer b.mu.Unlock()
+
+				delete(b.subs[eventID], ch)
+				if len(b.subs[eventID]) == 0 {
+					delete(b.subs, eventID)
+				}
+				close(ch)
+			})
+		},
+	}
+}
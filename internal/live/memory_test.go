@@ -0,0 +1,54 @@
+package live
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryBroker(t *testing.T) {
+	b := NewMemoryBroker()
+
+	sub := b.Subscribe(1)
+	other := b.Subscribe(2)
+	defer other.Close()
+
+	msg, err := NewMessage(TypeCheckIn, 1, map[string]int64{"guest_id": 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Publish(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-sub.C
+	if got.Type != TypeCheckIn || string(got.Data) != `{"guest_id":7}` {
+		t.Errorf("unexpected message %+v", got)
+	}
+
+	select {
+	case m := <-other.C:
+		t.Errorf("subscriber of another event received %+v", m)
+	default:
+	}
+
+	t.Run("slow subscribers do not block publishers", func(t *testing.T) {
+		for i := 0; i < subscriberBuffer*2; i++ {
+			b.Publish(context.Background(), msg)
+		}
+
+		if len(sub.C) != subscriberBuffer {
+			t.Errorf("expected %d buffered messages, got %d", subscriberBuffer, len(sub.C))
+		}
+	})
+
+	sub.Close()
+	sub.Close()
+
+	for range sub.C {
+	}
+
+	if _, ok := b.subs[1]; ok {
+		t.Error("expected closed subscription to be removed")
+	}
+}
@@ -0,0 +1,83 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const channelPrefix = "live:event:"
+
+// RedisBroker publishes messages through Redis pub/sub so subscribers
+// connected to any API instance receive them. Messages coming back from
+// Redis are delivered locally by a MemoryBroker.
+type RedisBroker struct {
+	rdb    *redis.Client
+	pubsub *redis.PubSub
+	local  *MemoryBroker
+	done   chan struct{}
+}
+
+func NewRedisBroker(ctx context.Context, rdb *redis.Client) (*RedisBroker, error) {
+	pubsub := rdb.PSubscribe(ctx, channelPrefix+"*")
+
+	// wait for the subscription to be confirmed so no message is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	b := &RedisBroker{
+		rdb:    rdb,
+		pubsub: pubsub,
+		local:  NewMemoryBroker(),
+		done:   make(chan struct{}),
+	}
+
+	go b.listen()
+
+	return b, nil
+}
+
+func (b *RedisBroker) listen() {
+	defer close(b.done)
+
+	for m := range b.pubsub.Channel() {
+		if !strings.HasPrefix(m.Channel, channelPrefix) {
+			continue
+		}
+
+		var msg Message
+		if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+			continue
+		}
+
+		b.local.Publish(context.Background(), msg)
+	}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	channel := channelPrefix + strconv.FormatInt(msg.EventID, 10)
+
+	return b.rdb.Publish(ctx, channel, payload).Err()
+}
+
+func (b *RedisBroker) Subscribe(eventID int64) *Subscription {
+	return b.local.Subscribe(eventID)
+}
+
+// Close stops listening to Redis. The client itself is left open.
+func (b *RedisBroker) Close() error {
+	err := b.pubsub.Close()
+	<-b.done
+
+	return err
+}
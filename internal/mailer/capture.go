@@ -0,0 +1,242 @@
+package mailer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCaptured is how many mails the in-memory capture keeps.
+const maxCaptured = 200
+
+var ErrMailNotFound = errors.New("captured mail not found")
+
+// CapturedMail is a rendered mail kept by the CaptureMailer.
+type CapturedMail struct {
+	ID          string               `json:"id"`
+	Template    string               `json:"template"`
+	Username    string               `json:"username"`
+	Email       string               `json:"email"`
+	ReplyTo     string               `json:"reply_to,omitempty"`
+	Headers     map[string]string    `json:"headers,omitempty"`
+	Subject     string               `json:"subject"`
+	HTML        string               `json:"html"`
+	Text        string               `json:"text,omitempty"`
+	Attachments []CapturedAttachment `json:"attachments,omitempty"`
+	Sandbox     bool                 `json:"sandbox"`
+	SentAt      time.Time            `json:"sent_at"`
+}
+
+type CapturedAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	Inline      bool   `json:"inline"`
+	ContentID   string `json:"content_id,omitempty"`
+	// Content is kept for inline images, so that the captured HTML can be
+	// shown with them.
+	Content []byte `json:"content,omitempty"`
+}
+
+// CaptureMailer renders mails and keeps them instead of sending them, for
+// development. Mails are kept in memory, or as JSON files in dir when set so
+// that they survive restarts.
+type CaptureMailer struct {
+	mu    sync.Mutex
+	dir   string
+	mails []CapturedMail
+	seq   int
+}
+
+func NewCaptureMailer(dir string) (*CaptureMailer, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	return &CaptureMailer{dir: dir}, nil
+}
+
+func (c *CaptureMailer) Send(templateFile, username, email string, data any, isSandbox bool) (int, error) {
+	return c.SendMessage(&Message{Template: templateFile, Username: username, Email: email, Data: data}, isSandbox)
+}
+
+func (c *CaptureMailer) SendMessage(msg *Message, isSandbox bool) (int, error) {
+	rendered, err := renderMessage(msg)
+	if err != nil {
+		return -1, err
+	}
+
+	mail := CapturedMail{
+		Template: msg.Template,
+		Username: msg.Username,
+		Email:    msg.Email,
+		ReplyTo:  msg.ReplyTo,
+		Headers:  msg.Headers,
+		Subject:  rendered.Subject,
+		HTML:     rendered.HTML,
+		Text:     rendered.Text,
+		Sandbox:  isSandbox,
+		SentAt:   time.Now().UTC(),
+	}
+
+	for _, a := range msg.Attachments {
+		captured := CapturedAttachment{
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Size:        len(a.Content),
+			Inline:      a.Inline,
+			ContentID:   a.ContentID,
+		}
+		if a.Inline {
+			captured.Content = a.Content
+		}
+
+		mail.Attachments = append(mail.Attachments, captured)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+	// ids sort in the order mails were captured, also across restarts
+	mail.ID = fmt.Sprintf("%d-%d", mail.SentAt.UnixNano(), c.seq)
+
+	if c.dir != "" {
+		b, err := json.Marshal(mail)
+		if err != nil {
+			return -1, err
+		}
+
+		if err := os.WriteFile(filepath.Join(c.dir, mail.ID+".json"), b, 0o644); err != nil {
+			return -1, err
+		}
+
+		return 200, nil
+	}
+
+	c.mails = append(c.mails, mail)
+	if len(c.mails) > maxCaptured {
+		c.mails = c.mails[len(c.mails)-maxCaptured:]
+	}
+
+	return 200, nil
+}
+
+// List returns the captured mails, latest first, without their bodies.
+func (c *CaptureMailer) List() ([]CapturedMail, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	mails := []CapturedMail{}
+	if c.dir == "" {
+		mails = append(mails, c.mails...)
+	} else {
+		entries, err := os.ReadDir(c.dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries {
+			id, ok := strings.CutSuffix(e.Name(), ".json")
+			if !ok {
+				continue
+			}
+
+			mail, err := c.read(id)
+			if err != nil {
+				return nil, err
+			}
+
+			mails = append(mails, *mail)
+		}
+	}
+
+	sort.Slice(mails, func(i, j int) bool { return mails[i].SentAt.After(mails[j].SentAt) })
+
+	for i := range mails {
+		mails[i].HTML = ""
+		mails[i].Text = ""
+
+		// the attachments are shared with the kept mail
+		attachments := make([]CapturedAttachment, len(mails[i].Attachments))
+		for j, a := range mails[i].Attachments {
+			a.Content = nil
+			attachments[j] = a
+		}
+		mails[i].Attachments = attachments
+	}
+
+	return mails, nil
+}
+
+// Get returns a captured mail.
+func (c *CaptureMailer) Get(id string) (*CapturedMail, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dir != "" {
+		return c.read(id)
+	}
+
+	for _, mail := range c.mails {
+		if mail.ID == id {
+			return &mail, nil
+		}
+	}
+
+	return nil, ErrMailNotFound
+}
+
+// Clear forgets all captured mails.
+func (c *CaptureMailer) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.mails = nil
+	if c.dir == "" {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *CaptureMailer) read(id string) (*CapturedMail, error) {
+	// ids are generated here, anything else is not a captured mail
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, ErrMailNotFound
+	}
+
+	b, err := os.ReadFile(filepath.Join(c.dir, id+".json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrMailNotFound
+		}
+		return nil, err
+	}
+
+	var mail CapturedMail
+	if err := json.Unmarshal(b, &mail); err != nil {
+		return nil, err
+	}
+
+	return &mail, nil
+}
@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"testing"
+)
+
+func TestCaptureMailer(t *testing.T) {
+	data := map[string]string{"GuestName": "Neema", "EventName": "Harusi", "When": "tomorrow", "RSVPURL": "http://rsvp"}
+
+	for name, dir := range map[string]string{"memory": "", "disk": t.TempDir()} {
+		t.Run(name, func(t *testing.T) {
+			c, err := NewCaptureMailer(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			msg := &Message{
+				Template:    ReminderTemplate,
+				Username:    "Neema",
+				Email:       "neema@example.com",
+				Data:        data,
+				Attachments: []Attachment{{Filename: "card.png", ContentType: "image/png", Content: []byte("png"), Inline: true, ContentID: "card"}},
+			}
+
+			for i := 0; i < 2; i++ {
+				if _, err := c.SendMessage(msg, true); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mails, err := c.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(mails) != 2 || mails[0].Subject != "Reminder: Harusi is tomorrow" || mails[0].HTML != "" {
+				t.Fatalf("unexpected captured mails %+v", mails)
+			}
+
+			mail, err := c.Get(mails[0].ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if mail.HTML == "" || string(mail.Attachments[0].Content) != "png" {
+				t.Errorf("expected the full mail, got %+v", mail)
+			}
+
+			if _, err := c.Get("../secrets"); err != ErrMailNotFound {
+				t.Errorf("expected unknown ids not to be found, got %v", err)
+			}
+
+			if err := c.Clear(); err != nil {
+				t.Fatal(err)
+			}
+
+			if mails, _ := c.List(); len(mails) != 0 {
+				t.Errorf("expected no mails after clearing, got %d", len(mails))
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package mailer
+
+// WelcomeData is available to the user invitation template.
+type WelcomeData struct {
+	Username      string
+	ActivationURL string
+}
+
+// RSVPSummaryData is available to the RSVP summary template.
+type RSVPSummaryData struct {
+	Username  string
+	EventName string
+	GuestsURL string
+	Summary   RSVPCounts
+}
+
+type RSVPCounts struct {
+	Accepted, Declined, Maybe, NoResponse, PlusOnes, Expired int
+}
+
+// ReminderData is available to the reminder templates of every channel.
+type ReminderData struct {
+	GuestName string
+	EventName string
+	Date      string
+	Location  string
+	// When is relative to the day the reminder is sent, e.g. "tomorrow".
+	When    string
+	RSVPURL string
+}
+
+// InvitationData is available to the guest invitation template and the
+// caption of card messages.
+type InvitationData struct {
+	GuestName string
+	EventName string
+	Date      string
+	Location  string
+	RSVPURL   string
+	// CardCID refers to the card image embedded in the mail.
+	CardCID string
+}
+
+// ThankYouData is available to the guest thank-you template.
+type ThankYouData struct {
+	GuestName string
+	EventName string
+	Message   string
+	HostName  string
+}
+
+// samples is the data every template is rendered with to validate and
+// preview edits, and in the golden files.
+var samples = map[string]any{
+	UserWelcomeTemplate: WelcomeData{
+		Username:      "Neema",
+		ActivationURL: "http://localhost:5173/confirm/sample-token",
+	},
+	RSVPSummaryTemplate: RSVPSummaryData{
+		Username:  "Neema",
+		EventName: "Harusi ya Neema & Baraka",
+		GuestsURL: "http://localhost:5173/events/1/guests",
+		Summary:   RSVPCounts{Accepted: 120, Declined: 14, Maybe: 9, NoResponse: 21, PlusOnes: 35, Expired: 21},
+	},
+	ReminderTemplate: ReminderData{
+		GuestName: "Amani",
+		EventName: "Harusi ya Neema & Baraka",
+		Date:      "Saturday, 12 December 2026",
+		Location:  "Mlimani City Hall, Dar es Salaam",
+		When:      "tomorrow",
+		RSVPURL:   "http://localhost:5173/rsvp/sample-token",
+	},
+	GuestInvitationTemplate: InvitationData{
+		GuestName: "Amani",
+		EventName: "Harusi ya Neema & Baraka",
+		Date:      "Saturday, 12 December 2026",
+		Location:  "Mlimani City Hall, Dar es Salaam",
+		RSVPURL:   "http://localhost:5173/rsvp/sample-token",
+		CardCID:   "card",
+	},
+	GuestThankYouTemplate: ThankYouData{
+		GuestName: "Amani",
+		EventName: "Harusi ya Neema & Baraka",
+		Message:   "The photos will be shared next week.",
+		HostName:  "Neema & Baraka",
+	},
+}
+
+// SampleData returns the sample data of a template, false for unknown
+// templates.
+func SampleData(templateFile string) (any, bool) {
+	data, ok := samples[templateFile]
+	return data, ok
+}
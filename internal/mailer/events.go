@@ -0,0 +1,153 @@
+package mailer
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sendgrid/sendgrid-go/helpers/eventwebhook"
+)
+
+// Events reported by the provider after which no more mail is sent to an
+// address.
+const (
+	EventBounce      = "bounce"
+	EventDropped     = "dropped"
+	EventSpamReport  = "spamreport"
+	EventUnsubscribe = "unsubscribe"
+
+	// bounceBlocked is the type of bounces the receiving server expects to
+	// lift, e.g. when the sender is rate limited.
+	bounceBlocked = "blocked"
+
+	maxEventsSize = 1 << 20
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrSuppressed is returned when sending to an address that bounced,
+	// complained or unsubscribed. Sending again will not succeed.
+	ErrSuppressed = errors.New("the recipient address is suppressed")
+)
+
+// Event is one entry of a SendGrid-style event webhook payload. Events the
+// webhook is not interested in, e.g. opens, are read as well.
+type Event struct {
+	Email     string `json:"email"`
+	Event     string `json:"event"`
+	Reason    string `json:"reason"`
+	Type      string `json:"type"`
+	Timestamp int64  `json:"timestamp"`
+	MessageID string `json:"sg_message_id"`
+}
+
+// Suppresses reports whether the event means no more mail should be sent to
+// its address.
+func (e Event) Suppresses() bool {
+	switch e.Event {
+	case EventBounce:
+		return e.Type != bounceBlocked
+	case EventDropped, EventSpamReport, EventUnsubscribe:
+		return true
+	default:
+		return false
+	}
+}
+
+// EventWebhook reads the event webhook of the provider, verifying the
+// ECDSA signature SendGrid sends with every request when a public key is
+// set.
+type EventWebhook struct {
+	key *ecdsa.PublicKey
+}
+
+// NewEventWebhook takes the base64 verification key shown in the provider's
+// signed event webhook settings. Requests are not verified without one.
+func NewEventWebhook(publicKey string) (*EventWebhook, error) {
+	if publicKey == "" {
+		return &EventWebhook{}, nil
+	}
+
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("decoding event webhook public key: %w", err)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parsing event webhook public key: %w", err)
+	}
+
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("event webhook public key is not an ECDSA key")
+	}
+
+	return &EventWebhook{key: ecKey}, nil
+}
+
+// ParseEvents verifies the request signature before reading the events.
+func (w *EventWebhook) ParseEvents(r *http.Request) ([]Event, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxEventsSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if w.key != nil {
+		signature := r.Header.Get(eventwebhook.VerificationHTTPHeader)
+		timestamp := r.Header.Get(eventwebhook.TimestampHTTPHeader)
+
+		ok, err := eventwebhook.VerifySignature(w.key, body, signature, timestamp)
+		if err != nil || !ok {
+			return nil, ErrInvalidSignature
+		}
+	}
+
+	var events []Event
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, fmt.Errorf("decoding events: %w", err)
+	}
+
+	for i := range events {
+		events[i].Email = strings.ToLower(strings.TrimSpace(events[i].Email))
+	}
+
+	return events, nil
+}
+
+// SuppressedFunc reports whether mail to an address is suppressed.
+type SuppressedFunc func(email string) (bool, error)
+
+// WithSuppressions returns a client that refuses to send to suppressed
+// addresses with ErrSuppressed instead of handing the mail to c.
+func WithSuppressions(c Client, isSuppressed SuppressedFunc) Client {
+	return &suppressingClient{Client: c, isSuppressed: isSuppressed}
+}
+
+type suppressingClient struct {
+	Client
+	isSuppressed SuppressedFunc
+}
+
+func (c *suppressingClient) Send(templateFile, username, email string, data any, isSandbox bool) (int, error) {
+	return c.SendMessage(&Message{Template: templateFile, Username: username, Email: email, Data: data}, isSandbox)
+}
+
+func (c *suppressingClient) SendMessage(msg *Message, isSandbox bool) (int, error) {
+	suppressed, err := c.isSuppressed(msg.Email)
+	if err != nil {
+		return -1, err
+	}
+
+	if suppressed {
+		return -1, ErrSuppressed
+	}
+
+	return c.Client.SendMessage(msg, isSandbox)
+}
@@ -0,0 +1,103 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sendgrid/sendgrid-go/helpers/eventwebhook"
+)
+
+func TestEventWebhook(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	webhook, err := NewEventWebhook(base64.StdEncoding.EncodeToString(der))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte(`[
+		{"email": "Neema@Example.com", "event": "bounce", "type": "bounce", "reason": "550 unknown user"},
+		{"email": "amani@example.com", "event": "bounce", "type": "blocked"},
+		{"email": "baraka@example.com", "event": "spamreport"},
+		{"email": "baraka@example.com", "event": "open"}
+	]`)
+
+	sign := func(timestamp string, body []byte) string {
+		hash := sha256.Sum256(append([]byte(timestamp), body...))
+		sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(sig)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set(eventwebhook.TimestampHTTPHeader, "1700000000")
+	req.Header.Set(eventwebhook.VerificationHTTPHeader, sign("1700000000", body))
+
+	events, err := webhook.ParseEvents(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 4 || events[0].Email != "neema@example.com" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	for i, want := range []bool{true, false, true, false} {
+		if events[i].Suppresses() != want {
+			t.Errorf("expected %+v to suppress: %v", events[i], want)
+		}
+	}
+
+	t.Run("should reject forged requests", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set(eventwebhook.TimestampHTTPHeader, "1700000001")
+		req.Header.Set(eventwebhook.VerificationHTTPHeader, sign("1700000000", body))
+
+		if _, err := webhook.ParseEvents(req); err != ErrInvalidSignature {
+			t.Errorf("expected a tampered timestamp to be rejected, got %v", err)
+		}
+
+		req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+
+		if _, err := webhook.ParseEvents(req); err != ErrInvalidSignature {
+			t.Errorf("expected an unsigned request to be rejected, got %v", err)
+		}
+	})
+}
+
+func TestWithSuppressions(t *testing.T) {
+	mock := &MockClient{}
+	client := WithSuppressions(mock, func(email string) (bool, error) {
+		return email == "neema@example.com", nil
+	})
+
+	if _, err := client.Send(ReminderTemplate, "Neema", "neema@example.com", nil, true); err != ErrSuppressed {
+		t.Errorf("expected suppressed addresses to be skipped, got %v", err)
+	}
+
+	if _, err := client.SendMessage(&Message{Template: ReminderTemplate, Email: "amani@example.com"}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mock.Sent) != 1 || mock.Sent[0].Email != "amani@example.com" {
+		t.Errorf("expected only the mail to amani to be sent, got %+v", mock.Sent)
+	}
+}
@@ -0,0 +1,54 @@
+package mailer
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// TestGolden renders every template with its sample data and compares the
+// mail with testdata/<template>.golden. Run go test -update after changing a
+// template and review the diff of the golden file.
+func TestGolden(t *testing.T) {
+	names, err := Templates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			data, ok := SampleData(name)
+			if !ok {
+				t.Fatalf("no sample data for %s", name)
+			}
+
+			rendered, err := Render(name, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := fmt.Sprintf("Subject: %s\n\n%s\n-- text --\n%s\n", rendered.Subject, rendered.HTML, rendered.Text)
+
+			path := filepath.Join("testdata", strings.TrimSuffix(name, ".tmpl")+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != string(want) {
+				t.Errorf("%s does not match %s, run go test -update to review the changes:\n%s", name, path, got)
+			}
+		})
+	}
+}
@@ -1,16 +1,151 @@
 package mailer
 
-import "embed"
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+)
 
 const (
 	FromName            = "GopherSocial"
-	maxRetires          = 3
 	UserWelcomeTemplate = "user_invitation.tmpl"
+	RSVPSummaryTemplate = "rsvp_summary.tmpl"
+	ReminderTemplate    = "event_reminder.tmpl"
+
+	// Guest-facing templates
+	GuestInvitationTemplate = "guest_invitation.tmpl"
+	GuestThankYouTemplate   = "guest_thank_you.tmpl"
 )
 
+// DefaultLocale is the locale of the embedded templates.
+const DefaultLocale = "en"
+
 //go:embed "templates"
 var FS embed.FS
 
 type Client interface {
 	Send(templateFile, username, email string, data any, isSandbox bool) (int, error)
+	// SendMessage sends a mail with attachments, inline images, a reply-to
+	// address or custom headers.
+	SendMessage(msg *Message, isSandbox bool) (int, error)
+}
+
+// Message is a mail rendered from Template with Data to one recipient.
+type Message struct {
+	Template string
+	Username string
+	Email    string
+	Data     any
+	// ReplyTo is where answers go instead of the sender, e.g. the host.
+	ReplyTo     string
+	Headers     map[string]string
+	Attachments []Attachment
+	// Locale picks the translation of the template, DefaultLocale when
+	// empty.
+	Locale string
+	// Source replaces the embedded template when set, see WithOverrides.
+	Source *Source
+}
+
+// Attachment is a file sent with a mail. Inline attachments are shown in the
+// HTML body where it refers to them as "cid:<ContentID>".
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+	Inline      bool
+	ContentID   string
+}
+
+// Rendered is a mail template executed with its data.
+type Rendered struct {
+	Subject string
+	HTML    string
+	// Text is the plain-text alternative, empty when the template has no
+	// "text" block.
+	Text string
+}
+
+// Render renders an embedded template: its "subject" and "text" blocks as
+// plain text and its "content" block as HTML in the base layout.
+func Render(templateFile string, data any) (*Rendered, error) {
+	tmpl, err := parseTemplate(templateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return tmpl.execute(data)
+}
+
+// renderMessage renders msg from its Source when set, from the embedded
+// template otherwise.
+func renderMessage(msg *Message) (*Rendered, error) {
+	if msg.Source != nil {
+		return msg.Source.Render(msg.Data)
+	}
+
+	return Render(msg.Template, msg.Data)
+}
+
+// mailTemplate is a template parsed twice: the subject and the text
+// alternative are not HTML and must not be escaped as such, while names and
+// other data in the HTML body must be.
+type mailTemplate struct {
+	text *template.Template
+	html *htmltemplate.Template
+}
+
+func parseTemplate(templateFile string) (*mailTemplate, error) {
+	name := "templates/" + templateFile
+
+	text, err := template.ParseFS(FS, name)
+	if err != nil {
+		return nil, err
+	}
+
+	html, err := parseLayout()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := html.ParseFS(FS, name); err != nil {
+		return nil, err
+	}
+
+	return &mailTemplate{text: text, html: html}, nil
+}
+
+// parseLayout parses the base layout, which renders the "content" block of
+// a template, and the partials templates can use.
+func parseLayout() (*htmltemplate.Template, error) {
+	return htmltemplate.ParseFS(FS, "templates/layouts/*.tmpl", "templates/partials/*.tmpl")
+}
+
+func (t *mailTemplate) execute(data any) (*Rendered, error) {
+	subject := new(bytes.Buffer)
+	if err := t.text.ExecuteTemplate(subject, "subject", data); err != nil {
+		return nil, err
+	}
+
+	body := new(bytes.Buffer)
+	if err := t.html.ExecuteTemplate(body, "layout", data); err != nil {
+		return nil, err
+	}
+
+	rendered := &Rendered{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    body.String(),
+	}
+
+	if t.text.Lookup("text") != nil {
+		text := new(bytes.Buffer)
+		if err := t.text.ExecuteTemplate(text, "text", data); err != nil {
+			return nil, err
+		}
+		rendered.Text = strings.TrimSpace(text.String())
+	}
+
+	return rendered, nil
 }
@@ -0,0 +1,92 @@
+package mailer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	data := map[string]string{"GuestName": "Neema", "EventName": "Harusi", "RSVPURL": "http://rsvp", "CardCID": "card"}
+
+	rendered, err := Render(GuestInvitationTemplate, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rendered.Subject != "You are invited to Harusi" {
+		t.Errorf("unexpected subject %q", rendered.Subject)
+	}
+
+	if !strings.Contains(rendered.HTML, `src="cid:card"`) {
+		t.Errorf("expected the inline card in the html body")
+	}
+
+	if !strings.HasPrefix(rendered.Text, "Hi Neema,") || strings.Contains(rendered.Text, "<") {
+		t.Errorf("unexpected text alternative %q", rendered.Text)
+	}
+
+	t.Run("should escape data in the html body only", func(t *testing.T) {
+		data := InvitationData{GuestName: `<script>alert("hi")</script>`, EventName: "Neema & Baraka", RSVPURL: "javascript:alert(1)"}
+
+		rendered, err := Render(GuestInvitationTemplate, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Contains(rendered.HTML, "<script>") || strings.Contains(rendered.HTML, `href="javascript:`) {
+			t.Errorf("expected the html body to be escaped, got %s", rendered.HTML)
+		}
+
+		if rendered.Subject != "You are invited to Neema & Baraka" || !strings.HasPrefix(rendered.Text, "Hi <script>") {
+			t.Errorf("expected the subject and text to be left as is, got %q %q", rendered.Subject, rendered.Text)
+		}
+	})
+
+	t.Run("should leave the text empty without a text block", func(t *testing.T) {
+		rendered, err := Render(UserWelcomeTemplate, map[string]string{"Username": "admin"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rendered.Text != "" {
+			t.Errorf("expected no text alternative, got %q", rendered.Text)
+		}
+	})
+}
+
+func TestGomailMessage(t *testing.T) {
+	msg := &Message{
+		Template: GuestInvitationTemplate,
+		Username: "Neema",
+		Email:    "neema@example.com",
+		ReplyTo:  "host@example.com",
+		Headers:  map[string]string{"X-Event-ID": "1"},
+		Attachments: []Attachment{
+			{Filename: "card.png", ContentType: "image/png", Content: []byte("png"), Inline: true, ContentID: "card"},
+			{Filename: "card.png", ContentType: "image/png", Content: []byte("png")},
+		},
+	}
+
+	rendered := &Rendered{Subject: "Invitation", HTML: "<p>Hi</p>", Text: "Hi"}
+
+	var buf bytes.Buffer
+	if _, err := newGomailMessage("events@example.com", msg, rendered).WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	raw := buf.String()
+	for _, want := range []string{
+		"Reply-To: host@example.com",
+		"X-Event-ID: 1",
+		"multipart/alternative",
+		"Content-Type: text/plain",
+		"Content-ID: <card>",
+		"Content-Disposition: inline",
+		"Content-Disposition: attachment",
+	} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("expected message to contain %q", want)
+		}
+	}
+}
@@ -1,61 +1,19 @@
 package mailer
 
-import (
-	"bytes"
-	"errors"
+import "errors"
 
-	"text/template"
-
-	gomail "gopkg.in/mail.v2"
-)
-
-type mailtrapClient struct {
-	fromEmail string
-	apiKey    string
-}
-
-func NewMailTrapClient(apiKey, fromEmail string) (mailtrapClient, error) {
+// NewMailTrapClient sends through the Mailtrap SMTP relay.
+func NewMailTrapClient(apiKey, fromEmail string) (*SMTPClient, error) {
 	if apiKey == "" {
-		return mailtrapClient{}, errors.New("api key is required")
-	}
-	
-	return mailtrapClient{
-		fromEmail: fromEmail,
-		apiKey:    apiKey,
-	}, nil
-}
-
-func (m mailtrapClient) Send(templateFile, username, email string, data any, isSandbox bool) (int, error) {
-	// Template parsing and building
-	tmpl, err := template.ParseFS(FS, "templates/"+templateFile)
-	if err != nil {
-		return -1, err
-	}
-
-	subject := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(subject, "subject", data)
-	if err != nil {
-		return -1, err
-	}
-
-	body := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(body, "body", data)
-	if err != nil {
-		return -1, err
-	}
-
-	message := gomail.NewMessage()
-	message.SetHeader("From", m.fromEmail)
-	message.SetHeader("To", email)
-	message.SetHeader("Subject", subject.String())
-
-	message.AddAlternative("text/html", body.String())
-
-	dialer := gomail.NewDialer("live.smtp.mailtrap.io", 587, "api", m.apiKey)
-
-	if err := dialer.DialAndSend(message); err != nil {
-		return -1, err
+		return nil, errors.New("api key is required")
 	}
 
-	return 200, nil
+	return NewSMTPClient(SMTPConfig{
+		Host:      "live.smtp.mailtrap.io",
+		Port:      587,
+		Username:  "api",
+		Password:  apiKey,
+		TLS:       TLSStartTLS,
+		FromEmail: fromEmail,
+	})
 }
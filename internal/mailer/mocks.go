@@ -0,0 +1,41 @@
+package mailer
+
+import "sync"
+
+// MockClient records the mails it is asked to send.
+type MockClient struct {
+	mu   sync.Mutex
+	Sent []MockMail
+}
+
+type MockMail struct {
+	Template    string
+	Username    string
+	Email       string
+	Data        any
+	ReplyTo     string
+	Attachments []Attachment
+	Locale      string
+	Source      *Source
+}
+
+func (m *MockClient) Send(templateFile, username, email string, data any, isSandbox bool) (int, error) {
+	return m.SendMessage(&Message{Template: templateFile, Username: username, Email: email, Data: data}, isSandbox)
+}
+
+func (m *MockClient) SendMessage(msg *Message, isSandbox bool) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Sent = append(m.Sent, MockMail{
+		Template:    msg.Template,
+		Username:    msg.Username,
+		Email:       msg.Email,
+		Data:        msg.Data,
+		ReplyTo:     msg.ReplyTo,
+		Attachments: msg.Attachments,
+		Locale:      msg.Locale,
+		Source:      msg.Source,
+	})
+	return 200, nil
+}
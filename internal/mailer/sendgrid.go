@@ -1,10 +1,8 @@
 package mailer
 
 import (
-	"bytes"
+	"encoding/base64"
 	"fmt"
-	"text/template"
-	"time"
 
 	"github.com/sendgrid/sendgrid-go"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
@@ -27,28 +25,41 @@ func NewSendgrid(apiKey, fromEmail string) *SendGridMailer {
 }
 
 func (m *SendGridMailer) Send(templateFile, username, email string, data any, isSandbox bool) (int, error) {
+	return m.SendMessage(&Message{Template: templateFile, Username: username, Email: email, Data: data}, isSandbox)
+}
+
+func (m *SendGridMailer) SendMessage(msg *Message, isSandbox bool) (int, error) {
 	from := mail.NewEmail(FromName, m.fromEmail)
-	to := mail.NewEmail(username, email)
+	to := mail.NewEmail(msg.Username, msg.Email)
 
-	// template parsing and building
-	tmpl, err := template.ParseFS(FS, "templates/"+templateFile)
+	rendered, err := renderMessage(msg)
 	if err != nil {
 		return -1, err
 	}
 
-	subject := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(subject, "subject", data)
-	if err != nil {
-		return -1, err
+	message := mail.NewSingleEmail(from, rendered.Subject, to, rendered.Text, rendered.HTML)
+
+	if msg.ReplyTo != "" {
+		message.SetReplyTo(mail.NewEmail("", msg.ReplyTo))
 	}
 
-	body := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(body, "body", data)
-	if err != nil {
-		return -1, err
+	for k, v := range msg.Headers {
+		message.SetHeader(k, v)
 	}
 
-	message := mail.NewSingleEmail(from, subject.String(), to, "", body.String())
+	for _, a := range msg.Attachments {
+		attachment := mail.NewAttachment().
+			SetContent(base64.StdEncoding.EncodeToString(a.Content)).
+			SetType(a.ContentType).
+			SetFilename(a.Filename).
+			SetDisposition("attachment")
+
+		if a.Inline {
+			attachment.SetDisposition("inline").SetContentID(a.ContentID)
+		}
+
+		message.AddAttachment(attachment)
+	}
 
 	message.SetMailSettings(&mail.MailSettings{
 		SandboxMode: &mail.Setting{
@@ -56,17 +67,15 @@ func (m *SendGridMailer) Send(templateFile, username, email string, data any, is
 		},
 	})
 
-	var retryErr error
-	for i := 0; i < maxRetires; i++ {
-		response, retryErr := m.client.Send(message)
-		if retryErr != nil {
-			// exponential backoff
-			time.Sleep(time.Second * time.Duration(i+1))
-			continue
-		}
+	// failed mails are retried by the caller, e.g. the outbox worker
+	response, err := m.client.Send(message)
+	if err != nil {
+		return -1, err
+	}
 
-		return response.StatusCode, nil
+	if response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("sendgrid replied %d: %s", response.StatusCode, response.Body)
 	}
 
-	return -1, fmt.Errorf("failed to send email after %d attempt, error: %v", maxRetires, retryErr)
+	return response.StatusCode, nil
 }
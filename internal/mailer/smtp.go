@@ -0,0 +1,129 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	gomail "gopkg.in/mail.v2"
+)
+
+// TLS modes of SMTP connections.
+const (
+	// TLSStartTLS upgrades the connection with STARTTLS and fails when the
+	// server does not support it.
+	TLSStartTLS = "starttls"
+	// TLSImplicit connects over TLS from the start, usually on port 465.
+	TLSImplicit = "tls"
+	// TLSNone sends in clear text, for local servers only.
+	TLSNone = "none"
+)
+
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username and Password authenticate to the server when set.
+	Username string
+	Password string
+	TLS      string
+	// InsecureSkipVerify accepts any certificate, for self-signed
+	// development servers.
+	InsecureSkipVerify bool
+	FromEmail          string
+}
+
+// SMTPClient sends mail through any SMTP server.
+type SMTPClient struct {
+	fromEmail string
+	dialer    *gomail.Dialer
+}
+
+func NewSMTPClient(cfg SMTPConfig) (*SMTPClient, error) {
+	if cfg.Host == "" || cfg.Port == 0 {
+		return nil, errors.New("smtp host and port are required")
+	}
+
+	dialer := gomail.NewDialer(cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+	dialer.Timeout = 10 * time.Second
+	dialer.TLSConfig = &tls.Config{ServerName: cfg.Host, InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	switch cfg.TLS {
+	case TLSStartTLS, "":
+		dialer.SSL = false
+		dialer.StartTLSPolicy = gomail.MandatoryStartTLS
+	case TLSImplicit:
+		dialer.SSL = true
+	case TLSNone:
+		dialer.SSL = false
+		dialer.StartTLSPolicy = gomail.NoStartTLS
+	default:
+		return nil, fmt.Errorf("unknown smtp tls mode %q", cfg.TLS)
+	}
+
+	return &SMTPClient{
+		fromEmail: cfg.FromEmail,
+		dialer:    dialer,
+	}, nil
+}
+
+func (c *SMTPClient) Send(templateFile, username, email string, data any, isSandbox bool) (int, error) {
+	return c.SendMessage(&Message{Template: templateFile, Username: username, Email: email, Data: data}, isSandbox)
+}
+
+func (c *SMTPClient) SendMessage(msg *Message, isSandbox bool) (int, error) {
+	rendered, err := renderMessage(msg)
+	if err != nil {
+		return -1, err
+	}
+
+	if err := c.dialer.DialAndSend(newGomailMessage(c.fromEmail, msg, rendered)); err != nil {
+		return -1, err
+	}
+
+	return 200, nil
+}
+
+// newGomailMessage builds the MIME message of msg for SMTP based clients.
+func newGomailMessage(fromEmail string, msg *Message, rendered *Rendered) *gomail.Message {
+	message := gomail.NewMessage()
+	message.SetAddressHeader("From", fromEmail, FromName)
+	message.SetAddressHeader("To", msg.Email, msg.Username)
+	message.SetHeader("Subject", rendered.Subject)
+
+	if msg.ReplyTo != "" {
+		message.SetHeader("Reply-To", msg.ReplyTo)
+	}
+
+	for k, v := range msg.Headers {
+		message.SetHeader(k, v)
+	}
+
+	// the last alternative is the preferred one
+	if rendered.Text != "" {
+		message.SetBody("text/plain", rendered.Text)
+		message.AddAlternative("text/html", rendered.HTML)
+	} else {
+		message.SetBody("text/html", rendered.HTML)
+	}
+
+	for _, a := range msg.Attachments {
+		header := map[string][]string{}
+		if a.ContentType != "" {
+			header["Content-Type"] = []string{a.ContentType}
+		}
+
+		if a.Inline {
+			if a.ContentID != "" {
+				header["Content-ID"] = []string{"<" + a.ContentID + ">"}
+			}
+			message.EmbedReader(a.Filename, bytes.NewReader(a.Content), gomail.SetHeader(header))
+			continue
+		}
+
+		message.AttachReader(a.Filename, bytes.NewReader(a.Content), gomail.SetHeader(header))
+	}
+
+	return message
+}
@@ -0,0 +1,137 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+var ErrUnknownTemplate = errors.New("unknown mail template")
+
+// Source is the text of the blocks of a mail template, as edited by
+// organisers to override an embedded template. HTML is the "content" block,
+// rendered in the base layout with the partials available.
+type Source struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// parse parses the blocks of the source.
+func (s *Source) parse() (*mailTemplate, error) {
+	text := template.New("subject")
+	if _, err := text.Parse(s.Subject); err != nil {
+		return nil, fmt.Errorf("subject: %w", err)
+	}
+
+	if strings.TrimSpace(s.Text) != "" {
+		if _, err := text.New("text").Parse(s.Text); err != nil {
+			return nil, fmt.Errorf("text: %w", err)
+		}
+	}
+
+	html, err := parseLayout()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := html.New("content").Parse(s.HTML); err != nil {
+		return nil, fmt.Errorf("html: %w", err)
+	}
+
+	return &mailTemplate{text: text, html: html}, nil
+}
+
+// Render executes the source with data.
+func (s *Source) Render(data any) (*Rendered, error) {
+	tmpl, err := s.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return tmpl.execute(data)
+}
+
+// Templates lists the embedded templates.
+func Templates() ([]string, error) {
+	names, err := fs.Glob(FS, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	for i, name := range names {
+		names[i] = strings.TrimPrefix(name, "templates/")
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// EmbeddedSource returns the source of the blocks of an embedded template.
+func EmbeddedSource(templateFile string) (*Source, error) {
+	tmpl, err := template.ParseFS(FS, "templates/"+templateFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || strings.Contains(err.Error(), "pattern matches no files") {
+			return nil, ErrUnknownTemplate
+		}
+		return nil, err
+	}
+
+	block := func(name string) string {
+		t := tmpl.Lookup(name)
+		if t == nil || t.Tree == nil {
+			return ""
+		}
+		return strings.TrimSpace(t.Tree.Root.String())
+	}
+
+	return &Source{
+		Subject: block("subject"),
+		HTML:    block("content"),
+		Text:    block("text"),
+	}, nil
+}
+
+// SourceFunc returns the override of a template for a locale, or nil when
+// the embedded template is used.
+type SourceFunc func(templateFile, locale string) (*Source, error)
+
+// WithOverrides returns a client that renders mails from the overrides found
+// by lookup before handing them to c.
+func WithOverrides(c Client, lookup SourceFunc) Client {
+	return &overridingClient{Client: c, lookup: lookup}
+}
+
+type overridingClient struct {
+	Client
+	lookup SourceFunc
+}
+
+func (c *overridingClient) Send(templateFile, username, email string, data any, isSandbox bool) (int, error) {
+	return c.SendMessage(&Message{Template: templateFile, Username: username, Email: email, Data: data}, isSandbox)
+}
+
+func (c *overridingClient) SendMessage(msg *Message, isSandbox bool) (int, error) {
+	if msg.Source == nil {
+		locale := msg.Locale
+		if locale == "" {
+			locale = DefaultLocale
+		}
+
+		src, err := c.lookup(msg.Template, locale)
+		if err != nil {
+			return -1, err
+		}
+
+		if src != nil {
+			override := *msg
+			override.Source = src
+			msg = &override
+		}
+	}
+
+	return c.Client.SendMessage(msg, isSandbox)
+}
@@ -0,0 +1,79 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedSource(t *testing.T) {
+	names, err := Templates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := map[string]any{"GuestName": "Neema", "EventName": "Harusi", "When": "tomorrow", "Summary": map[string]int{}}
+
+	for _, name := range names {
+		src, err := EmbeddedSource(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		embedded, err := Render(name, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rendered, err := src.Render(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if rendered.Subject != embedded.Subject || rendered.Text != embedded.Text {
+			t.Errorf("%s: expected the source to render as the embedded template", name)
+		}
+	}
+
+	if _, err := EmbeddedSource("missing.tmpl"); err != ErrUnknownTemplate {
+		t.Errorf("expected unknown templates to be reported, got %v", err)
+	}
+}
+
+func TestWithOverrides(t *testing.T) {
+	mock := &MockClient{}
+	client := WithOverrides(mock, func(templateFile, locale string) (*Source, error) {
+		if locale != "sw" {
+			return nil, nil
+		}
+
+		return &Source{Subject: "Karibu {{.GuestName}}", HTML: "<p>Habari</p>"}, nil
+	})
+
+	if _, err := client.SendMessage(&Message{Template: ReminderTemplate, Locale: "sw", Data: map[string]string{"GuestName": "Neema"}}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Send(ReminderTemplate, "Neema", "neema@example.com", nil, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if mock.Sent[0].Source == nil || mock.Sent[1].Source != nil {
+		t.Fatalf("expected only the swahili mail to be overridden, got %+v", mock.Sent)
+	}
+
+	rendered, err := mock.Sent[0].Source.Render(mock.Sent[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(rendered.Subject, "Karibu Neema") {
+		t.Errorf("unexpected subject %q", rendered.Subject)
+	}
+
+	t.Run("should reject invalid html templates", func(t *testing.T) {
+		src := &Source{Subject: "Hi", HTML: `<a href="{{.URL}`}
+		if _, err := src.parse(); err == nil || !strings.HasPrefix(err.Error(), "html:") {
+			t.Errorf("expected an html error, got %v", err)
+		}
+	})
+}
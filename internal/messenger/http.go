@@ -0,0 +1,134 @@
+package messenger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sikozonpc/social/internal/sms"
+)
+
+const maxCallbackSize = 64 << 10
+
+type HTTPConfig struct {
+	// URL messages are posted to.
+	URL    string
+	APIKey string
+	// From is the business number or sender ID the messages come from.
+	From string
+	// CallbackURL is where the provider reports message statuses, signed
+	// with CallbackSecret in sms.SignatureHeader. The secret is required
+	// with it.
+	CallbackURL    string
+	CallbackSecret string
+	CountryCode    string
+}
+
+// HTTPClient sends card messages through a provider accepting a multipart
+// POST of the fields "from", "to", "caption", "callback_url", "sandbox" and
+// the "image" file with a bearer API key, and replying with the message
+// {"id"}. Chat business APIs can be used this way through a small adapter
+// uploading the media first.
+type HTTPClient struct {
+	cfg    HTTPConfig
+	client *http.Client
+}
+
+func NewHTTPClient(cfg HTTPConfig) (*HTTPClient, error) {
+	if cfg.URL == "" || cfg.APIKey == "" {
+		return nil, fmt.Errorf("messenger provider url and api key are required")
+	}
+
+	if cfg.CallbackURL != "" && cfg.CallbackSecret == "" {
+		return nil, fmt.Errorf("messenger callback secret is required with a callback url")
+	}
+
+	return &HTTPClient{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (c *HTTPClient) SendCard(msg *CardMessage, isSandbox bool) (string, error) {
+	to, err := sms.Normalize(msg.To, c.cfg.CountryCode)
+	if err != nil {
+		return "", err
+	}
+
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+
+	fields := [][2]string{
+		{"from", c.cfg.From},
+		{"to", to},
+		{"caption", msg.Caption},
+		{"callback_url", c.cfg.CallbackURL},
+		{"sandbox", strconv.FormatBool(isSandbox)},
+	}
+	for _, f := range fields {
+		if err := mw.WriteField(f[0], f[1]); err != nil {
+			return "", err
+		}
+	}
+
+	fw, err := mw.CreateFormFile("image", msg.ImageName)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := fw.Write(msg.Image); err != nil {
+		return "", err
+	}
+
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.cfg.URL, body)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("messenger provider replied %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var sent struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&sent); err != nil {
+		return "", fmt.Errorf("decoding messenger provider reply: %w", err)
+	}
+
+	return sent.ID, nil
+}
+
+// ParseStatus verifies the callback signature before reading the report.
+// Every report is refused when no CallbackSecret is set up.
+func (c *HTTPClient) ParseStatus(r *http.Request) (*StatusReport, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCallbackSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sms.Verify(body, r.Header.Get(sms.SignatureHeader), c.cfg.CallbackSecret); err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	return decodeStatus(body)
+}
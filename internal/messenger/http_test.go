@@ -0,0 +1,92 @@
+package messenger
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sikozonpc/social/internal/sms"
+)
+
+func TestHTTPClient(t *testing.T) {
+	var to, caption string
+	var image []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		to = r.FormValue("to")
+		caption = r.FormValue("caption")
+
+		f, _, err := r.FormFile("image")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		image, _ = io.ReadAll(f)
+
+		w.Write([]byte(`{"id": "wamid-1"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewHTTPClient(HTTPConfig{URL: srv.URL, APIKey: "key", CountryCode: "255", CallbackSecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &CardMessage{To: "0712 345 678", Image: []byte("png"), ImageName: "card.png", Caption: "You are invited"}
+
+	id, err := client.SendCard(msg, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != "wamid-1" || to != "+255712345678" || caption != msg.Caption || string(image) != "png" {
+		t.Errorf("unexpected message %q to %q: %q %q", id, to, caption, image)
+	}
+
+	t.Run("should verify status callbacks", func(t *testing.T) {
+		body := []byte(`{"id": "wamid-1", "status": "read"}`)
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set(sms.SignatureHeader, hex.EncodeToString(sms.Sign(body, "secret")))
+
+		report, err := client.ParseStatus(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if report.MessageID != "wamid-1" || report.Status != StatusRead {
+			t.Errorf("unexpected report %+v", report)
+		}
+
+		req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set(sms.SignatureHeader, hex.EncodeToString(sms.Sign(body, "guess")))
+
+		if _, err := client.ParseStatus(req); err != ErrInvalidSignature {
+			t.Errorf("expected forged callback to be rejected, got %v", err)
+		}
+	})
+	t.Run("should refuse callbacks without a secret", func(t *testing.T) {
+		if _, err := NewHTTPClient(HTTPConfig{URL: srv.URL, APIKey: "key", CallbackURL: "http://api/v1/messages/status"}); err == nil {
+			t.Error("expected a callback url without secret to be refused")
+		}
+
+		client, err := NewHTTPClient(HTTPConfig{URL: srv.URL, APIKey: "key"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body := []byte(`{"id": "wamid-1", "status": "read"}`)
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+
+		if _, err := client.ParseStatus(req); err != ErrInvalidSignature {
+			t.Errorf("expected unsigned callback to be rejected, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+// Package messenger delivers invitation cards as rich chat messages, an
+// image with a caption, to the guests' phones.
+package messenger
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+const (
+	CardInvitationTemplate = "card_invitation.tmpl"
+)
+
+// Message statuses, in the order a message goes through them.
+const (
+	StatusSent      = "sent"
+	StatusDelivered = "delivered"
+	StatusRead      = "read"
+	StatusFailed    = "failed"
+)
+
+var (
+	ErrInvalidCallback  = errors.New("invalid message status callback")
+	ErrInvalidSignature = errors.New("invalid message status callback signature")
+)
+
+//go:embed "templates"
+var FS embed.FS
+
+// CardMessage is an invitation card image sent with a caption.
+type CardMessage struct {
+	// To is the guest's phone number, normalised to E.164 when sending.
+	To string
+	// Image is the PNG of the card, named ImageName.
+	Image     []byte
+	ImageName string
+	Caption   string
+}
+
+// Client sends card messages. SendCard returns the provider's ID of the
+// message, which status callbacks refer to.
+type Client interface {
+	SendCard(msg *CardMessage, isSandbox bool) (string, error)
+	// ParseStatus reads a message status callback made by the provider.
+	ParseStatus(r *http.Request) (*StatusReport, error)
+}
+
+// StatusReport is a status update of a sent message.
+type StatusReport struct {
+	MessageID string `json:"id"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Caption executes the "caption" of a message template.
+func Caption(templateFile string, data any) (string, error) {
+	tmpl, err := template.ParseFS(FS, "templates/"+templateFile)
+	if err != nil {
+		return "", err
+	}
+
+	caption := new(bytes.Buffer)
+	if err := tmpl.ExecuteTemplate(caption, "caption", data); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(caption.String()), nil
+}
+
+func decodeStatus(body []byte) (*StatusReport, error) {
+	var report StatusReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		return nil, ErrInvalidCallback
+	}
+
+	switch report.Status {
+	case StatusSent, StatusDelivered, StatusRead, StatusFailed:
+	default:
+		return nil, ErrInvalidCallback
+	}
+
+	if report.MessageID == "" {
+		return nil, ErrInvalidCallback
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,65 @@
+package messenger
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sikozonpc/social/internal/sms"
+)
+
+// SandboxClient logs card messages to w instead of sending them, for
+// development and tests. When dir is set the card images are saved there.
+// Its status callbacks are not signed, so they must not be accepted in
+// production.
+type SandboxClient struct {
+	mu          sync.Mutex
+	w           io.Writer
+	dir         string
+	countryCode string
+	sent        int
+}
+
+func NewSandboxClient(w io.Writer, dir, countryCode string) *SandboxClient {
+	return &SandboxClient{w: w, dir: dir, countryCode: countryCode}
+}
+
+func (c *SandboxClient) SendCard(msg *CardMessage, isSandbox bool) (string, error) {
+	to, err := sms.Normalize(msg.To, c.countryCode)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sent++
+	id := fmt.Sprintf("sandbox-%d", c.sent)
+
+	image := fmt.Sprintf("%d bytes", len(msg.Image))
+	if c.dir != "" {
+		path := filepath.Join(c.dir, id+"-"+filepath.Base(msg.ImageName))
+		if err := os.WriteFile(path, msg.Image, 0o644); err != nil {
+			return "", err
+		}
+		image = path
+	}
+
+	if _, err := fmt.Fprintf(c.w, "ID: %s\nTo: %s\nImage: %s\n%s\n\n", id, to, image, msg.Caption); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (c *SandboxClient) ParseStatus(r *http.Request) (*StatusReport, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCallbackSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeStatus(body)
+}
@@ -0,0 +1,41 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"io"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// pdfDPI is the resolution cards are printed at, used to derive the page
+// size from the rendered image.
+const pdfDPI = 300
+
+// EncodePDF writes img as a single page PDF sized to the image at 300dpi.
+func EncodePDF(w io.Writer, img image.Image) error {
+	var buf bytes.Buffer
+	if err := EncodePNG(&buf, img); err != nil {
+		return err
+	}
+
+	width := float64(img.Bounds().Dx()) / pdfDPI * 25.4
+	height := float64(img.Bounds().Dy()) / pdfDPI * 25.4
+
+	// the page keeps the image's own proportions, so landscape cards are
+	// just portrait pages that are wider than they are tall
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		OrientationStr: "P",
+		UnitStr:        "mm",
+		Size:           fpdf.SizeType{Wd: width, Ht: height},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+
+	opts := fpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader("card", opts, &buf)
+	pdf.ImageOptions("card", 0, 0, width, height, false, opts, 0, "")
+
+	return pdf.Output(w)
+}
@@ -0,0 +1,208 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // template images may be jpeg
+	"image/png"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/sikozonpc/social/internal/store"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	DefaultDateFormat = "Monday, 02 January 2006 at 15:04"
+	defaultWidth      = 1080
+	defaultHeight     = 1350
+	// MaxCanvasPixels bounds the memory of a rendered card, about 100 MB.
+	MaxCanvasPixels = 25_000_000
+)
+
+var (
+	ErrEmptyCanvas = errors.New("card template has no image and no layout size")
+	ErrCanvasSize  = fmt.Errorf("cards must be at most %d pixels on each side and %d pixels in total", MaxTemplateSize, MaxCanvasPixels)
+)
+
+// Data is the guest and event information drawn on a card.
+type Data struct {
+	GuestName string
+	GuestType string
+	EventName string
+	Date      string
+	Location  string
+	// QRCode is the content encoded in the template's QR slot, if any.
+	QRCode string
+}
+
+// fingerprintVersion changes whenever the drawing changes, so that cards
+// rendered before are no longer up to date.
+const fingerprintVersion = 1
+
+// Fingerprint identifies what a card looks like: the template image and
+// layout and the data drawn on it. The QR code is left out as it follows
+// from the card itself.
+func Fingerprint(tmpl *store.CardTemplate, data Data) string {
+	data.QRCode = ""
+
+	h := sha256.New()
+	json.NewEncoder(h).Encode(struct {
+		Version   int
+		Template  int64
+		ImagePath string
+		Layout    store.CardLayout
+		Data      Data
+	}{fingerprintVersion, tmpl.ID, tmpl.ImagePath, tmpl.Layout, data})
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func NewData(event *store.Event, guest *store.Guest) Data {
+	return Data{
+		GuestName: guest.Name,
+		GuestType: guest.Type,
+		EventName: event.Name,
+		Date:      event.Date,
+		Location:  event.Location,
+	}
+}
+
+// Renderer composes card templates with guest data. Template images are
+// read from assets by their ImagePath.
+type Renderer struct {
+	assets fs.FS
+}
+
+func New(assets fs.FS) *Renderer {
+	return &Renderer{assets: assets}
+}
+
+// Render draws the template image, every text box of the layout and the QR
+// code slot into a new image.
+func (r *Renderer) Render(tmpl *store.CardTemplate, data Data) (*image.RGBA, error) {
+	canvas, err := r.canvas(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, box := range tmpl.Layout.TextBoxes {
+		if err := drawTextBox(canvas, box, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if slot := tmpl.Layout.QRCode; slot != nil && data.QRCode != "" {
+		qr, err := qrcode.New(data.QRCode, qrcode.Medium)
+		if err != nil {
+			return nil, err
+		}
+
+		qr.DisableBorder = true
+		img := qr.Image(slot.Size)
+
+		rect := image.Rect(slot.X, slot.Y, slot.X+slot.Size, slot.Y+slot.Size)
+		draw.Draw(canvas, rect, img, img.Bounds().Min, draw.Src)
+	}
+
+	return canvas, nil
+}
+
+func (r *Renderer) canvas(tmpl *store.CardTemplate) (*image.RGBA, error) {
+	width, height := tmpl.Layout.Width, tmpl.Layout.Height
+
+	var background image.Image
+	if tmpl.ImagePath != "" {
+		f, err := r.assets.Open(tmpl.ImagePath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		background, _, err = image.Decode(f)
+		if err != nil {
+			return nil, err
+		}
+
+		if width == 0 || height == 0 {
+			width, height = background.Bounds().Dx(), background.Bounds().Dy()
+		}
+	}
+
+	if width == 0 || height == 0 {
+		if len(tmpl.Layout.TextBoxes) == 0 {
+			return nil, ErrEmptyCanvas
+		}
+		width, height = defaultWidth, defaultHeight
+	}
+
+	if err := CheckCanvasSize(width, height); err != nil {
+		return nil, err
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	if background != nil {
+		draw.Draw(canvas, canvas.Bounds(), background, background.Bounds().Min, draw.Over)
+	}
+
+	return canvas, nil
+}
+
+// CheckCanvasSize reports ErrCanvasSize when a card of width by height
+// pixels is too large to render.
+func CheckCanvasSize(width, height int) error {
+	if width > MaxTemplateSize || height > MaxTemplateSize || width*height > MaxCanvasPixels {
+		return ErrCanvasSize
+	}
+
+	return nil
+}
+
+func boxText(box store.CardTextBox, data Data) string {
+	var value string
+	switch box.Field {
+	case store.CardFieldGuestName:
+		value = data.GuestName
+	case store.CardFieldGuestType:
+		value = data.GuestType
+	case store.CardFieldEventName:
+		value = data.EventName
+	case store.CardFieldLocation:
+		value = data.Location
+	case store.CardFieldDate:
+		value = FormatDate(data.Date, box.DateFormat)
+	case store.CardFieldStatic:
+		return box.Text
+	}
+
+	return box.Text + value
+}
+
+// FormatDate formats an event date with layout, DefaultDateFormat when
+// empty. Values that are not dates are returned as they are.
+func FormatDate(value, layout string) string {
+	if layout == "" {
+		layout = DefaultDateFormat
+	}
+
+	for _, in := range []string{time.RFC3339, time.DateTime, time.DateOnly} {
+		if t, err := time.Parse(in, value); err == nil {
+			return t.Format(layout)
+		}
+	}
+
+	return value
+}
+
+func EncodePNG(w io.Writer, img image.Image) error {
+	return png.Encode(w, img)
+}
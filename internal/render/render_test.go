@@ -0,0 +1,120 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sikozonpc/social/internal/store"
+)
+
+func TestRender(t *testing.T) {
+	var background bytes.Buffer
+	bg := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	bg.Set(299, 199, color.RGBA{R: 255, A: 255})
+	if err := png.Encode(&background, bg); err != nil {
+		t.Fatal(err)
+	}
+
+	r := New(fstest.MapFS{
+		"templates/wedding.png": {Data: background.Bytes()},
+	})
+
+	tmpl := &store.CardTemplate{
+		ImagePath: "templates/wedding.png",
+		Layout: store.CardLayout{
+			TextBoxes: []store.CardTextBox{
+				{Field: store.CardFieldGuestName, X: 10, Y: 10, Width: 280, Size: 24, Color: "#000"},
+			},
+			QRCode: &store.CardQRSlot{X: 200, Y: 100, Size: 64},
+		},
+	}
+
+	img, err := r.Render(tmpl, Data{GuestName: "Neema Mushi", QRCode: "card:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := img.Bounds().Size(); got != (image.Point{300, 200}) {
+		t.Fatalf("expected canvas to match the template image, got %v", got)
+	}
+
+	if !hasInk(img, image.Rect(10, 10, 290, 40)) {
+		t.Error("expected the guest name to be drawn")
+	}
+
+	if !hasInk(img, image.Rect(200, 100, 264, 164)) {
+		t.Error("expected the QR code to be drawn")
+	}
+
+	if _, _, _, a := img.At(299, 199).RGBA(); a == 0 {
+		t.Error("expected the template image to be kept")
+	}
+}
+
+func TestRenderWithoutCanvas(t *testing.T) {
+	_, err := New(fstest.MapFS{}).Render(&store.CardTemplate{}, Data{})
+	if err != ErrEmptyCanvas {
+		t.Fatalf("expected ErrEmptyCanvas, got %v", err)
+	}
+}
+
+func TestRenderOversizedCanvas(t *testing.T) {
+	for _, layout := range []store.CardLayout{
+		{Width: 100000, Height: 100000},
+		{Width: 6000, Height: 6000},
+	} {
+		if _, err := New(fstest.MapFS{}).Render(&store.CardTemplate{Layout: layout}, Data{}); err != ErrCanvasSize {
+			t.Errorf("expected ErrCanvasSize for %dx%d, got %v", layout.Width, layout.Height, err)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	tmpl := &store.CardTemplate{ID: 1, ImagePath: "templates/ab/ab.png"}
+	data := Data{GuestName: "Amani", EventName: "Harusi"}
+
+	fingerprint := Fingerprint(tmpl, data)
+
+	data.QRCode = "signed"
+	if Fingerprint(tmpl, data) != fingerprint {
+		t.Error("expected the QR code to be left out")
+	}
+
+	data.GuestName = "Neema"
+	if Fingerprint(tmpl, data) == fingerprint {
+		t.Error("expected a renamed guest to change the fingerprint")
+	}
+
+	tmpl.Layout.TextBoxes = []store.CardTextBox{{Field: store.CardFieldGuestName}}
+	if Fingerprint(tmpl, Data{GuestName: "Amani", EventName: "Harusi"}) == fingerprint {
+		t.Error("expected a layout change to change the fingerprint")
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	got := FormatDate("2024-12-21T15:00:00Z", "02 Jan 2006")
+	if got != "21 Dec 2024" {
+		t.Errorf("unexpected date %q", got)
+	}
+
+	if got := FormatDate("next saturday", ""); got != "next saturday" {
+		t.Errorf("expected unparsable dates to be kept, got %q", got)
+	}
+}
+
+func hasInk(img image.Image, rect image.Rectangle) bool {
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			if r < 0x8000 && g < 0x8000 && b < 0x8000 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	MinTemplateSize = 200
+	MaxTemplateSize = 6000
+	ThumbnailWidth  = 320
+)
+
+var (
+	ErrUnsupportedImage = errors.New("template images must be PNG or JPEG")
+	ErrImageSize        = fmt.Errorf("template images must be between %d and %d pixels on each side", MinTemplateSize, MaxTemplateSize)
+)
+
+// TemplateImage is an uploaded card template image.
+type TemplateImage struct {
+	ContentType string
+	Width       int
+	Height      int
+	Image       image.Image
+}
+
+// DecodeTemplateImage sniffs the content type of data rather than trusting
+// the one given with the upload, and checks the dimensions from the image
+// header before decoding the pixels.
+func DecodeTemplateImage(data []byte) (*TemplateImage, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/png", "image/jpeg":
+	default:
+		return nil, ErrUnsupportedImage
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+
+	if cfg.Width < MinTemplateSize || cfg.Height < MinTemplateSize ||
+		cfg.Width > MaxTemplateSize || cfg.Height > MaxTemplateSize {
+		return nil, ErrImageSize
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+
+	return &TemplateImage{
+		ContentType: contentType,
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+		Image:       img,
+	}, nil
+}
+
+// Thumbnail scales img down to width, keeping its aspect ratio. Images
+// narrower than width are returned as they are.
+func Thumbnail(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() <= width {
+		return img
+	}
+
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+
+	thumb := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(thumb, thumb.Bounds(), img, bounds, draw.Src, nil)
+
+	return thumb
+}
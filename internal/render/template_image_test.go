@@ -0,0 +1,39 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestDecodeTemplateImage(t *testing.T) {
+	encode := func(width, height int) []byte {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	img, err := DecodeTemplateImage(encode(1080, 1350))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if img.ContentType != "image/png" || img.Width != 1080 || img.Height != 1350 {
+		t.Errorf("unexpected image %s %dx%d", img.ContentType, img.Width, img.Height)
+	}
+
+	if got := Thumbnail(img.Image, ThumbnailWidth).Bounds().Size(); got != (image.Point{320, 400}) {
+		t.Errorf("expected the thumbnail to keep the aspect ratio, got %v", got)
+	}
+
+	if _, err := DecodeTemplateImage(encode(100, 1350)); err != ErrImageSize {
+		t.Errorf("expected small images to be rejected, got %v", err)
+	}
+
+	if _, err := DecodeTemplateImage([]byte("<svg xmlns='http://www.w3.org/2000/svg'></svg>")); err != ErrUnsupportedImage {
+		t.Errorf("expected svg to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sikozonpc/social/internal/store"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomedium"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const defaultFontSize = 32
+
+var fontData = map[string][]byte{
+	"regular":     goregular.TTF,
+	"bold":        gobold.TTF,
+	"italic":      goitalic.TTF,
+	"bold_italic": gobolditalic.TTF,
+	"medium":      gomedium.TTF,
+	"mono":        gomono.TTF,
+}
+
+var (
+	fontsMu sync.Mutex
+	fonts   = make(map[string]*opentype.Font)
+)
+
+func loadFont(name string) (*opentype.Font, error) {
+	if name == "" {
+		name = "regular"
+	}
+
+	fontsMu.Lock()
+	defer fontsMu.Unlock()
+
+	if f, ok := fonts[name]; ok {
+		return f, nil
+	}
+
+	data, ok := fontData[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown font %q", name)
+	}
+
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	fonts[name] = f
+	return f, nil
+}
+
+func drawTextBox(dst *image.RGBA, box store.CardTextBox, data Data) error {
+	text := strings.TrimSpace(boxText(box, data))
+	if text == "" {
+		return nil
+	}
+
+	f, err := loadFont(box.Font)
+	if err != nil {
+		return err
+	}
+
+	size := box.Size
+	if size == 0 {
+		size = defaultFontSize
+	}
+
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return err
+	}
+	defer face.Close()
+
+	col, err := parseColor(box.Color)
+	if err != nil {
+		return err
+	}
+
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(col),
+		Face: face,
+	}
+
+	metrics := face.Metrics()
+	baseline := fixed.I(box.Y) + metrics.Ascent
+
+	for _, line := range wrap(d, text, box.Width) {
+		x := fixed.I(box.X)
+		if box.Width > 0 {
+			free := fixed.I(box.Width) - d.MeasureString(line)
+			switch box.Align {
+			case "center":
+				x += free / 2
+			case "right":
+				x += free
+			}
+		}
+
+		d.Dot = fixed.Point26_6{X: x, Y: baseline}
+		d.DrawString(line)
+
+		baseline += metrics.Height
+	}
+
+	return nil
+}
+
+// wrap breaks text into lines no wider than width pixels. A width of zero
+// keeps the text on a single line.
+func wrap(d *font.Drawer, text string, width int) []string {
+	if width <= 0 {
+		return []string{text}
+	}
+
+	max := fixed.I(width)
+
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+
+		if line != "" && d.MeasureString(candidate) > max {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+
+		line = candidate
+	}
+
+	return append(lines, line)
+}
+
+// parseColor reads a #rgb or #rrggbb hex color, defaulting to black.
+func parseColor(s string) (color.Color, error) {
+	s = strings.TrimPrefix(s, "#")
+
+	switch len(s) {
+	case 0:
+		return color.Black, nil
+	case 3:
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	case 6:
+	default:
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
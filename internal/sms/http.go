@@ -0,0 +1,154 @@
+package sms
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of a callback body.
+	SignatureHeader = "X-Signature"
+	maxCallbackSize = 64 << 10
+)
+
+type HTTPConfig struct {
+	// URL messages are posted to.
+	URL    string
+	APIKey string
+	// From is the sender ID or number shown to recipients.
+	From string
+	// CallbackURL is where the provider reports delivery statuses, signed
+	// with CallbackSecret, which is required with it.
+	CallbackURL    string
+	CallbackSecret string
+	CountryCode    string
+}
+
+// HTTPClient sends messages through a provider accepting a JSON POST of
+// {"from", "to", "text", "callback_url", "sandbox"} with a bearer API key
+// and replying with the message {"id"}. Most SMS gateways can be used this
+// way directly or through a small adapter.
+type HTTPClient struct {
+	cfg    HTTPConfig
+	client *http.Client
+}
+
+func NewHTTPClient(cfg HTTPConfig) (*HTTPClient, error) {
+	if cfg.URL == "" || cfg.APIKey == "" {
+		return nil, fmt.Errorf("sms provider url and api key are required")
+	}
+
+	if cfg.CallbackURL != "" && cfg.CallbackSecret == "" {
+		return nil, fmt.Errorf("sms callback secret is required with a callback url")
+	}
+
+	return &HTTPClient{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+type httpMessage struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Text        string `json:"text"`
+	CallbackURL string `json:"callback_url,omitempty"`
+	Sandbox     bool   `json:"sandbox"`
+}
+
+func (c *HTTPClient) Send(templateFile, phoneNumber string, data any, isSandbox bool) (string, error) {
+	to, err := Normalize(phoneNumber, c.cfg.CountryCode)
+	if err != nil {
+		return "", err
+	}
+
+	text, err := Render(templateFile, data)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(httpMessage{
+		From:        c.cfg.From,
+		To:          to,
+		Text:        text,
+		CallbackURL: c.cfg.CallbackURL,
+		Sandbox:     isSandbox,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.cfg.URL, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("sms provider replied %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var sent struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&sent); err != nil {
+		return "", fmt.Errorf("decoding sms provider reply: %w", err)
+	}
+
+	return sent.ID, nil
+}
+
+// ParseStatus verifies the callback signature before reading the report.
+// Every report is refused when no CallbackSecret is set up.
+func (c *HTTPClient) ParseStatus(r *http.Request) (*StatusReport, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCallbackSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Verify(body, r.Header.Get(SignatureHeader), c.cfg.CallbackSecret); err != nil {
+		return nil, err
+	}
+
+	return decodeStatus(body)
+}
+
+// Verify checks the hex encoded signature of a callback body made with
+// secret. An empty secret verifies nothing.
+func Verify(body []byte, signature, secret string) error {
+	if secret == "" {
+		return ErrInvalidSignature
+	}
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, Sign(body, secret)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Sign returns the HMAC-SHA256 of a callback body, as sent hex encoded in
+// SignatureHeader.
+func Sign(body []byte, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return mac.Sum(nil)
+}
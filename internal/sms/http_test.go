@@ -0,0 +1,82 @@
+package sms
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPClient(t *testing.T) {
+	var got httpMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"id": "msg-1"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewHTTPClient(HTTPConfig{URL: srv.URL, APIKey: "key", From: "Events", CountryCode: "255", CallbackSecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := map[string]string{"GuestName": "Neema", "EventName": "Harusi", "When": "tomorrow", "RSVPURL": "http://rsvp"}
+
+	id, err := client.Send(EventReminderTemplate, "0712 345 678", data, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != "msg-1" || got.To != "+255712345678" || !got.Sandbox || got.From != "Events" {
+		t.Errorf("unexpected message %q %+v", id, got)
+	}
+
+	t.Run("should verify status callbacks", func(t *testing.T) {
+		body := []byte(`{"id": "msg-1", "status": "delivered"}`)
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set(SignatureHeader, hex.EncodeToString(Sign(body, "secret")))
+
+		report, err := client.ParseStatus(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if report.MessageID != "msg-1" || report.Status != StatusDelivered {
+			t.Errorf("unexpected report %+v", report)
+		}
+
+		req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set(SignatureHeader, hex.EncodeToString(Sign(body, "guess")))
+
+		if _, err := client.ParseStatus(req); err != ErrInvalidSignature {
+			t.Errorf("expected forged callback to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("should refuse callbacks without a secret", func(t *testing.T) {
+		if _, err := NewHTTPClient(HTTPConfig{URL: srv.URL, APIKey: "key", CallbackURL: "http://api/v1/sms/status"}); err == nil {
+			t.Error("expected a callback url without secret to be refused")
+		}
+
+		client, err := NewHTTPClient(HTTPConfig{URL: srv.URL, APIKey: "key"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body := []byte(`{"id": "msg-1", "status": "delivered"}`)
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set(SignatureHeader, hex.EncodeToString(Sign(body, "")))
+
+		if _, err := client.ParseStatus(req); err != ErrInvalidSignature {
+			t.Errorf("expected unsigned callback to be rejected, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package sms
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidPhoneNumber = errors.New("invalid phone number")
+
+// Normalize converts a phone number as typed by people into E.164, e.g.
+// "0712 345 678" into "+255712345678" with countryCode "255". Numbers
+// starting with "+" or "00" already carry their country code.
+func Normalize(number, countryCode string) (string, error) {
+	var digits strings.Builder
+	for i, r := range strings.TrimSpace(number) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", ErrInvalidPhoneNumber
+		}
+	}
+
+	n := digits.String()
+	local := ""
+	switch {
+	case strings.HasPrefix(n, "+"):
+		n = n[1:]
+	case strings.HasPrefix(n, "00"):
+		n = n[2:]
+	case strings.HasPrefix(n, "0"):
+		local = n[1:]
+	case countryCode != "" && strings.HasPrefix(n, countryCode) && len(n) > 10:
+		// already international, just missing the +
+	default:
+		local = n
+	}
+
+	if local != "" || n == "" {
+		// subscriber numbers are never shorter than 7 digits
+		if len(local) < 7 {
+			return "", ErrInvalidPhoneNumber
+		}
+		n = countryCode + local
+	}
+
+	// E.164 numbers have at most 15 digits and never start with a zero
+	if len(n) < 8 || len(n) > 15 || n[0] == '0' {
+		return "", ErrInvalidPhoneNumber
+	}
+
+	return "+" + n, nil
+}
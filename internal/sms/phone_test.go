@@ -0,0 +1,35 @@
+package sms
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"0712 345 678", "+255712345678"},
+		{"+255 712-345-678", "+255712345678"},
+		{"00255712345678", "+255712345678"},
+		{"255712345678", "+255712345678"},
+		{"712345678", "+255712345678"},
+		{"+1 (415) 555-0100", "+14155550100"},
+	}
+
+	for _, tt := range tests {
+		got, err := Normalize(tt.in, "255")
+		if err != nil {
+			t.Errorf("Normalize(%q): %v", tt.in, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "12345", "0712 345 678 ext 2", "+2557123456789012", "07+12345678"} {
+		if got, err := Normalize(in, "255"); err == nil {
+			t.Errorf("Normalize(%q) = %q, expected an error", in, got)
+		}
+	}
+}
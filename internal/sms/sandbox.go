@@ -0,0 +1,66 @@
+package sms
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// SandboxClient writes text messages to w instead of sending them, for
+// development and tests. Its status callbacks are not signed, so they must
+// not be accepted in production.
+type SandboxClient struct {
+	mu          sync.Mutex
+	w           io.Writer
+	countryCode string
+	sent        int
+}
+
+func NewSandboxClient(w io.Writer, countryCode string) *SandboxClient {
+	return &SandboxClient{w: w, countryCode: countryCode}
+}
+
+// NewFileSandboxClient appends the messages to the file at path.
+func NewFileSandboxClient(path, countryCode string) (*SandboxClient, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSandboxClient(f, countryCode), nil
+}
+
+func (c *SandboxClient) Send(templateFile, phoneNumber string, data any, isSandbox bool) (string, error) {
+	to, err := Normalize(phoneNumber, c.countryCode)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := Render(templateFile, data)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sent++
+	id := fmt.Sprintf("sandbox-%d", c.sent)
+
+	if _, err := fmt.Fprintf(c.w, "ID: %s\nTo: %s\n%s\n\n", id, to, body); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (c *SandboxClient) ParseStatus(r *http.Request) (*StatusReport, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCallbackSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeStatus(body)
+}
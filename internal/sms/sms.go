@@ -0,0 +1,79 @@
+package sms
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+const (
+	EventReminderTemplate = "event_reminder.tmpl"
+)
+
+// Delivery statuses reported by providers.
+const (
+	StatusSent      = "sent"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+var (
+	ErrInvalidCallback  = errors.New("invalid delivery status callback")
+	ErrInvalidSignature = errors.New("invalid delivery status callback signature")
+)
+
+//go:embed "templates"
+var FS embed.FS
+
+// Client sends text messages. Send returns the provider's ID of the message,
+// which delivery status callbacks refer to.
+type Client interface {
+	Send(templateFile, phoneNumber string, data any, isSandbox bool) (string, error)
+	// ParseStatus reads a delivery status callback made by the provider.
+	ParseStatus(r *http.Request) (*StatusReport, error)
+}
+
+// StatusReport is a delivery status update of a sent message.
+type StatusReport struct {
+	MessageID string `json:"id"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Render executes the "body" of a text message template.
+func Render(templateFile string, data any) (string, error) {
+	tmpl, err := template.ParseFS(FS, "templates/"+templateFile)
+	if err != nil {
+		return "", err
+	}
+
+	body := new(bytes.Buffer)
+	if err := tmpl.ExecuteTemplate(body, "body", data); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(body.String()), nil
+}
+
+func decodeStatus(body []byte) (*StatusReport, error) {
+	var report StatusReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		return nil, ErrInvalidCallback
+	}
+
+	switch report.Status {
+	case StatusSent, StatusDelivered, StatusFailed:
+	default:
+		return nil, ErrInvalidCallback
+	}
+
+	if report.MessageID == "" {
+		return nil, ErrInvalidCallback
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// AssetStore tells which stored images are still referred to by cards and
+// card templates. Images are stored once per content, so one can be shared.
+type AssetStore struct {
+	db *sql.DB
+}
+
+// InUse returns the keys of keys that a card or template refers to.
+func (s *AssetStore) InUse(ctx context.Context, keys []string) (map[string]bool, error) {
+	query := `
+		SELECT key
+		FROM unnest($1::text[]) AS key
+		WHERE EXISTS (SELECT 1 FROM cards WHERE image_path = key)
+			OR EXISTS (SELECT 1 FROM card_templates WHERE image_path = key)
+			OR EXISTS (SELECT 1 FROM card_templates WHERE thumbnail_path = key)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(keys))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	used := map[string]bool{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+
+		used[key] = true
+	}
+
+	return used, rows.Err()
+}
@@ -0,0 +1,449 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	CampaignScheduled = "scheduled"
+	CampaignSending   = "sending"
+	CampaignSent      = "sent"
+
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+
+	DeliveryPending   = "pending"
+	DeliverySent      = "sent"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// campaignSendAt computes when a campaign c of event e goes out, so that it
+// follows the event when its date changes. at_time is a time of day in the
+// event's timezone.
+const campaignSendAt = `
+	CASE WHEN c.at_time IS NULL
+		THEN e.date - make_interval(days => c.days_before)
+		ELSE (date_trunc('day', e.date AT TIME ZONE e.timezone) - make_interval(days => c.days_before) + c.at_time)
+			AT TIME ZONE e.timezone
+	END`
+
+// Campaign sends a reminder to the guests of an event matching Statuses and
+// Types (all guests when empty), DaysBefore the event date.
+type Campaign struct {
+	ID         int64    `json:"id"`
+	EventID    int64    `json:"event_id"`
+	Name       string   `json:"name"`
+	Channel    string   `json:"channel"`
+	DaysBefore int      `json:"days_before"`
+	AtTime     string   `json:"at_time,omitempty"`
+	Statuses   []string `json:"statuses"`
+	Types      []string `json:"types"`
+	Status     string   `json:"status"`
+	SendAt     string   `json:"send_at"`
+	Pending    int      `json:"pending"`
+	// Sent includes the deliveries confirmed as delivered.
+	Sent      int    `json:"sent"`
+	Failed    int    `json:"failed"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// CampaignDelivery tracks the reminder of a campaign to one guest.
+type CampaignDelivery struct {
+	ID         int64  `json:"id"`
+	CampaignID int64  `json:"campaign_id"`
+	GuestID    int64  `json:"guest_id"`
+	GuestName  string `json:"guest_name"`
+	Recipient  string `json:"recipient"`
+	Status     string `json:"status"`
+	Attempts   int    `json:"attempts"`
+	LastError  string `json:"last_error,omitempty"`
+	SentAt     string `json:"sent_at,omitempty"`
+	// set on claimed deliveries, for rendering the reminder
+	Channel   string `json:"-"`
+	RSVPToken string `json:"-"`
+	Event     Event  `json:"-"`
+}
+
+type CampaignStore struct {
+	db *sql.DB
+}
+
+func (s *CampaignStore) Create(ctx context.Context, campaign *Campaign) error {
+	query := `
+		INSERT INTO campaigns (event_id, name, channel, days_before, at_time, statuses, types)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::time, $6, $7)
+		RETURNING id, status, created_at, updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		campaign.EventID,
+		campaign.Name,
+		campaign.Channel,
+		campaign.DaysBefore,
+		campaign.AtTime,
+		pq.Array(campaign.Statuses),
+		pq.Array(campaign.Types),
+	).Scan(
+		&campaign.ID,
+		&campaign.Status,
+		&campaign.CreatedAt,
+		&campaign.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetByEvent returns the campaigns of an event with their delivery counts.
+func (s *CampaignStore) GetByEvent(ctx context.Context, eventID int64) ([]Campaign, error) {
+	return s.query(ctx, `c.event_id = $1`, `send_at ASC`, eventID)
+}
+
+func (s *CampaignStore) GetByID(ctx context.Context, id int64) (*Campaign, error) {
+	campaigns, err := s.query(ctx, `c.id = $1`, `c.id`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(campaigns) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &campaigns[0], nil
+}
+
+// GetDue returns the scheduled campaigns whose send time is before now.
+// Campaigns of events that are over, or that would go out after the event,
+// are left out; they are due again if the event moves later.
+func (s *CampaignStore) GetDue(ctx context.Context, now time.Time) ([]Campaign, error) {
+	where := `c.status = 'scheduled' AND ` + campaignSendAt + ` <= $1
+		AND ` + campaignSendAt + ` < e.date AND e.date > $1`
+
+	return s.query(ctx, where, `send_at ASC`, now)
+}
+
+func (s *CampaignStore) query(ctx context.Context, where, orderBy string, args ...any) ([]Campaign, error) {
+	query := `
+		SELECT
+			c.id, c.event_id, c.name, c.channel, c.days_before, COALESCE(to_char(c.at_time, 'HH24:MI'), ''),
+			c.statuses, c.types, c.status, ` + campaignSendAt + ` AS send_at,
+			COUNT(d.id) FILTER (WHERE d.status = 'pending'),
+			COUNT(d.id) FILTER (WHERE d.status IN ('sent', 'delivered')),
+			COUNT(d.id) FILTER (WHERE d.status = 'failed'),
+			c.created_at, c.updated_at
+		FROM campaigns c
+		JOIN events e ON e.id = c.event_id
+		LEFT JOIN campaign_deliveries d ON d.campaign_id = c.id
+		WHERE ` + where + `
+		GROUP BY c.id, e.date, e.timezone
+		ORDER BY ` + orderBy
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	campaigns := []Campaign{}
+	for rows.Next() {
+		var c Campaign
+		err := rows.Scan(
+			&c.ID,
+			&c.EventID,
+			&c.Name,
+			&c.Channel,
+			&c.DaysBefore,
+			&c.AtTime,
+			pq.Array(&c.Statuses),
+			pq.Array(&c.Types),
+			&c.Status,
+			&c.SendAt,
+			&c.Pending,
+			&c.Sent,
+			&c.Failed,
+			&c.CreatedAt,
+			&c.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		campaigns = append(campaigns, c)
+	}
+
+	return campaigns, rows.Err()
+}
+
+func (s *CampaignStore) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM campaigns WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Enqueue starts sending a scheduled campaign by creating a pending
+// delivery for every guest of its audience with an address on the
+// campaign's channel. It returns the number of deliveries created, or
+// ErrNotFound when the campaign was already started.
+func (s *CampaignStore) Enqueue(ctx context.Context, campaignID int64) (int, error) {
+	var queued int64
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `UPDATE campaigns SET status = 'sending' WHERE id = $1 AND status = 'scheduled'`
+
+		res, err := tx.ExecContext(ctx, query, campaignID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		query = `
+			INSERT INTO campaign_deliveries (campaign_id, guest_id, recipient)
+			SELECT c.id, g.id, CASE c.channel WHEN 'sms' THEN g.phone_number ELSE g.email::text END
+			FROM campaigns c
+			JOIN guests g ON g.event_id = c.event_id
+			WHERE c.id = $1
+				AND (cardinality(c.statuses) = 0 OR g.status = ANY(c.statuses))
+				AND (cardinality(c.types) = 0 OR g.type = ANY(c.types))
+				AND CASE c.channel WHEN 'sms' THEN g.phone_number ELSE g.email::text END <> ''
+			ON CONFLICT (campaign_id, guest_id) DO NOTHING
+		`
+
+		res, err = tx.ExecContext(ctx, query, campaignID)
+		if err != nil {
+			return err
+		}
+
+		queued, err = res.RowsAffected()
+		return err
+	})
+
+	return int(queued), err
+}
+
+// GetDeliveries returns the per-guest delivery state of a campaign.
+func (s *CampaignStore) GetDeliveries(ctx context.Context, campaignID int64) ([]CampaignDelivery, error) {
+	query := `
+		SELECT d.id, d.campaign_id, d.guest_id, g.name, d.recipient, d.status, d.attempts, d.last_error,
+			COALESCE(to_char(d.sent_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'), '')
+		FROM campaign_deliveries d
+		JOIN guests g ON g.id = d.guest_id
+		WHERE d.campaign_id = $1
+		ORDER BY g.name ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	deliveries := []CampaignDelivery{}
+	for rows.Next() {
+		var d CampaignDelivery
+		err := rows.Scan(
+			&d.ID,
+			&d.CampaignID,
+			&d.GuestID,
+			&d.GuestName,
+			&d.Recipient,
+			&d.Status,
+			&d.Attempts,
+			&d.LastError,
+			&d.SentAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// ClaimDeliveries takes up to limit pending deliveries that are due and
+// counts an attempt for each. Claimed deliveries are not handed out again
+// for lease, so a worker that dies mid-way only delays them.
+func (s *CampaignStore) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]CampaignDelivery, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE campaign_deliveries
+			SET attempts = attempts + 1, next_attempt_at = NOW() + $2 * interval '1 second'
+			WHERE id IN (
+				SELECT id FROM campaign_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at ASC
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, campaign_id, guest_id, recipient, status, attempts
+		)
+		SELECT cl.id, cl.campaign_id, cl.guest_id, g.name, g.rsvp_token, cl.recipient, cl.status, cl.attempts,
+			c.channel, e.id, e.name, e.date, e.timezone, e.location
+		FROM claimed cl
+		JOIN guests g ON g.id = cl.guest_id
+		JOIN campaigns c ON c.id = cl.campaign_id
+		JOIN events e ON e.id = c.event_id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var deliveries []CampaignDelivery
+	for rows.Next() {
+		var d CampaignDelivery
+		err := rows.Scan(
+			&d.ID,
+			&d.CampaignID,
+			&d.GuestID,
+			&d.GuestName,
+			&d.RSVPToken,
+			&d.Recipient,
+			&d.Status,
+			&d.Attempts,
+			&d.Channel,
+			&d.Event.ID,
+			&d.Event.Name,
+			&d.Event.Date,
+			&d.Event.Timezone,
+			&d.Event.Location,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// MarkSent records a delivery accepted by the sender, with the provider's
+// message ID when it reports delivery statuses.
+func (s *CampaignStore) MarkSent(ctx context.Context, deliveryID int64, providerID string) error {
+	query := `
+		UPDATE campaign_deliveries SET status = 'sent', sent_at = NOW(), last_error = '', provider_id = $2
+		WHERE id = $1
+	`
+
+	return s.exec(ctx, query, deliveryID, providerID)
+}
+
+// UpdateStatus applies a provider's delivery report to the delivery of the
+// message providerID. Reports never move a delivery back to sent.
+func (s *CampaignStore) UpdateStatus(ctx context.Context, providerID, status, reason string) error {
+	query := `
+		UPDATE campaign_deliveries
+		SET status = $2, last_error = $3,
+			delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() ELSE delivered_at END
+		WHERE provider_id = $1 AND status IN ('sent', 'delivered') AND $2 <> 'sent'
+	`
+
+	return s.exec(ctx, query, providerID, status, reason)
+}
+
+// MarkFailed records a failed attempt. The delivery is retried at retryAt,
+// or given up on when retryAt is zero.
+func (s *CampaignStore) MarkFailed(ctx context.Context, deliveryID int64, reason string, retryAt time.Time) error {
+	if retryAt.IsZero() {
+		query := `UPDATE campaign_deliveries SET status = 'failed', last_error = $2 WHERE id = $1`
+		return s.exec(ctx, query, deliveryID, reason)
+	}
+
+	query := `UPDATE campaign_deliveries SET last_error = $2, next_attempt_at = $3 WHERE id = $1`
+	return s.exec(ctx, query, deliveryID, reason, retryAt)
+}
+
+// Complete marks the campaigns being sent without pending deliveries left
+// as sent.
+func (s *CampaignStore) Complete(ctx context.Context) error {
+	query := `
+		UPDATE campaigns c SET status = 'sent'
+		WHERE c.status = 'sending' AND NOT EXISTS (
+			SELECT 1 FROM campaign_deliveries d WHERE d.campaign_id = c.id AND d.status = 'pending'
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+func (s *CampaignStore) exec(ctx context.Context, query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,406 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	CardJobRunning   = "running"
+	CardJobCompleted = "completed"
+
+	CardItemPending  = "pending"
+	CardItemRendered = "rendered"
+	// CardItemSkipped is a guest whose card was already up to date.
+	CardItemSkipped = "skipped"
+	CardItemFailed  = "failed"
+
+	// MaxCardAttempts is how many times a guest's card is tried, counting
+	// the claims of workers that died before finishing it.
+	MaxCardAttempts = 3
+)
+
+// CardJob generates the cards of the guests of an event matching GuestIDs,
+// Search, Statuses and Types (all guests when empty), with a card item per
+// guest.
+type CardJob struct {
+	ID             int64    `json:"id"`
+	EventID        int64    `json:"event_id"`
+	UserID         int64    `json:"user_id"`
+	CardTemplateID int64    `json:"card_template_id"`
+	GuestIDs       []int64  `json:"guest_ids"`
+	Search         string   `json:"search"`
+	Statuses       []string `json:"statuses"`
+	Types          []string `json:"types"`
+	// Force renders the cards that are up to date again.
+	Force       bool    `json:"force"`
+	Status      string  `json:"status"`
+	Total       int     `json:"total"`
+	Pending     int     `json:"pending"`
+	Rendered    int     `json:"rendered"`
+	Skipped     int     `json:"skipped"`
+	Failed      int     `json:"failed"`
+	Progress    float64 `json:"progress"`
+	CompletedAt string  `json:"completed_at,omitempty"`
+	CreatedAt   string  `json:"created_at"`
+	UpdatedAt   string  `json:"updated_at"`
+}
+
+// CardJobItem tracks the card of one guest of a job.
+type CardJobItem struct {
+	ID        int64  `json:"id"`
+	JobID     int64  `json:"job_id"`
+	GuestID   int64  `json:"guest_id"`
+	GuestName string `json:"guest_name"`
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	CardID    int64  `json:"card_id,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+	// set on claimed items, for rendering the card
+	EventID        int64 `json:"-"`
+	UserID         int64 `json:"-"`
+	CardTemplateID int64 `json:"-"`
+	Force          bool  `json:"-"`
+}
+
+type CardJobStore struct {
+	db *sql.DB
+}
+
+// Create starts a job with a pending item per guest it covers. It returns
+// ErrConflict when a job of the event is still running. Jobs without guests
+// are completed right away.
+func (s *CardJobStore) Create(ctx context.Context, job *CardJob) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			INSERT INTO card_jobs (event_id, user_id, card_template_id, guest_ids, search, statuses, types, force)
+			VALUES ($1, NULLIF($2, 0), NULLIF($3, 0), $4, $5, $6, $7, $8)
+			RETURNING id, status, created_at, updated_at
+		`
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			job.EventID,
+			job.UserID,
+			job.CardTemplateID,
+			pq.Array(job.GuestIDs),
+			job.Search,
+			pq.Array(job.Statuses),
+			pq.Array(job.Types),
+			job.Force,
+		).Scan(
+			&job.ID,
+			&job.Status,
+			&job.CreatedAt,
+			&job.UpdatedAt,
+		)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrConflict
+			}
+			return err
+		}
+
+		query = `
+			INSERT INTO card_job_items (job_id, guest_id)
+			SELECT j.id, g.id
+			FROM card_jobs j
+			JOIN guests g ON g.event_id = j.event_id
+			WHERE j.id = $1
+				AND (cardinality(j.guest_ids) = 0 OR g.id = ANY(j.guest_ids))
+				AND (j.search = '' OR g.name ILIKE '%' || j.search || '%' OR g.phone_number ILIKE '%' || j.search || '%')
+				AND (cardinality(j.statuses) = 0 OR g.status = ANY(j.statuses))
+				AND (cardinality(j.types) = 0 OR g.type = ANY(j.types))
+		`
+
+		res, err := tx.ExecContext(ctx, query, job.ID)
+		if err != nil {
+			return err
+		}
+
+		total, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		job.Total, job.Pending = int(total), int(total)
+
+		if total > 0 {
+			return nil
+		}
+
+		query = `
+			UPDATE card_jobs SET status = 'completed', completed_at = NOW() WHERE id = $1
+			RETURNING status, to_char(completed_at, 'YYYY-MM-DD"T"HH24:MI:SSOF')
+		`
+
+		job.Progress = 100
+		return tx.QueryRowContext(ctx, query, job.ID).Scan(&job.Status, &job.CompletedAt)
+	})
+}
+
+func (s *CardJobStore) GetByID(ctx context.Context, id int64) (*CardJob, error) {
+	jobs, err := s.query(ctx, `j.id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(jobs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &jobs[0], nil
+}
+
+// GetByEvent returns the jobs of an event, the latest first.
+func (s *CardJobStore) GetByEvent(ctx context.Context, eventID int64) ([]CardJob, error) {
+	return s.query(ctx, `j.event_id = $1`, eventID)
+}
+
+func (s *CardJobStore) query(ctx context.Context, where string, args ...any) ([]CardJob, error) {
+	query := `
+		SELECT
+			j.id, j.event_id, COALESCE(j.user_id, 0), COALESCE(j.card_template_id, 0),
+			j.guest_ids, j.search, j.statuses, j.types, j.force, j.status,
+			COUNT(i.id),
+			COUNT(i.id) FILTER (WHERE i.status = 'pending'),
+			COUNT(i.id) FILTER (WHERE i.status = 'rendered'),
+			COUNT(i.id) FILTER (WHERE i.status = 'skipped'),
+			COUNT(i.id) FILTER (WHERE i.status = 'failed'),
+			COALESCE(to_char(j.completed_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'), ''),
+			j.created_at, j.updated_at
+		FROM card_jobs j
+		LEFT JOIN card_job_items i ON i.job_id = j.id
+		WHERE ` + where + `
+		GROUP BY j.id
+		ORDER BY j.created_at DESC, j.id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	jobs := []CardJob{}
+	for rows.Next() {
+		var j CardJob
+		err := rows.Scan(
+			&j.ID,
+			&j.EventID,
+			&j.UserID,
+			&j.CardTemplateID,
+			pq.Array(&j.GuestIDs),
+			&j.Search,
+			pq.Array(&j.Statuses),
+			pq.Array(&j.Types),
+			&j.Force,
+			&j.Status,
+			&j.Total,
+			&j.Pending,
+			&j.Rendered,
+			&j.Skipped,
+			&j.Failed,
+			&j.CompletedAt,
+			&j.CreatedAt,
+			&j.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		j.Progress = 100
+		if j.Total > 0 {
+			j.Progress = float64(j.Total-j.Pending) * 100 / float64(j.Total)
+		}
+
+		jobs = append(jobs, j)
+	}
+
+	return jobs, rows.Err()
+}
+
+// GetItems returns the items of a job, only those in status when set.
+func (s *CardJobStore) GetItems(ctx context.Context, jobID int64, status string) ([]CardJobItem, error) {
+	query := `
+		SELECT i.id, i.job_id, i.guest_id, g.name, i.status, i.attempts, COALESCE(i.card_id, 0), i.last_error
+		FROM card_job_items i
+		JOIN guests g ON g.id = i.guest_id
+		WHERE i.job_id = $1 AND ($2 = '' OR i.status = $2)
+		ORDER BY g.name ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, jobID, status)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := []CardJobItem{}
+	for rows.Next() {
+		var i CardJobItem
+		err := rows.Scan(
+			&i.ID,
+			&i.JobID,
+			&i.GuestID,
+			&i.GuestName,
+			&i.Status,
+			&i.Attempts,
+			&i.CardID,
+			&i.LastError,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, i)
+	}
+
+	return items, rows.Err()
+}
+
+// ClaimItems takes up to limit pending items of running jobs that are due
+// and counts an attempt for each. Claimed items are not handed out again for
+// lease, so the items of a worker that dies are picked up after it.
+func (s *CardJobStore) ClaimItems(ctx context.Context, limit int, lease time.Duration) ([]CardJobItem, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE card_job_items
+			SET attempts = attempts + 1, next_attempt_at = NOW() + $2 * interval '1 second'
+			WHERE id IN (
+				SELECT i.id FROM card_job_items i
+				JOIN card_jobs j ON j.id = i.job_id
+				WHERE i.status = 'pending' AND i.next_attempt_at <= NOW()
+					AND i.attempts < $3 AND j.status = 'running'
+				ORDER BY i.next_attempt_at ASC, i.id ASC
+				LIMIT $1
+				FOR UPDATE OF i SKIP LOCKED
+			)
+			RETURNING id, job_id, guest_id, status, attempts
+		)
+		SELECT cl.id, cl.job_id, cl.guest_id, g.name, cl.status, cl.attempts,
+			j.event_id, COALESCE(j.user_id, 0), COALESCE(j.card_template_id, 0), j.force
+		FROM claimed cl
+		JOIN guests g ON g.id = cl.guest_id
+		JOIN card_jobs j ON j.id = cl.job_id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit, lease.Seconds(), MaxCardAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var items []CardJobItem
+	for rows.Next() {
+		var i CardJobItem
+		err := rows.Scan(
+			&i.ID,
+			&i.JobID,
+			&i.GuestID,
+			&i.GuestName,
+			&i.Status,
+			&i.Attempts,
+			&i.EventID,
+			&i.UserID,
+			&i.CardTemplateID,
+			&i.Force,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, i)
+	}
+
+	return items, rows.Err()
+}
+
+// MarkDone records the card of an item, rendered or skipped.
+func (s *CardJobStore) MarkDone(ctx context.Context, itemID int64, status string, cardID int64) error {
+	query := `
+		UPDATE card_job_items SET status = $2, card_id = NULLIF($3, 0), last_error = ''
+		WHERE id = $1
+	`
+
+	return s.exec(ctx, query, itemID, status, cardID)
+}
+
+// MarkFailed records a failed attempt. The item is retried at retryAt, or
+// given up on when retryAt is zero.
+func (s *CardJobStore) MarkFailed(ctx context.Context, itemID int64, reason string, retryAt time.Time) error {
+	if retryAt.IsZero() {
+		query := `UPDATE card_job_items SET status = 'failed', last_error = $2 WHERE id = $1`
+		return s.exec(ctx, query, itemID, reason)
+	}
+
+	query := `UPDATE card_job_items SET last_error = $2, next_attempt_at = $3 WHERE id = $1`
+	return s.exec(ctx, query, itemID, reason, retryAt)
+}
+
+// Complete gives up on the items claimed MaxCardAttempts times without
+// finishing and marks the jobs without pending items left as completed.
+func (s *CardJobStore) Complete(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		UPDATE card_job_items
+		SET status = 'failed', last_error = CASE WHEN last_error = '' THEN 'abandoned by the workers' ELSE last_error END
+		WHERE status = 'pending' AND attempts >= $1 AND next_attempt_at <= NOW()
+	`
+
+	if _, err := s.db.ExecContext(ctx, query, MaxCardAttempts); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE card_jobs j SET status = 'completed', completed_at = NOW()
+		WHERE j.status = 'running' AND NOT EXISTS (
+			SELECT 1 FROM card_job_items i WHERE i.job_id = j.id AND i.status = 'pending'
+		)
+	`
+
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+func (s *CardJobStore) exec(ctx context.Context, query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+const (
+	MessageSent      = "sent"
+	MessageDelivered = "delivered"
+	MessageRead      = "read"
+	MessageFailed    = "failed"
+)
+
+// CardMessage tracks an invitation card sent to a guest's phone as a chat
+// message.
+type CardMessage struct {
+	ID          int64  `json:"id"`
+	CardID      int64  `json:"card_id"`
+	GuestID     int64  `json:"guest_id"`
+	GuestName   string `json:"guest_name"`
+	EventID     int64  `json:"event_id"`
+	Recipient   string `json:"recipient"`
+	ProviderID  string `json:"provider_id"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	SentAt      string `json:"sent_at"`
+	DeliveredAt string `json:"delivered_at,omitempty"`
+	ReadAt      string `json:"read_at,omitempty"`
+}
+
+type CardMessageStore struct {
+	db *sql.DB
+}
+
+func (s *CardMessageStore) Create(ctx context.Context, msg *CardMessage) error {
+	query := `
+		INSERT INTO card_messages (card_id, guest_id, event_id, recipient, provider_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, sent_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		msg.CardID,
+		msg.GuestID,
+		msg.EventID,
+		msg.Recipient,
+		msg.ProviderID,
+	).Scan(
+		&msg.ID,
+		&msg.Status,
+		&msg.SentAt,
+	)
+}
+
+// GetByGuest returns the messages sent to a guest, latest first.
+func (s *CardMessageStore) GetByGuest(ctx context.Context, guestID int64) ([]CardMessage, error) {
+	return s.query(ctx, "m.guest_id = $1", guestID)
+}
+
+// GetByEvent returns the messages sent to an event's guests, latest first.
+func (s *CardMessageStore) GetByEvent(ctx context.Context, eventID int64) ([]CardMessage, error) {
+	return s.query(ctx, "m.event_id = $1", eventID)
+}
+
+func (s *CardMessageStore) query(ctx context.Context, where string, args ...any) ([]CardMessage, error) {
+	query := `
+		SELECT m.id, m.card_id, m.guest_id, g.name, m.event_id, m.recipient, m.provider_id, m.status, m.error,
+			to_char(m.sent_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'),
+			COALESCE(to_char(m.delivered_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'), ''),
+			COALESCE(to_char(m.read_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'), '')
+		FROM card_messages m
+		JOIN guests g ON g.id = m.guest_id
+		WHERE ` + where + `
+		ORDER BY m.sent_at DESC, m.id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	messages := []CardMessage{}
+	for rows.Next() {
+		var m CardMessage
+		err := rows.Scan(
+			&m.ID,
+			&m.CardID,
+			&m.GuestID,
+			&m.GuestName,
+			&m.EventID,
+			&m.Recipient,
+			&m.ProviderID,
+			&m.Status,
+			&m.Error,
+			&m.SentAt,
+			&m.DeliveredAt,
+			&m.ReadAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, m)
+	}
+
+	return messages, rows.Err()
+}
+
+// UpdateStatus applies a provider's status report to the message
+// providerID. Reports arriving out of order never move a message back, a
+// read message stays read. Unknown messages are reported as not found.
+func (s *CardMessageStore) UpdateStatus(ctx context.Context, providerID, status, reason string) error {
+	query := `
+		WITH m AS (
+			SELECT id,
+				array_position(ARRAY['sent', 'delivered', 'read'], status) AS current,
+				array_position(ARRAY['sent', 'delivered', 'read'], $2::varchar) AS next
+			FROM card_messages
+			WHERE provider_id = $1
+		)
+		UPDATE card_messages cm SET
+			status = CASE WHEN m.current IS NULL OR m.next > m.current OR $2 = 'failed' AND m.current < 3
+				THEN $2 ELSE cm.status END,
+			error = CASE WHEN $2 = 'failed' THEN $3 ELSE cm.error END,
+			delivered_at = CASE WHEN m.next >= 2 THEN COALESCE(cm.delivered_at, NOW()) ELSE cm.delivered_at END,
+			read_at = CASE WHEN m.next = 3 THEN COALESCE(cm.read_at, NOW()) ELSE cm.read_at END
+		FROM m
+		WHERE cm.id = m.id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, providerID, status, reason)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -4,84 +4,148 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 )
 
+var ErrCardRevoked = errors.New("card has been revoked")
+
 type Card struct {
 	ID             int64  `json:"id"`
 	ImagePath      string `json:"image_path"`
 	EventID        int64  `json:"event_id"`
 	GuestID        int64  `json:"guest_id"`
 	CardTemplateID int64  `json:"card_template_id"`
-	CreatedAt      string `json:"created_at"`
-	UpdatedAt      string `json:"updated_at"`
-	Guest          *Guest `json:"guest"`
-	Event          Event  `json:"event"`
+	// Fingerprint tells whether the card is up to date with its guest, event
+	// and template, see render.Fingerprint.
+	Fingerprint string `json:"-"`
+	// RevokedAt is set once the card is revoked, its code is then refused
+	// at check-in.
+	RevokedAt     string `json:"revoked_at,omitempty"`
+	RevokedReason string `json:"revoked_reason,omitempty"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+	Code          string `json:"code,omitempty"` // signed QR token, derived from the IDs and never stored
+	Guest         *Guest `json:"guest"`
+	Event         Event  `json:"event"`
 }
 type CardStore struct {
 	db *sql.DB
 }
 
+const cardListColumns = `
+	c.id, c.image_path, c.event_id, c.guest_id, COALESCE(c.card_template_id, 0), c.created_at, c.updated_at,
+	g.id, g.name, g.email, g.phone_number, g.status, g.type`
+
+// GetCards lists the current card of the guests of an event, searching
+// guest names and phone numbers.
 func (s *CardStore) GetCards(ctx context.Context, eventId int64, fq PaginatedFeedQuery) ([]Card, error) {
 	query := `
-		SELECT
-			c.id, c.image_path,
-			gs.name, gs.phone_number
+		SELECT ` + cardListColumns + `
 		FROM cards c
-		LEFT JOIN guests gs ON gs.id = c.guest_id
-		WHERE
-			(gs.name ILIKE '%' || $4 || '%' OR gs.phone_number ILIKE '%' || $4 || '%')
-		GROUP BY c.id, gs.name
-		ORDER BY c.created_at ` + fq.Sort + `
+		JOIN guests g ON g.card_id = c.id
+		WHERE c.event_id = $1 AND
+			(g.name ILIKE '%' || $4 || '%' OR g.phone_number ILIKE '%' || $4 || '%')
+		ORDER BY c.created_at ` + fq.Sort + `, c.id ` + fq.Sort + `
 		LIMIT $2 OFFSET $3
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, fq.Limit, fq.Offset, fq.Search)
+	rows, err := s.db.QueryContext(ctx, query, eventId, fq.Limit, fq.Offset, fq.Search)
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 
-	var cards []Card
+	cards := []Card{}
 	for rows.Next() {
-		var card Card
-		err := rows.Scan(
-			&card.ID,
-			&card.ImagePath,
-			&card.Guest.Name,
-			&card.Guest.PhoneNumber,
-		)
-		if err != nil {
+		card := Card{Guest: &Guest{}}
+		if err := scanCardRow(rows, &card); err != nil {
 			return nil, err
 		}
 
 		cards = append(cards, card)
 	}
 
-	return cards, nil
+	return cards, rows.Err()
+}
+
+// ForEach calls fn with the current card of every guest of an event
+// matching search, by guest name.
+func (s *CardStore) ForEach(ctx context.Context, eventID int64, search string, fn func(*Card) error) error {
+	query := `
+		SELECT ` + cardListColumns + `
+		FROM cards c
+		JOIN guests g ON g.card_id = c.id
+		WHERE c.event_id = $1 AND
+			(g.name ILIKE '%' || $2 || '%' OR g.phone_number ILIKE '%' || $2 || '%')
+		ORDER BY g.name ASC, c.id ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, eventID, search)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		card := Card{Guest: &Guest{}}
+		if err := scanCardRow(rows, &card); err != nil {
+			return err
+		}
+
+		if err := fn(&card); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func scanCardRow(row interface{ Scan(...any) error }, card *Card) error {
+	return row.Scan(
+		&card.ID,
+		&card.ImagePath,
+		&card.EventID,
+		&card.GuestID,
+		&card.CardTemplateID,
+		&card.CreatedAt,
+		&card.UpdatedAt,
+		&card.Guest.ID,
+		&card.Guest.Name,
+		&card.Guest.Email,
+		&card.Guest.PhoneNumber,
+		&card.Guest.Status,
+		&card.Guest.Type,
+	)
 }
 
 func (s *CardStore) GetByID(ctx context.Context, id int64) (*Card, error) {
 	query := `
-		SELECT id, image_path, guest_id, card_template_id, created_at,  updated_at,
+		SELECT c.id, c.image_path, c.event_id, c.guest_id, COALESCE(c.card_template_id, 0), c.fingerprint,
+			COALESCE(to_char(c.revoked_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'), ''), c.revoked_reason, c.created_at,  c.updated_at,
 			gs.name, gs.phone_number
 		FROM cards c
-		LEFT JOIN guests gs ON gs.id = c.guest_id
-		WHERE id = $1
+		JOIN guests gs ON gs.id = c.guest_id
+		WHERE c.id = $1
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	var card Card
+	card := Card{Guest: &Guest{}}
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&card.ID,
 		&card.ImagePath,
+		&card.EventID,
 		&card.GuestID,
 		&card.CardTemplateID,
+		&card.Fingerprint,
+		&card.RevokedAt,
+		&card.RevokedReason,
 		&card.CreatedAt,
 		&card.UpdatedAt,
 		&card.Guest.Name,
@@ -99,32 +163,108 @@ func (s *CardStore) GetByID(ctx context.Context, id int64) (*Card, error) {
 	return &card, nil
 }
 
+// Create inserts the card. It only becomes the guest's current card with
+// SetCurrent, once it has been rendered.
 func (s *CardStore) Create(ctx context.Context, card *Card) error {
 	query := `
-		INSERT INTO cards (event_id, guest_id, card_template_id, image_path)
-		VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at
+		INSERT INTO cards (event_id, guest_id, card_template_id, image_path, fingerprint)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5) RETURNING id, created_at, updated_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
+	return s.db.QueryRowContext(
 		ctx,
 		query,
 		card.EventID,
 		card.GuestID,
 		card.CardTemplateID,
 		card.ImagePath,
+		card.Fingerprint,
 	).Scan(
 		&card.ID,
 		&card.CreatedAt,
 		&card.UpdatedAt,
 	)
-	if err != nil {
+}
+
+// SetCurrent makes card the current card of its guest and revokes the card
+// it replaces on behalf of userID, so a guest only ever has one valid code.
+func (s *CardStore) SetCurrent(ctx context.Context, card *Card, userID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var previous sql.NullInt64
+		err := tx.QueryRowContext(ctx, `SELECT card_id FROM guests WHERE id = $1 FOR UPDATE`, card.GuestID).Scan(&previous)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE guests SET card_id = $1 WHERE id = $2`, card.ID, card.GuestID); err != nil {
+			return err
+		}
+
+		if !previous.Valid || previous.Int64 == card.ID {
+			return nil
+		}
+
+		query := `
+			UPDATE cards
+			SET revoked_at = NOW(), revoked_reason = $2, revoked_by = NULLIF($3, 0)
+			WHERE id = $1 AND revoked_at IS NULL
+		`
+
+		_, err = tx.ExecContext(ctx, query, previous.Int64, fmt.Sprintf("replaced by card %d", card.ID), userID)
 		return err
-	}
+	})
+}
 
-	return nil
+// Revoke marks the card revoked by userID and unlinks it from its guest, who
+// has no current card until one is issued again. ErrCardRevoked is returned
+// when it was revoked already.
+func (s *CardStore) Revoke(ctx context.Context, cardID int64, reason string, userID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			UPDATE cards
+			SET revoked_at = NOW(), revoked_reason = $2, revoked_by = NULLIF($3, 0)
+			WHERE id = $1 AND revoked_at IS NULL
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		res, err := tx.ExecContext(ctx, query, cardID, reason, userID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			var exists bool
+			if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM cards WHERE id = $1)`, cardID).Scan(&exists); err != nil {
+				return err
+			}
+
+			if exists {
+				return ErrCardRevoked
+			}
+			return ErrNotFound
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE guests SET card_id = NULL WHERE card_id = $1`, cardID)
+		return err
+	})
 }
 
 func (s *CardStore) Delete(ctx context.Context, cardID int64) error {
@@ -153,21 +293,23 @@ func (s *CardStore) Delete(ctx context.Context, cardID int64) error {
 func (s *CardStore) Update(ctx context.Context, tx *sql.Tx, card *Card) error {
 	query := `
 		UPDATE cards
-		SET event_id = $1, guest_id = $2, card_template_id = $3, image_path = $4
-		WHERE id = $4
+		SET event_id = $1, guest_id = $2, card_template_id = NULLIF($3, 0), image_path = $4, fingerprint = $5
+		WHERE id = $6
 		RETURNING id, created_at, updated_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
+	err := conn(s.db, tx).QueryRowContext(
 		ctx,
 		query,
 		card.EventID,
 		card.GuestID,
 		card.CardTemplateID,
 		card.ImagePath,
+		card.Fingerprint,
+		card.ID,
 	).Scan(
 		&card.ID,
 		&card.CreatedAt,
@@ -3,59 +3,145 @@ package store
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
+	"fmt"
+)
+
+// Fields a CardTextBox can be bound to.
+const (
+	CardFieldGuestName = "guest_name"
+	CardFieldEventName = "event_name"
+	CardFieldDate      = "date"
+	CardFieldLocation  = "location"
+	CardFieldGuestType = "guest_type"
+	CardFieldStatic    = "static"
+)
+
+// Visibilities of a card template. Personal templates are only seen by
+// their owner, shared ones by everyone; system templates have no owner and
+// are managed by admins.
+const (
+	TemplatePersonal = "personal"
+	TemplateShared   = "shared"
+	TemplateSystem   = "system"
 )
 
 type CardTemplate struct {
-	ID        int64  `json:"id"`
-	ImagePath string `json:"image_path"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// UserID is the owner, zero for system templates.
+	UserID        int64      `json:"user_id"`
+	Visibility    string     `json:"visibility"`
+	ImagePath     string     `json:"image_path"`
+	ContentType   string     `json:"content_type"`
+	Width         int        `json:"width"`
+	Height        int        `json:"height"`
+	ThumbnailPath string     `json:"thumbnail_path"`
+	Layout        CardLayout `json:"layout"`
+	CreatedAt     string     `json:"created_at"`
+	UpdatedAt     string     `json:"updated_at"`
+}
+
+// CardLayout describes where guest and event details are drawn on top of the
+// template image. Coordinates are in pixels from the top-left corner.
+type CardLayout struct {
+	// Width and Height override the size of the template image, up to the
+	// largest image accepted for templates.
+	Width     int           `json:"width,omitempty" validate:"gte=0,max=6000"`
+	Height    int           `json:"height,omitempty" validate:"gte=0,max=6000"`
+	TextBoxes []CardTextBox `json:"text_boxes" validate:"max=50,dive"`
+	QRCode    *CardQRSlot   `json:"qr_code,omitempty"`
+}
+
+type CardTextBox struct {
+	Field string `json:"field" validate:"required,oneof=guest_name event_name date location guest_type static"`
+	// Text is drawn as is for static boxes; for other fields it is used as a
+	// prefix, e.g. "Venue: ".
+	Text       string  `json:"text,omitempty"`
+	X          int     `json:"x" validate:"gte=0"`
+	Y          int     `json:"y" validate:"gte=0"`
+	Width      int     `json:"width" validate:"gte=0"`
+	Font       string  `json:"font,omitempty" validate:"omitempty,oneof=regular bold italic bold_italic medium mono"`
+	Size       float64 `json:"size,omitempty" validate:"omitempty,gt=0,lte=400"`
+	Color      string  `json:"color,omitempty" validate:"omitempty,hexcolor"`
+	Align      string  `json:"align,omitempty" validate:"omitempty,oneof=left center right"`
+	DateFormat string  `json:"date_format,omitempty"`
+}
+
+type CardQRSlot struct {
+	X    int `json:"x" validate:"gte=0"`
+	Y    int `json:"y" validate:"gte=0"`
+	Size int `json:"size" validate:"gte=32,max=6000"`
+}
+
+func (l CardLayout) Value() (driver.Value, error) {
+	return json.Marshal(l)
+}
+
+func (l *CardLayout) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	case nil:
+		*l = CardLayout{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into CardLayout", src)
+	}
 }
 
 type CardTemplateStore struct {
 	db *sql.DB
 }
 
-func (s *CardTemplateStore) GetCards(ctx context.Context, eventId int64, fq PaginatedFeedQuery) ([]CardTemplate, error) {
+const cardTemplateColumns = `
+	id, name, COALESCE(user_id, 0), visibility, image_path, content_type,
+	width, height, thumbnail_path, layout, created_at, updated_at`
+
+// GetAvailable lists the templates userID can use: their own, the shared
+// and the system ones. visibility narrows the list down to one of them, with
+// personal meaning the user's own, and fq.Search matches names.
+func (s *CardTemplateStore) GetAvailable(ctx context.Context, userID int64, visibility string, fq PaginatedFeedQuery) ([]CardTemplate, error) {
 	query := `
-		SELECT
-			ct.id, ct.image_path
-		FROM card_templates ct
-		ORDER BY ct.created_at ` + fq.Sort + `
-		LIMIT $1 OFFSET $2
+		SELECT ` + cardTemplateColumns + `
+		FROM card_templates
+		WHERE (user_id = $1 OR visibility IN ('shared', 'system'))
+			AND ($2 = '' OR visibility = $2)
+			AND ($3 = '' OR name ILIKE '%' || $3 || '%')
+		ORDER BY created_at ` + fq.Sort + `, id ` + fq.Sort + `
+		LIMIT $4 OFFSET $5
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, fq.Limit, fq.Offset, fq.Search)
+	rows, err := s.db.QueryContext(ctx, query, userID, visibility, fq.Search, fq.Limit, fq.Offset)
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 
-	var cards []CardTemplate
+	templates := []CardTemplate{}
 	for rows.Next() {
-		var card CardTemplate
-		err := rows.Scan(
-			&card.ID,
-			&card.ImagePath,
-		)
-		if err != nil {
+		var tmpl CardTemplate
+		if err := scanCardTemplate(rows, &tmpl); err != nil {
 			return nil, err
 		}
 
-		cards = append(cards, card)
+		templates = append(templates, tmpl)
 	}
 
-	return cards, nil
+	return templates, rows.Err()
 }
 
 func (s *CardTemplateStore) GetByID(ctx context.Context, id int64) (*CardTemplate, error) {
 	query := `
-		SELECT id, image_path, created_at,  updated_at
+		SELECT ` + cardTemplateColumns + `
 		FROM card_templates
 		WHERE id = $1
 	`
@@ -64,13 +150,7 @@ func (s *CardTemplateStore) GetByID(ctx context.Context, id int64) (*CardTemplat
 	defer cancel()
 
 	var card CardTemplate
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&card.ID,
-		&card.ImagePath,
-		&card.CreatedAt,
-		&card.UpdatedAt,
-	)
-	if err != nil {
+	if err := scanCardTemplate(s.db.QueryRowContext(ctx, query, id), &card); err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			return nil, ErrNotFound
@@ -84,17 +164,26 @@ func (s *CardTemplateStore) GetByID(ctx context.Context, id int64) (*CardTemplat
 
 func (s *CardTemplateStore) Create(ctx context.Context, tx *sql.Tx, card *CardTemplate) error {
 	query := `
-		INSERT INTO card_templates (image_path)
-		VALUES ($1, $2, $3) RETURNING id, created_at, updated_at
+		INSERT INTO card_templates (name, user_id, visibility, image_path, content_type, width, height, thumbnail_path, layout)
+		VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
+	err := conn(s.db, tx).QueryRowContext(
 		ctx,
 		query,
+		card.Name,
+		card.UserID,
+		card.Visibility,
 		card.ImagePath,
+		card.ContentType,
+		card.Width,
+		card.Height,
+		card.ThumbnailPath,
+		card.Layout,
 	).Scan(
 		&card.ID,
 		&card.CreatedAt,
@@ -133,18 +222,28 @@ func (s *CardTemplateStore) Delete(ctx context.Context, cardID int64) error {
 func (s *CardTemplateStore) Update(ctx context.Context, tx *sql.Tx, card *CardTemplate) error {
 	query := `
 		UPDATE card_templates
-		SET image_path = $1
-		WHERE id = $2
+		SET name = $1, user_id = NULLIF($2, 0), visibility = $3, image_path = $4, content_type = $5,
+			width = $6, height = $7, thumbnail_path = $8, layout = $9
+		WHERE id = $10
 		RETURNING id, created_at, updated_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
+	err := conn(s.db, tx).QueryRowContext(
 		ctx,
 		query,
+		card.Name,
+		card.UserID,
+		card.Visibility,
 		card.ImagePath,
+		card.ContentType,
+		card.Width,
+		card.Height,
+		card.ThumbnailPath,
+		card.Layout,
+		card.ID,
 	).Scan(
 		&card.ID,
 		&card.CreatedAt,
@@ -161,3 +260,20 @@ func (s *CardTemplateStore) Update(ctx context.Context, tx *sql.Tx, card *CardTe
 
 	return nil
 }
+
+func scanCardTemplate(row interface{ Scan(...any) error }, card *CardTemplate) error {
+	return row.Scan(
+		&card.ID,
+		&card.Name,
+		&card.UserID,
+		&card.Visibility,
+		&card.ImagePath,
+		&card.ContentType,
+		&card.Width,
+		&card.Height,
+		&card.ThumbnailPath,
+		&card.Layout,
+		&card.CreatedAt,
+		&card.UpdatedAt,
+	)
+}
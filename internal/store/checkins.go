@@ -0,0 +1,265 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var ErrAlreadyCheckedIn = errors.New("guest is already checked in")
+
+type CheckIn struct {
+	ID          int64  `json:"id"`
+	EventID     int64  `json:"event_id"`
+	GuestID     int64  `json:"guest_id"`
+	CardID      int64  `json:"card_id"`
+	UserID      int64  `json:"user_id"`
+	Username    string `json:"username"`
+	Gate        string `json:"gate"`
+	DeviceID    string `json:"device_id"`
+	CheckedInAt string `json:"checked_in_at"`
+}
+
+type CheckInStore struct {
+	db *sql.DB
+}
+
+// Create records the check-in and increments the event's scanned count in
+// the same transaction. ErrAlreadyCheckedIn is returned when the guest has
+// an active check-in.
+func (s *CheckInStore) Create(ctx context.Context, checkIn *CheckIn) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO checkins (event_id, guest_id, card_id, user_id, gate)
+			VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, 0), $5)
+			ON CONFLICT (guest_id) WHERE undone_at IS NULL DO NOTHING
+			RETURNING id, checked_in_at
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			checkIn.EventID,
+			checkIn.GuestID,
+			checkIn.CardID,
+			checkIn.UserID,
+			checkIn.Gate,
+		).Scan(
+			&checkIn.ID,
+			&checkIn.CheckedInAt,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrAlreadyCheckedIn
+			default:
+				return err
+			}
+		}
+
+		return s.addScanned(ctx, tx, checkIn.EventID, 1)
+	})
+}
+
+// GetByGuest returns the active check-in of a guest.
+func (s *CheckInStore) GetByGuest(ctx context.Context, guestID int64) (*CheckIn, error) {
+	query := `
+		SELECT ch.id, ch.event_id, ch.guest_id, COALESCE(ch.card_id, 0), COALESCE(ch.user_id, 0),
+			COALESCE(u.username, ''), ch.gate, ch.device_id, ch.checked_in_at
+		FROM checkins ch
+		LEFT JOIN users u ON u.id = ch.user_id
+		WHERE ch.guest_id = $1 AND ch.undone_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var checkIn CheckIn
+	err := s.db.QueryRowContext(ctx, query, guestID).Scan(
+		&checkIn.ID,
+		&checkIn.EventID,
+		&checkIn.GuestID,
+		&checkIn.CardID,
+		&checkIn.UserID,
+		&checkIn.Username,
+		&checkIn.Gate,
+		&checkIn.DeviceID,
+		&checkIn.CheckedInAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &checkIn, nil
+}
+
+// GetByEvent returns the active check-ins of an event.
+func (s *CheckInStore) GetByEvent(ctx context.Context, eventID int64) ([]CheckIn, error) {
+	query := `
+		SELECT ch.id, ch.event_id, ch.guest_id, COALESCE(ch.card_id, 0), COALESCE(ch.user_id, 0),
+			COALESCE(u.username, ''), ch.gate, ch.device_id, ch.checked_in_at
+		FROM checkins ch
+		LEFT JOIN users u ON u.id = ch.user_id
+		WHERE ch.event_id = $1 AND ch.undone_at IS NULL
+		ORDER BY ch.checked_in_at ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	checkIns := []CheckIn{}
+	for rows.Next() {
+		var ch CheckIn
+		err := rows.Scan(
+			&ch.ID,
+			&ch.EventID,
+			&ch.GuestID,
+			&ch.CardID,
+			&ch.UserID,
+			&ch.Username,
+			&ch.Gate,
+			&ch.DeviceID,
+			&ch.CheckedInAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		checkIns = append(checkIns, ch)
+	}
+
+	return checkIns, rows.Err()
+}
+
+// Merge records a check-in made offline at checkIn.CheckedInAt. When the
+// guest is already checked in the earliest scan wins: a later stored
+// check-in is replaced by this one, otherwise checkIn is set to the stored
+// check-in and ErrAlreadyCheckedIn is returned. Merge reports whether the
+// guest was checked in for the first time, only which counts towards the
+// event's scanned count.
+func (s *CheckInStore) Merge(ctx context.Context, checkIn *CheckIn) (bool, error) {
+	var inserted bool
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO checkins (event_id, guest_id, card_id, user_id, gate, device_id, checked_in_at)
+			VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, 0), $5, $6, $7)
+			ON CONFLICT (guest_id) WHERE undone_at IS NULL DO UPDATE
+			SET card_id = EXCLUDED.card_id, user_id = EXCLUDED.user_id, gate = EXCLUDED.gate,
+				device_id = EXCLUDED.device_id, checked_in_at = EXCLUDED.checked_in_at, synced_at = NOW()
+			WHERE checkins.checked_in_at > EXCLUDED.checked_in_at
+			RETURNING id, checked_in_at, (xmax = 0) AS inserted
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			checkIn.EventID,
+			checkIn.GuestID,
+			checkIn.CardID,
+			checkIn.UserID,
+			checkIn.Gate,
+			checkIn.DeviceID,
+			checkIn.CheckedInAt,
+		).Scan(
+			&checkIn.ID,
+			&checkIn.CheckedInAt,
+			&inserted,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				if err := s.getActive(ctx, tx, checkIn); err != nil {
+					return err
+				}
+				return ErrAlreadyCheckedIn
+			default:
+				return err
+			}
+		}
+
+		if !inserted {
+			return nil
+		}
+
+		return s.addScanned(ctx, tx, checkIn.EventID, 1)
+	})
+
+	return inserted, err
+}
+
+// getActive loads the active check-in of checkIn.GuestID into checkIn.
+func (s *CheckInStore) getActive(ctx context.Context, tx *sql.Tx, checkIn *CheckIn) error {
+	query := `
+		SELECT ch.id, COALESCE(ch.card_id, 0), COALESCE(ch.user_id, 0), COALESCE(u.username, ''),
+			ch.gate, ch.device_id, ch.checked_in_at
+		FROM checkins ch
+		LEFT JOIN users u ON u.id = ch.user_id
+		WHERE ch.guest_id = $1 AND ch.undone_at IS NULL
+	`
+
+	return tx.QueryRowContext(ctx, query, checkIn.GuestID).Scan(
+		&checkIn.ID,
+		&checkIn.CardID,
+		&checkIn.UserID,
+		&checkIn.Username,
+		&checkIn.Gate,
+		&checkIn.DeviceID,
+		&checkIn.CheckedInAt,
+	)
+}
+
+// Undo marks the guest's active check-in as undone by userID and decrements
+// the event's scanned count.
+func (s *CheckInStore) Undo(ctx context.Context, eventID, guestID, userID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			UPDATE checkins
+			SET undone_at = NOW(), undone_by = NULLIF($3, 0)
+			WHERE event_id = $1 AND guest_id = $2 AND undone_at IS NULL
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		res, err := tx.ExecContext(ctx, query, eventID, guestID, userID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return s.addScanned(ctx, tx, eventID, -1)
+	})
+}
+
+func (s *CheckInStore) addScanned(ctx context.Context, tx *sql.Tx, eventID int64, delta int) error {
+	query := `UPDATE events SET scanned_count = scanned_count + $1 WHERE id = $2`
+
+	_, err := tx.ExecContext(ctx, query, delta, eventID)
+	return err
+}
@@ -10,9 +10,11 @@ type Event struct {
 	ID             int64  `json:"id"`
 	Name           string `json:"name"`
 	Date           string `json:"date"`
+	Timezone       string `json:"timezone"` // IANA name, UTC when empty
 	Location       string `json:"location"`
 	ScannedCount   int64  `json:"scanned_count"`
 	CardTemplateID string `json:"card_template_id"`
+	RSVPDeadline   string `json:"rsvp_deadline,omitempty"`
 	UserID         int64  `json:"user_id"`
 	CreatedAt      string `json:"created_at"`
 	UpdatedAt      string `json:"updated_at"`
@@ -27,15 +29,14 @@ type EventStore struct {
 func (s *EventStore) GetAllEvents(ctx context.Context, fq PaginatedFeedQuery) ([]Event, error) {
 	query := `
 		SELECT
-			e.id, e.name, e.date, e.location, e.scanned_count,
-			ct.image_path,
+			e.id, e.name, e.date, e.location, e.scanned_count, e.created_at,
+			COALESCE(ct.image_path, ''),
 			u.username
 		FROM events e
 		LEFT JOIN card_templates ct ON ct.id = e.card_template_id
 		LEFT JOIN users u ON u.id = e.user_id
 		WHERE
 			(e.name ILIKE '%' || $3 || '%' OR e.location ILIKE '%' || $3 || '%' OR u.username ILIKE '%' || $3 || '%')
-		GROUP BY e.id, u.username
 		ORDER BY e.created_at ` + fq.Sort + `
 		LIMIT $1 OFFSET $2
 	`
@@ -55,6 +56,7 @@ func (s *EventStore) GetAllEvents(ctx context.Context, fq PaginatedFeedQuery) ([
 		err := rows.Scan(
 			&e.ID,
 			&e.Name,
+			&e.Date,
 			&e.Location,
 			&e.ScannedCount,
 			&e.CreatedAt,
@@ -73,7 +75,8 @@ func (s *EventStore) GetAllEvents(ctx context.Context, fq PaginatedFeedQuery) ([
 
 func (s *EventStore) GetByID(ctx context.Context, id int64) (*Event, error) {
 	query := `
-		SELECT id, name, date, location, scanned_count, card_template_id, user_id, created_at,  updated_at
+		SELECT id, name, date, timezone, location, scanned_count, COALESCE(card_template_id::text, ''), rsvp_deadline,
+			user_id, created_at, updated_at
 		FROM events
 		WHERE id = $1
 	`
@@ -82,13 +85,16 @@ func (s *EventStore) GetByID(ctx context.Context, id int64) (*Event, error) {
 	defer cancel()
 
 	var event Event
+	var rsvpDeadline sql.NullString
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&event.ID,
 		&event.Name,
 		&event.Date,
+		&event.Timezone,
 		&event.Location,
 		&event.ScannedCount,
 		&event.CardTemplateID,
+		&rsvpDeadline,
 		&event.UserID,
 		&event.CreatedAt,
 		&event.UpdatedAt,
@@ -102,13 +108,16 @@ func (s *EventStore) GetByID(ctx context.Context, id int64) (*Event, error) {
 		}
 	}
 
+	event.RSVPDeadline = rsvpDeadline.String
+
 	return &event, nil
 }
 
 func (s *EventStore) Create(ctx context.Context, event *Event) error {
 	query := `
-		INSERT INTO events (name, date, location, user_id)
-		VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at
+		INSERT INTO events (name, date, location, user_id, card_template_id, rsvp_deadline, timezone)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::bigint, NULLIF($6, '')::timestamptz, COALESCE(NULLIF($7, ''), 'UTC'))
+		RETURNING id, timezone, created_at, updated_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -121,8 +130,12 @@ func (s *EventStore) Create(ctx context.Context, event *Event) error {
 		event.Date,
 		event.Location,
 		event.UserID,
+		event.CardTemplateID,
+		event.RSVPDeadline,
+		event.Timezone,
 	).Scan(
 		&event.ID,
+		&event.Timezone,
 		&event.CreatedAt,
 		&event.UpdatedAt,
 	)
@@ -159,9 +172,16 @@ func (s *EventStore) Delete(ctx context.Context, eventID int64) error {
 func (s *EventStore) Update(ctx context.Context, event *Event) error {
 	query := `
 		UPDATE events
-		SET name = $1, date = $2, location = $3
-		WHERE id = $4
-		RETURNING id, created_at, updated_at
+		SET name = $1, date = $2, location = $3, card_template_id = NULLIF($4, '')::bigint,
+			-- moving the deadline reopens the RSVPs
+			rsvp_closed_at = CASE
+				WHEN rsvp_deadline IS DISTINCT FROM NULLIF($5, '')::timestamptz THEN NULL
+				ELSE rsvp_closed_at
+			END,
+			rsvp_deadline = NULLIF($5, '')::timestamptz,
+			timezone = COALESCE(NULLIF($7, ''), timezone)
+		WHERE id = $6
+		RETURNING id, timezone, created_at, updated_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -174,8 +194,12 @@ func (s *EventStore) Update(ctx context.Context, event *Event) error {
 		event.Date,
 		event.Location,
 		event.CardTemplateID,
+		event.RSVPDeadline,
+		event.ID,
+		event.Timezone,
 	).Scan(
 		&event.ID,
+		&event.Timezone,
 		&event.CreatedAt,
 		&event.UpdatedAt,
 	)
@@ -4,6 +4,22 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+
+	"github.com/lib/pq"
+)
+
+const (
+	GuestStatusPending  = "pending"
+	GuestStatusAccepted = "accepted"
+	GuestStatusDeclined = "declined"
+	GuestStatusMaybe    = "maybe"
+	// GuestStatusNoResponse is set on pending guests once the RSVP deadline
+	// of their event has passed.
+	GuestStatusNoResponse = "no_response"
+
+	GuestTypeSingle = "single"
+	GuestTypeDouble = "double"
+	GuestTypeVIP    = "vip"
 )
 
 type Guest struct {
@@ -17,6 +33,11 @@ type Guest struct {
 	UpdatedAt   string `json:"updated_at"`
 	CardID      int64  `json:"card_id"`
 	EventID     int64  `json:"event_id"`
+	CheckedInAt string `json:"checked_in_at,omitempty"`
+	RSVPToken   string `json:"rsvp_token,omitempty"`
+	PlusOnes    int    `json:"plus_ones"`
+	RSVPNote    string `json:"rsvp_note"`
+	RespondedAt string `json:"responded_at,omitempty"`
 	Event       Event  `json:"event"`
 	Card        Card   `json:"card"`
 }
@@ -28,19 +49,19 @@ type GuestStore struct {
 func (s *GuestStore) GetGuests(ctx context.Context, eventId int64, fq PaginatedFeedQuery) ([]Guest, error) {
 	query := `
 		SELECT
-			gs.id, gs.name, gs.email, gs.phone_number, gs.status, gs.type
+			gs.id, gs.name, gs.email, gs.phone_number, gs.status, gs.type,
+			COALESCE(gs.card_id, 0), gs.event_id, gs.created_at, gs.updated_at,
+			gs.rsvp_token, gs.plus_ones
 		FROM guests gs
-		LEFT JOIN cards c ON c.id = gs.card_id
 		WHERE gs.event_id = $1 AND
 			(gs.name ILIKE '%' || $4 || '%' OR gs.phone_number ILIKE '%' || $4 || '%')
-		GROUP BY gs.id, gs.name
 		ORDER BY gs.created_at ` + fq.Sort + `
 		LIMIT $2 OFFSET $3
 	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, fq.Limit, fq.Offset, fq.Search)
+	rows, err := s.db.QueryContext(ctx, query, eventId, fq.Limit, fq.Offset, fq.Search)
 	if err != nil {
 		return nil, err
 	}
@@ -57,7 +78,12 @@ func (s *GuestStore) GetGuests(ctx context.Context, eventId int64, fq PaginatedF
 			&g.PhoneNumber,
 			&g.Status,
 			&g.Type,
+			&g.CardID,
+			&g.EventID,
 			&g.CreatedAt,
+			&g.UpdatedAt,
+			&g.RSVPToken,
+			&g.PlusOnes,
 		)
 		if err != nil {
 			return nil, err
@@ -70,17 +96,28 @@ func (s *GuestStore) GetGuests(ctx context.Context, eventId int64, fq PaginatedF
 }
 
 func (s *GuestStore) GetByID(ctx context.Context, id int64) (*Guest, error) {
+	return s.getBy(ctx, "id", id)
+}
+
+// GetByRSVPToken returns the guest an RSVP link was issued to.
+func (s *GuestStore) GetByRSVPToken(ctx context.Context, token string) (*Guest, error) {
+	return s.getBy(ctx, "rsvp_token", token)
+}
+
+func (s *GuestStore) getBy(ctx context.Context, column string, value any) (*Guest, error) {
 	query := `
-		SELECT id, name, email, phone_number, status, type, card_id, event_id, created_at,  updated_at
+		SELECT id, name, email, phone_number, status, type, COALESCE(card_id, 0), event_id, created_at, updated_at,
+			rsvp_token, plus_ones, rsvp_note, responded_at
 		FROM guests
-		WHERE id = $1
+		WHERE ` + column + ` = $1
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
 	var guest Guest
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
+	var respondedAt sql.NullString
+	err := s.db.QueryRowContext(ctx, query, value).Scan(
 		&guest.ID,
 		&guest.Name,
 		&guest.Email,
@@ -91,6 +128,10 @@ func (s *GuestStore) GetByID(ctx context.Context, id int64) (*Guest, error) {
 		&guest.EventID,
 		&guest.CreatedAt,
 		&guest.UpdatedAt,
+		&guest.RSVPToken,
+		&guest.PlusOnes,
+		&guest.RSVPNote,
+		&respondedAt,
 	)
 	if err != nil {
 		switch {
@@ -101,19 +142,21 @@ func (s *GuestStore) GetByID(ctx context.Context, id int64) (*Guest, error) {
 		}
 	}
 
+	guest.RespondedAt = respondedAt.String
+
 	return &guest, nil
 }
 
 func (s *GuestStore) Create(ctx context.Context, tx *sql.Tx, guest *Guest) error {
 	query := `
 		INSERT INTO guests (name, email, phone_number, status, type, event_id)
-		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, rsvp_token, created_at, updated_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
+	err := conn(s.db, tx).QueryRowContext(
 		ctx,
 		query,
 		guest.Name,
@@ -124,6 +167,7 @@ func (s *GuestStore) Create(ctx context.Context, tx *sql.Tx, guest *Guest) error
 		guest.EventID,
 	).Scan(
 		&guest.ID,
+		&guest.RSVPToken,
 		&guest.CreatedAt,
 		&guest.UpdatedAt,
 	)
@@ -134,6 +178,105 @@ func (s *GuestStore) Create(ctx context.Context, tx *sql.Tx, guest *Guest) error
 	return nil
 }
 
+func (s *GuestStore) CreateMany(ctx context.Context, guests []*Guest) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		for _, guest := range guests {
+			if err := s.Create(ctx, tx, guest); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Import bulk loads guests with COPY inside a single transaction. It is used
+// for spreadsheet imports where the generated IDs are not needed.
+func (s *GuestStore) Import(ctx context.Context, guests []*Guest) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return s.copyIn(ctx, tx, guests)
+	})
+}
+
+func (s *GuestStore) copyIn(ctx context.Context, tx *sql.Tx, guests []*Guest) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("guests", "name", "email", "phone_number", "status", "type", "event_id"))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, guest := range guests {
+		_, err := stmt.ExecContext(
+			ctx,
+			guest.Name,
+			guest.Email,
+			guest.PhoneNumber,
+			guest.Status,
+			guest.Type,
+			guest.EventID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// flush the buffered rows
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ForEach calls fn for every guest of an event matching search, without the
+// page size limit of GetGuests.
+func (s *GuestStore) ForEach(ctx context.Context, eventID int64, search string, fn func(*Guest) error) error {
+	query := `
+		SELECT
+			gs.id, gs.name, gs.email, gs.phone_number, gs.status, gs.type,
+			COALESCE(gs.card_id, 0), gs.event_id, gs.created_at, gs.updated_at,
+			COALESCE(to_char(ch.checked_in_at, 'YYYY-MM-DD HH24:MI:SS'), '')
+		FROM guests gs
+		LEFT JOIN checkins ch ON ch.guest_id = gs.id AND ch.undone_at IS NULL
+		WHERE gs.event_id = $1 AND
+			(gs.name ILIKE '%' || $2 || '%' OR gs.phone_number ILIKE '%' || $2 || '%')
+		ORDER BY gs.name ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, eventID, search)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var g Guest
+		err := rows.Scan(
+			&g.ID,
+			&g.Name,
+			&g.Email,
+			&g.PhoneNumber,
+			&g.Status,
+			&g.Type,
+			&g.CardID,
+			&g.EventID,
+			&g.CreatedAt,
+			&g.UpdatedAt,
+			&g.CheckedInAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(&g); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 func (s *GuestStore) Delete(ctx context.Context, guestID int64) error {
 	query := `DELETE FROM guests WHERE id = $1`
 
@@ -168,7 +311,7 @@ func (s *GuestStore) Update(ctx context.Context, tx *sql.Tx, guest *Guest) error
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
+	err := conn(s.db, tx).QueryRowContext(
 		ctx,
 		query,
 		guest.Name,
@@ -176,6 +319,7 @@ func (s *GuestStore) Update(ctx context.Context, tx *sql.Tx, guest *Guest) error
 		guest.PhoneNumber,
 		guest.Status,
 		guest.Type,
+		guest.ID,
 	).Scan(
 		&guest.ID,
 		&guest.CreatedAt,
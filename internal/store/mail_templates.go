@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+// MailTemplate overrides an embedded mail template for a locale.
+type MailTemplate struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Locale    string `json:"locale"`
+	Subject   string `json:"subject"`
+	HTML      string `json:"html"`
+	Text      string `json:"text"`
+	UpdatedBy int64  `json:"updated_by,omitempty"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type MailTemplateStore struct {
+	db *sql.DB
+}
+
+// GetAll returns every override, by name and locale.
+func (s *MailTemplateStore) GetAll(ctx context.Context) ([]MailTemplate, error) {
+	query := `
+		SELECT id, name, locale, subject, html, text, COALESCE(updated_by, 0), created_at, updated_at
+		FROM mail_templates
+		ORDER BY name ASC, locale ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	templates := []MailTemplate{}
+	for rows.Next() {
+		var t MailTemplate
+		if err := scanMailTemplate(rows, &t); err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, t)
+	}
+
+	return templates, rows.Err()
+}
+
+// Get returns the override of a template for locale.
+func (s *MailTemplateStore) Get(ctx context.Context, name, locale string) (*MailTemplate, error) {
+	query := `
+		SELECT id, name, locale, subject, html, text, COALESCE(updated_by, 0), created_at, updated_at
+		FROM mail_templates
+		WHERE name = $1 AND locale = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var t MailTemplate
+	if err := scanMailTemplate(s.db.QueryRowContext(ctx, query, name, locale), &t); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &t, nil
+}
+
+// Save creates or replaces the override of a template for its locale.
+func (s *MailTemplateStore) Save(ctx context.Context, t *MailTemplate) error {
+	query := `
+		INSERT INTO mail_templates (name, locale, subject, html, text, updated_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0))
+		ON CONFLICT (name, locale) DO UPDATE
+		SET subject = EXCLUDED.subject, html = EXCLUDED.html, text = EXCLUDED.text, updated_by = EXCLUDED.updated_by
+		RETURNING id, created_at, updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		t.Name,
+		t.Locale,
+		t.Subject,
+		t.HTML,
+		t.Text,
+		t.UpdatedBy,
+	).Scan(
+		&t.ID,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+	)
+}
+
+// Delete removes an override, restoring the embedded template.
+func (s *MailTemplateStore) Delete(ctx context.Context, name, locale string) error {
+	query := `DELETE FROM mail_templates WHERE name = $1 AND locale = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, name, locale)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func scanMailTemplate(row interface{ Scan(...any) error }, t *MailTemplate) error {
+	return row.Scan(
+		&t.ID,
+		&t.Name,
+		&t.Locale,
+		&t.Subject,
+		&t.HTML,
+		&t.Text,
+		&t.UpdatedBy,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+	)
+}
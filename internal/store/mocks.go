@@ -3,30 +3,53 @@ package store
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"sync"
 	"time"
 )
 
 func NewMockStore() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Users:  &MockUserStore{},
+		Events: &MockEventStore{},
+		Guests: &MockGuestStore{},
+
+		Cards:         &MockCardStore{},
+		CardTemplates: &MockCardTemplateStore{},
+		CheckIns:      &MockCheckInStore{},
+		RSVPs:         &MockRSVPStore{},
+		Campaigns:     &MockCampaignStore{},
+		CardMessages:  &MockCardMessageStore{},
+		Outbox:        &MockOutboxStore{},
+		Roles:         &MockRoleStore{},
+		MailTemplates: &MockMailTemplateStore{},
+		Suppressions:  &MockSuppressionStore{},
+		CardJobs:      &MockCardJobStore{},
+		Assets:        &MockAssetStore{},
 	}
 }
 
-type MockUserStore struct {}
+type MockUserStore struct{}
 
 func (m *MockUserStore) Create(ctx context.Context, tx *sql.Tx, u *User) error {
 	return nil
 }
 
+// GetByID makes user 1, the user of the test token, an admin.
 func (m *MockUserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
-	return &User{ID: userID}, nil
+	user := &User{ID: userID}
+	if userID == 1 {
+		user.Role = Role{Name: "admin", Level: 3}
+	}
+
+	return user, nil
 }
 
 func (m *MockUserStore) GetByEmail(context.Context, string) (*User, error) {
 	return &User{}, nil
 }
 
-func (m *MockUserStore) CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration) error {
+func (m *MockUserStore) CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration, mail *OutboxMail) error {
 	return nil
 }
 
@@ -37,3 +60,656 @@ func (m *MockUserStore) Activate(ctx context.Context, t string) error {
 func (m *MockUserStore) Delete(ctx context.Context, id int64) error {
 	return nil
 }
+
+type MockEventStore struct{}
+
+// GetByID returns events owned by user 1, held in Dar es Salaam. Event 2 is
+// past its RSVP deadline.
+func (m *MockEventStore) GetByID(ctx context.Context, id int64) (*Event, error) {
+	event := &Event{ID: id, UserID: 1, Date: "2024-12-21T18:00:00Z", Timezone: "Africa/Dar_es_Salaam"}
+	if id == 2 {
+		event.RSVPDeadline = "2024-12-01T00:00:00Z"
+	}
+
+	return event, nil
+}
+
+func (m *MockEventStore) Create(ctx context.Context, event *Event) error {
+	return nil
+}
+
+func (m *MockEventStore) Delete(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockEventStore) Update(ctx context.Context, event *Event) error {
+	return nil
+}
+
+func (m *MockEventStore) GetAllEvents(ctx context.Context, fq PaginatedFeedQuery) ([]Event, error) {
+	return []Event{}, nil
+}
+
+type MockGuestStore struct{}
+
+func (m *MockGuestStore) Create(ctx context.Context, tx *sql.Tx, guest *Guest) error {
+	return nil
+}
+
+func (m *MockGuestStore) CreateMany(ctx context.Context, guests []*Guest) error {
+	for i, guest := range guests {
+		guest.ID = int64(i + 1)
+	}
+
+	return nil
+}
+
+func (m *MockGuestStore) Import(ctx context.Context, guests []*Guest) error {
+	return nil
+}
+
+func (m *MockGuestStore) ForEach(ctx context.Context, eventID int64, search string, fn func(*Guest) error) error {
+	return nil
+}
+
+func (m *MockGuestStore) Delete(ctx context.Context, guestID int64) error {
+	return nil
+}
+
+// GetByID gives guest 3 an issued card and a phone number.
+func (m *MockGuestStore) GetByID(ctx context.Context, id int64) (*Guest, error) {
+	guest := &Guest{ID: id, EventID: 1, Status: GuestStatusPending, Type: GuestTypeSingle}
+	if id == 3 {
+		guest.Name = "Amani"
+		guest.CardID = 3
+		guest.PhoneNumber = "0712 345 678"
+		guest.Email = "amani@example.com"
+	}
+
+	return guest, nil
+}
+
+// GetByRSVPToken knows the token "rsvp-token" as guest 1,
+// "closed-token" as guest 2, invited to an event whose RSVPs are closed, and
+// "late-token" as guest 6, whose RSVPs close while answering.
+func (m *MockGuestStore) GetByRSVPToken(ctx context.Context, token string) (*Guest, error) {
+	switch token {
+	case "rsvp-token":
+		return &Guest{ID: 1, Name: "Neema", EventID: 1, Status: GuestStatusPending, Type: GuestTypeDouble, RSVPToken: token}, nil
+	case "closed-token":
+		return &Guest{ID: 2, Name: "Baraka", EventID: 2, Status: GuestStatusNoResponse, Type: GuestTypeSingle, RSVPToken: token}, nil
+	case "late-token":
+		return &Guest{ID: 6, Name: "Zawadi", EventID: 1, Status: GuestStatusPending, Type: GuestTypeSingle, RSVPToken: token}, nil
+	default:
+		return nil, ErrNotFound
+	}
+}
+
+func (m *MockGuestStore) GetGuests(ctx context.Context, eventId int64, fq PaginatedFeedQuery) ([]Guest, error) {
+	return []Guest{}, nil
+}
+
+func (m *MockGuestStore) Update(ctx context.Context, tx *sql.Tx, guest *Guest) error {
+	return nil
+}
+
+// MockCardTemplateStore has a personal template of user 2 as template 2, a
+// shared one of user 2 as template 3 and system templates otherwise.
+type MockCardTemplateStore struct{}
+
+func (m *MockCardTemplateStore) Create(ctx context.Context, tx *sql.Tx, card *CardTemplate) error {
+	card.ID = 4
+	return nil
+}
+
+func (m *MockCardTemplateStore) Delete(ctx context.Context, cardID int64) error {
+	return nil
+}
+
+func (m *MockCardTemplateStore) GetByID(ctx context.Context, id int64) (*CardTemplate, error) {
+	tmpl := &CardTemplate{
+		ID:         id,
+		Name:       fmt.Sprintf("Template %d", id),
+		Visibility: TemplateSystem,
+		Layout: CardLayout{
+			Width:  400,
+			Height: 300,
+			TextBoxes: []CardTextBox{
+				{Field: CardFieldGuestName, X: 20, Y: 20, Width: 360, Align: "center"},
+			},
+		},
+	}
+
+	switch id {
+	case 2:
+		tmpl.UserID, tmpl.Visibility = 2, TemplatePersonal
+	case 3:
+		tmpl.UserID, tmpl.Visibility = 2, TemplateShared
+	}
+
+	return tmpl, nil
+}
+
+func (m *MockCardTemplateStore) GetAvailable(ctx context.Context, userID int64, visibility string, fq PaginatedFeedQuery) ([]CardTemplate, error) {
+	tmpl, _ := m.GetByID(ctx, 3)
+	return []CardTemplate{*tmpl}, nil
+}
+
+func (m *MockCardTemplateStore) Update(ctx context.Context, tx *sql.Tx, card *CardTemplate) error {
+	return nil
+}
+
+// MockCardStore reports the cards in Fingerprints as rendered with them.
+type MockCardStore struct {
+	Fingerprints map[int64]string
+}
+
+func (m *MockCardStore) Create(ctx context.Context, card *Card) error {
+	card.ID = 1
+	return nil
+}
+
+func (m *MockCardStore) Delete(ctx context.Context, cardID int64) error {
+	return nil
+}
+
+// GetByID returns card N as the card of guest N in event 1. Card 4 is
+// revoked.
+func (m *MockCardStore) GetByID(ctx context.Context, id int64) (*Card, error) {
+	card := &Card{ID: id, EventID: 1, GuestID: id, ImagePath: fmt.Sprintf("cards/%d.png", id), Fingerprint: m.Fingerprints[id], Guest: &Guest{ID: id}}
+	if id == 4 {
+		card.RevokedAt = "2024-12-20T10:00:00Z"
+		card.RevokedReason = "shared online"
+	}
+
+	return card, nil
+}
+
+// GetCards lists the cards of guests 1 and 3, named alike.
+func (m *MockCardStore) GetCards(ctx context.Context, eventId int64, fq PaginatedFeedQuery) ([]Card, error) {
+	cards := []Card{}
+	for _, id := range []int64{1, 3} {
+		card, _ := m.GetByID(ctx, id)
+		card.EventID = eventId
+		card.Guest.Name = "Amani"
+		cards = append(cards, *card)
+	}
+
+	return cards, nil
+}
+
+func (m *MockCardStore) ForEach(ctx context.Context, eventID int64, search string, fn func(*Card) error) error {
+	cards, _ := m.GetCards(ctx, eventID, PaginatedFeedQuery{})
+	for i := range cards {
+		if err := fn(&cards[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *MockCardStore) Update(ctx context.Context, tx *sql.Tx, card *Card) error {
+	return nil
+}
+
+func (m *MockCardStore) SetCurrent(ctx context.Context, card *Card, userID int64) error {
+	return nil
+}
+
+func (m *MockCardStore) Revoke(ctx context.Context, cardID int64, reason string, userID int64) error {
+	if cardID == 4 {
+		return ErrCardRevoked
+	}
+
+	return nil
+}
+
+// MockCheckInStore treats guest 2 as already checked in.
+type MockCheckInStore struct{}
+
+func (m *MockCheckInStore) Create(ctx context.Context, checkIn *CheckIn) error {
+	if checkIn.GuestID == 2 {
+		return ErrAlreadyCheckedIn
+	}
+
+	checkIn.ID = 1
+	return nil
+}
+
+func (m *MockCheckInStore) GetByGuest(ctx context.Context, guestID int64) (*CheckIn, error) {
+	if guestID != 2 {
+		return nil, ErrNotFound
+	}
+
+	return &CheckIn{ID: 1, GuestID: guestID, Username: "mlinzi", CheckedInAt: "2024-12-21T18:42:00Z"}, nil
+}
+
+func (m *MockCheckInStore) GetByEvent(ctx context.Context, eventID int64) ([]CheckIn, error) {
+	checkIn, _ := m.GetByGuest(ctx, 2)
+	checkIn.EventID = eventID
+
+	return []CheckIn{*checkIn}, nil
+}
+
+// Merge keeps the stored check-in of guest 2 unless the offline scan is older.
+func (m *MockCheckInStore) Merge(ctx context.Context, checkIn *CheckIn) (bool, error) {
+	if checkIn.GuestID == 2 {
+		existing, _ := m.GetByGuest(ctx, 2)
+		if checkIn.CheckedInAt >= existing.CheckedInAt {
+			*checkIn = *existing
+			return false, ErrAlreadyCheckedIn
+		}
+
+		checkIn.ID = existing.ID
+		return false, nil
+	}
+
+	checkIn.ID = 1
+	return true, nil
+}
+
+func (m *MockCheckInStore) Undo(ctx context.Context, eventID, guestID, userID int64) error {
+	if guestID != 2 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+type MockRSVPStore struct{}
+
+func (m *MockRSVPStore) Create(ctx context.Context, rsvp *RSVP) error {
+	if rsvp.GuestID == 6 {
+		return ErrRSVPClosed
+	}
+
+	rsvp.ID = 1
+	return nil
+}
+
+func (m *MockRSVPStore) GetByGuest(ctx context.Context, guestID int64) ([]RSVP, error) {
+	return []RSVP{{ID: 1, GuestID: guestID, Status: GuestStatusAccepted}}, nil
+}
+
+// GetDueEvents reports event 1 as due.
+func (m *MockRSVPStore) GetDueEvents(ctx context.Context, now time.Time) ([]Event, error) {
+	return []Event{{ID: 1, Name: "Harusi", UserID: 1, RSVPDeadline: "2024-12-01T00:00:00Z"}}, nil
+}
+
+func (m *MockRSVPStore) Close(ctx context.Context, eventID int64) (*RSVPSummary, error) {
+	return &RSVPSummary{EventID: eventID, Accepted: 2, PlusOnes: 1, NoResponse: 3, Expired: 3, ExpiredGuestIDs: []int64{5, 6, 7}}, nil
+}
+
+// MockCampaignStore has a single day-before email campaign with one due
+// delivery.
+type MockCampaignStore struct{}
+
+func (m *MockCampaignStore) Create(ctx context.Context, campaign *Campaign) error {
+	campaign.ID = 1
+	campaign.Status = CampaignScheduled
+	return nil
+}
+
+func (m *MockCampaignStore) GetByID(ctx context.Context, id int64) (*Campaign, error) {
+	if id != 1 {
+		return nil, ErrNotFound
+	}
+
+	return &Campaign{ID: 1, EventID: 1, Name: "Day before", Channel: ChannelEmail, DaysBefore: 1, Status: CampaignScheduled}, nil
+}
+
+func (m *MockCampaignStore) GetByEvent(ctx context.Context, eventID int64) ([]Campaign, error) {
+	campaign, _ := m.GetByID(ctx, 1)
+	return []Campaign{*campaign}, nil
+}
+
+func (m *MockCampaignStore) GetDue(ctx context.Context, now time.Time) ([]Campaign, error) {
+	return m.GetByEvent(ctx, 1)
+}
+
+func (m *MockCampaignStore) Delete(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockCampaignStore) Enqueue(ctx context.Context, campaignID int64) (int, error) {
+	return 1, nil
+}
+
+func (m *MockCampaignStore) GetDeliveries(ctx context.Context, campaignID int64) ([]CampaignDelivery, error) {
+	return []CampaignDelivery{{ID: 1, CampaignID: campaignID, GuestID: 1, Recipient: "neema@example.com", Status: DeliveryPending}}, nil
+}
+
+func (m *MockCampaignStore) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]CampaignDelivery, error) {
+	return []CampaignDelivery{{
+		ID:         1,
+		CampaignID: 1,
+		GuestID:    1,
+		GuestName:  "Neema",
+		Recipient:  "neema@example.com",
+		Attempts:   1,
+		Channel:    ChannelEmail,
+		RSVPToken:  "rsvp-token",
+		Event:      Event{ID: 1, Name: "Harusi", Date: "2024-12-21T18:00:00Z"},
+	}}, nil
+}
+
+func (m *MockCampaignStore) MarkSent(ctx context.Context, deliveryID int64, providerID string) error {
+	return nil
+}
+
+// UpdateStatus only knows the message "sandbox-1".
+func (m *MockCampaignStore) UpdateStatus(ctx context.Context, providerID, status, reason string) error {
+	if providerID != "sandbox-1" {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (m *MockCampaignStore) MarkFailed(ctx context.Context, deliveryID int64, reason string, retryAt time.Time) error {
+	return nil
+}
+
+func (m *MockCampaignStore) Complete(ctx context.Context) error {
+	return nil
+}
+
+// MockCardMessageStore only knows the message "sandbox-1".
+type MockCardMessageStore struct{}
+
+func (m *MockCardMessageStore) Create(ctx context.Context, msg *CardMessage) error {
+	msg.ID = 1
+	msg.Status = MessageSent
+	return nil
+}
+
+func (m *MockCardMessageStore) GetByGuest(ctx context.Context, guestID int64) ([]CardMessage, error) {
+	return []CardMessage{}, nil
+}
+
+func (m *MockCardMessageStore) GetByEvent(ctx context.Context, eventID int64) ([]CardMessage, error) {
+	return []CardMessage{}, nil
+}
+
+func (m *MockCardMessageStore) UpdateStatus(ctx context.Context, providerID, status, reason string) error {
+	if providerID != "sandbox-1" {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// MockOutboxStore has one dead mail, 1. Enqueued mails are kept in Queued
+// and handed out once by Claim.
+type MockOutboxStore struct {
+	mu      sync.Mutex
+	Queued  []OutboxMail
+	claimed int
+}
+
+func (m *MockOutboxStore) Enqueue(ctx context.Context, tx *sql.Tx, mail *OutboxMail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mail.ID = int64(len(m.Queued) + 2)
+	mail.Status = OutboxPending
+	m.Queued = append(m.Queued, *mail)
+	return nil
+}
+
+func (m *MockOutboxStore) GetByID(ctx context.Context, id int64) (*OutboxMail, error) {
+	if id != 1 {
+		return nil, ErrNotFound
+	}
+
+	return &OutboxMail{ID: 1, Template: "user_invitation.tmpl", Status: OutboxDead, Attempts: 8}, nil
+}
+
+func (m *MockOutboxStore) GetByStatus(ctx context.Context, status string, fq PaginatedFeedQuery) ([]OutboxMail, error) {
+	return []OutboxMail{{ID: 1, Template: "user_invitation.tmpl", Status: OutboxDead, Attempts: 8}}, nil
+}
+
+func (m *MockOutboxStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]OutboxMail, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	claimed := m.Queued[m.claimed:]
+	m.claimed = len(m.Queued)
+
+	for i := range claimed {
+		claimed[i].Attempts++
+	}
+
+	return claimed, nil
+}
+
+func (m *MockOutboxStore) MarkSent(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockOutboxStore) MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	return nil
+}
+
+func (m *MockOutboxStore) Redrive(ctx context.Context, id int64) error {
+	if id != 1 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (m *MockOutboxStore) RedriveAll(ctx context.Context) (int, error) {
+	return 1, nil
+}
+
+// MockRoleStore has the levels of the seeded roles.
+type MockRoleStore struct{}
+
+func (m *MockRoleStore) GetByName(ctx context.Context, name string) (*Role, error) {
+	levels := map[string]int{"user": 1, "moderator": 2, "admin": 3}
+
+	level, ok := levels[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &Role{Name: name, Level: level}, nil
+}
+
+// MockMailTemplateStore overrides the reminder template in Swahili.
+type MockMailTemplateStore struct{}
+
+func (m *MockMailTemplateStore) GetAll(ctx context.Context) ([]MailTemplate, error) {
+	t, _ := m.Get(ctx, "event_reminder.tmpl", "sw")
+	return []MailTemplate{*t}, nil
+}
+
+func (m *MockMailTemplateStore) Get(ctx context.Context, name, locale string) (*MailTemplate, error) {
+	if name != "event_reminder.tmpl" || locale != "sw" {
+		return nil, ErrNotFound
+	}
+
+	return &MailTemplate{ID: 1, Name: name, Locale: locale, Subject: "Kumbukumbu: {{.EventName}}", HTML: "<p>Habari {{.GuestName}}</p>"}, nil
+}
+
+func (m *MockMailTemplateStore) Save(ctx context.Context, t *MailTemplate) error {
+	t.ID = 1
+	return nil
+}
+
+func (m *MockMailTemplateStore) Delete(ctx context.Context, name, locale string) error {
+	if _, err := m.Get(ctx, name, locale); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MockSuppressionStore starts with bounced@example.com suppressed and keeps
+// the addresses suppressed since.
+type MockSuppressionStore struct {
+	mu         sync.Mutex
+	Suppressed map[string]Suppression
+}
+
+func (m *MockSuppressionStore) all() map[string]Suppression {
+	if m.Suppressed == nil {
+		m.Suppressed = map[string]Suppression{
+			"bounced@example.com": {Email: "bounced@example.com", Reason: "bounce"},
+		}
+	}
+
+	return m.Suppressed
+}
+
+func (m *MockSuppressionStore) Suppress(ctx context.Context, suppression *Suppression) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.all()[suppression.Email] = *suppression
+	return nil
+}
+
+func (m *MockSuppressionStore) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.all()[email]
+	return ok, nil
+}
+
+func (m *MockSuppressionStore) GetAll(ctx context.Context, fq PaginatedFeedQuery) ([]Suppression, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	suppressions := []Suppression{}
+	for _, suppression := range m.all() {
+		suppressions = append(suppressions, suppression)
+	}
+
+	return suppressions, nil
+}
+
+func (m *MockSuppressionStore) Delete(ctx context.Context, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.all()[email]; !ok {
+		return ErrNotFound
+	}
+
+	delete(m.Suppressed, email)
+	return nil
+}
+
+// MockCardJobStore has job 1 running for event 1, with pending items for
+// guests 1 and 3 that are claimed once. Event 2 already has a running job.
+// The outcome of every item is kept in Done.
+type MockCardJobStore struct {
+	mu      sync.Mutex
+	claimed bool
+	Done    map[int64]string
+}
+
+func (m *MockCardJobStore) Create(ctx context.Context, job *CardJob) error {
+	if job.EventID == 2 {
+		return ErrConflict
+	}
+
+	job.ID = 1
+	job.Status = CardJobRunning
+	job.Total, job.Pending = 2, 2
+	return nil
+}
+
+func (m *MockCardJobStore) GetByID(ctx context.Context, id int64) (*CardJob, error) {
+	if id != 1 {
+		return nil, ErrNotFound
+	}
+
+	return &CardJob{ID: 1, EventID: 1, UserID: 1, CardTemplateID: 1, Status: CardJobRunning, Total: 2, Pending: 1, Rendered: 1, Progress: 50}, nil
+}
+
+func (m *MockCardJobStore) GetByEvent(ctx context.Context, eventID int64) ([]CardJob, error) {
+	job, _ := m.GetByID(ctx, 1)
+	return []CardJob{*job}, nil
+}
+
+func (m *MockCardJobStore) GetItems(ctx context.Context, jobID int64, status string) ([]CardJobItem, error) {
+	items := []CardJobItem{
+		{ID: 1, JobID: jobID, GuestID: 1, Status: CardItemRendered, Attempts: 1, CardID: 1},
+		{ID: 2, JobID: jobID, GuestID: 3, Status: CardItemPending},
+	}
+
+	if status == "" {
+		return items, nil
+	}
+
+	filtered := []CardJobItem{}
+	for _, item := range items {
+		if item.Status == status {
+			filtered = append(filtered, item)
+		}
+	}
+
+	return filtered, nil
+}
+
+func (m *MockCardJobStore) ClaimItems(ctx context.Context, limit int, lease time.Duration) ([]CardJobItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.claimed {
+		return nil, nil
+	}
+	m.claimed = true
+
+	return []CardJobItem{
+		{ID: 1, JobID: 1, GuestID: 1, Status: CardItemPending, Attempts: 1, EventID: 1, CardTemplateID: 1},
+		{ID: 2, JobID: 1, GuestID: 3, Status: CardItemPending, Attempts: 1, EventID: 1, CardTemplateID: 1},
+	}, nil
+}
+
+func (m *MockCardJobStore) MarkDone(ctx context.Context, itemID int64, status string, cardID int64) error {
+	m.mark(itemID, status)
+	return nil
+}
+
+func (m *MockCardJobStore) MarkFailed(ctx context.Context, itemID int64, reason string, retryAt time.Time) error {
+	if retryAt.IsZero() {
+		m.mark(itemID, CardItemFailed)
+	}
+	return nil
+}
+
+func (m *MockCardJobStore) mark(itemID int64, status string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Done == nil {
+		m.Done = map[int64]string{}
+	}
+	m.Done[itemID] = status
+}
+
+func (m *MockCardJobStore) Complete(ctx context.Context) error {
+	return nil
+}
+
+// MockAssetStore reports the keys in Used as in use.
+type MockAssetStore struct {
+	Used map[string]bool
+}
+
+func (m *MockAssetStore) InUse(ctx context.Context, keys []string) (map[string]bool, error) {
+	used := map[string]bool{}
+	for _, key := range keys {
+		if m.Used[key] {
+			used[key] = true
+		}
+	}
+
+	return used, nil
+}
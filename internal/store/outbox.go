@@ -0,0 +1,273 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	// OutboxDead mails gave up after too many attempts and wait to be
+	// re-driven.
+	OutboxDead = "dead"
+)
+
+// OutboxMail is a mail waiting in the outbox to be delivered by the mail
+// worker. It is written in the same transaction as the change it reports.
+type OutboxMail struct {
+	ID             int64  `json:"id"`
+	Template       string `json:"template"`
+	RecipientName  string `json:"recipient_name"`
+	RecipientEmail string `json:"recipient_email"`
+	// Payload is the encoded template data, reply-to, headers and
+	// attachments of the mail.
+	Payload       json.RawMessage `json:"-"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"last_error,omitempty"`
+	NextAttemptAt string          `json:"next_attempt_at"`
+	SentAt        string          `json:"sent_at,omitempty"`
+	CreatedAt     string          `json:"created_at"`
+	UpdatedAt     string          `json:"updated_at"`
+}
+
+type OutboxStore struct {
+	db *sql.DB
+}
+
+// Enqueue adds a mail to the outbox, within tx when given.
+func (s *OutboxStore) Enqueue(ctx context.Context, tx *sql.Tx, mail *OutboxMail) error {
+	return enqueueMail(ctx, conn(s.db, tx), mail)
+}
+
+func enqueueMail(ctx context.Context, q queryer, mail *OutboxMail) error {
+	query := `
+		INSERT INTO mail_outbox (template, recipient_name, recipient_email, payload)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, next_attempt_at, created_at, updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	payload := mail.Payload
+	if len(payload) == 0 {
+		payload = json.RawMessage("{}")
+	}
+
+	return q.QueryRowContext(
+		ctx,
+		query,
+		mail.Template,
+		mail.RecipientName,
+		mail.RecipientEmail,
+		[]byte(payload),
+	).Scan(
+		&mail.ID,
+		&mail.Status,
+		&mail.NextAttemptAt,
+		&mail.CreatedAt,
+		&mail.UpdatedAt,
+	)
+}
+
+const outboxColumns = `
+	id, template, recipient_name, recipient_email, status, attempts, last_error,
+	to_char(next_attempt_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'),
+	COALESCE(to_char(sent_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'), ''),
+	to_char(created_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'),
+	to_char(updated_at, 'YYYY-MM-DD"T"HH24:MI:SSOF')`
+
+func scanOutboxMail(row interface{ Scan(...any) error }, mail *OutboxMail, extra ...any) error {
+	dest := []any{
+		&mail.ID,
+		&mail.Template,
+		&mail.RecipientName,
+		&mail.RecipientEmail,
+		&mail.Status,
+		&mail.Attempts,
+		&mail.LastError,
+		&mail.NextAttemptAt,
+		&mail.SentAt,
+		&mail.CreatedAt,
+		&mail.UpdatedAt,
+	}
+
+	return row.Scan(append(dest, extra...)...)
+}
+
+func (s *OutboxStore) GetByID(ctx context.Context, id int64) (*OutboxMail, error) {
+	query := `SELECT ` + outboxColumns + ` FROM mail_outbox WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var mail OutboxMail
+	if err := scanOutboxMail(s.db.QueryRowContext(ctx, query, id), &mail); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &mail, nil
+}
+
+// GetByStatus lists the mails with status, or all mails when empty, latest
+// first.
+func (s *OutboxStore) GetByStatus(ctx context.Context, status string, fq PaginatedFeedQuery) ([]OutboxMail, error) {
+	query := `
+		SELECT ` + outboxColumns + `
+		FROM mail_outbox
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at ` + fq.Sort + `, id ` + fq.Sort + `
+		LIMIT $2 OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, status, fq.Limit, fq.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	mails := []OutboxMail{}
+	for rows.Next() {
+		var mail OutboxMail
+		if err := scanOutboxMail(rows, &mail); err != nil {
+			return nil, err
+		}
+
+		mails = append(mails, mail)
+	}
+
+	return mails, rows.Err()
+}
+
+// Claim takes up to limit pending mails that are due and counts an attempt
+// for each. Claimed mails are not handed out again for lease, so a worker
+// that dies mid-way only delays them.
+func (s *OutboxStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]OutboxMail, error) {
+	query := `
+		UPDATE mail_outbox
+		SET attempts = attempts + 1, next_attempt_at = NOW() + $2 * interval '1 second'
+		WHERE id IN (
+			SELECT id FROM mail_outbox
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + outboxColumns + `, payload
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var mails []OutboxMail
+	for rows.Next() {
+		var mail OutboxMail
+		var payload []byte
+		if err := scanOutboxMail(rows, &mail, &payload); err != nil {
+			return nil, err
+		}
+
+		mail.Payload = payload
+		mails = append(mails, mail)
+	}
+
+	return mails, rows.Err()
+}
+
+func (s *OutboxStore) MarkSent(ctx context.Context, id int64) error {
+	query := `
+		UPDATE mail_outbox SET status = 'sent', sent_at = NOW(), last_error = ''
+		WHERE id = $1
+	`
+
+	return s.exec(ctx, query, id)
+}
+
+// MarkFailed records a failed attempt. The mail is retried at retryAt, or
+// dead-lettered when retryAt is zero.
+func (s *OutboxStore) MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	query := `
+		UPDATE mail_outbox
+		SET status = CASE WHEN $3::timestamptz IS NULL THEN 'dead' ELSE 'pending' END,
+			last_error = $2,
+			next_attempt_at = COALESCE($3::timestamptz, next_attempt_at)
+		WHERE id = $1
+	`
+
+	var next sql.NullTime
+	if !retryAt.IsZero() {
+		next = sql.NullTime{Time: retryAt, Valid: true}
+	}
+
+	return s.exec(ctx, query, id, reason, next)
+}
+
+// Redrive sends a dead mail again with a fresh set of attempts.
+func (s *OutboxStore) Redrive(ctx context.Context, id int64) error {
+	query := `
+		UPDATE mail_outbox SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE id = $1 AND status = 'dead'
+	`
+
+	return s.exec(ctx, query, id)
+}
+
+// RedriveAll sends every dead mail again and returns how many there were.
+func (s *OutboxStore) RedriveAll(ctx context.Context) (int, error) {
+	query := `
+		UPDATE mail_outbox SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE status = 'dead'
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *OutboxStore) exec(ctx context.Context, query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
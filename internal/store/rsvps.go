@@ -0,0 +1,295 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrRSVPClosed = errors.New("the RSVPs of the event are closed")
+
+// RSVP is a response of a guest to an invitation. Every response is kept,
+// the latest one is also reflected on the guest.
+type RSVP struct {
+	ID        int64  `json:"id"`
+	GuestID   int64  `json:"guest_id"`
+	Status    string `json:"status"`
+	PlusOnes  int    `json:"plus_ones"`
+	Note      string `json:"note"`
+	CreatedAt string `json:"created_at"`
+}
+
+// RSVPSummary counts the answers of an event's guests when its RSVPs close.
+type RSVPSummary struct {
+	EventID    int64 `json:"event_id"`
+	Accepted   int   `json:"accepted"`
+	Declined   int   `json:"declined"`
+	Maybe      int   `json:"maybe"`
+	NoResponse int   `json:"no_response"`
+	// PlusOnes is the number of extra people coming with accepted guests.
+	PlusOnes int `json:"plus_ones"`
+	// Expired is the number of guests moved to no_response on closing.
+	Expired int `json:"expired"`
+	// ExpiredGuestIDs are the guests moved to no_response on closing.
+	ExpiredGuestIDs []int64 `json:"-"`
+}
+
+type RSVPStore struct {
+	db *sql.DB
+}
+
+// Create records the response and updates the guest's status, plus-ones
+// and note in the same transaction. ErrRSVPClosed is returned when the
+// deadline of the event has passed, the event row being locked so that a
+// response cannot land while Close moves the guests to no_response.
+func (s *RSVPStore) Create(ctx context.Context, rsvp *RSVP) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT (e.rsvp_deadline IS NULL OR e.rsvp_deadline > NOW()) AND e.rsvp_closed_at IS NULL
+			FROM guests g
+			JOIN events e ON e.id = g.event_id
+			WHERE g.id = $1
+			FOR SHARE OF e
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var open bool
+		if err := tx.QueryRowContext(ctx, query, rsvp.GuestID).Scan(&open); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if !open {
+			return ErrRSVPClosed
+		}
+
+		query = `
+			INSERT INTO rsvp_responses (guest_id, status, plus_ones, note)
+			VALUES ($1, $2, $3, $4) RETURNING id, created_at
+		`
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			rsvp.GuestID,
+			rsvp.Status,
+			rsvp.PlusOnes,
+			rsvp.Note,
+		).Scan(
+			&rsvp.ID,
+			&rsvp.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		query = `
+			UPDATE guests
+			SET status = $1, plus_ones = $2, rsvp_note = $3, responded_at = NOW()
+			WHERE id = $4
+		`
+
+		res, err := tx.ExecContext(ctx, query, rsvp.Status, rsvp.PlusOnes, rsvp.Note, rsvp.GuestID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// GetByGuest returns the responses of a guest, latest first.
+func (s *RSVPStore) GetByGuest(ctx context.Context, guestID int64) ([]RSVP, error) {
+	query := `
+		SELECT id, guest_id, status, plus_ones, note, created_at
+		FROM rsvp_responses
+		WHERE guest_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, guestID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	responses := []RSVP{}
+	for rows.Next() {
+		var rsvp RSVP
+		err := rows.Scan(
+			&rsvp.ID,
+			&rsvp.GuestID,
+			&rsvp.Status,
+			&rsvp.PlusOnes,
+			&rsvp.Note,
+			&rsvp.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		responses = append(responses, rsvp)
+	}
+
+	return responses, rows.Err()
+}
+
+// GetDueEvents returns the events whose RSVP deadline passed before now and
+// whose RSVPs have not been closed yet.
+func (s *RSVPStore) GetDueEvents(ctx context.Context, now time.Time) ([]Event, error) {
+	query := `
+		SELECT id, name, date, location, rsvp_deadline, user_id
+		FROM events
+		WHERE rsvp_deadline <= $1 AND rsvp_closed_at IS NULL
+		ORDER BY rsvp_deadline ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		err := rows.Scan(
+			&e.ID,
+			&e.Name,
+			&e.Date,
+			&e.Location,
+			&e.RSVPDeadline,
+			&e.UserID,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// Close closes the RSVPs of an event whose deadline has passed, moving the
+// guests that never answered to no_response. ErrNotFound is returned when
+// the RSVPs are not due or were already closed, e.g. by another instance.
+func (s *RSVPStore) Close(ctx context.Context, eventID int64) (*RSVPSummary, error) {
+	summary := &RSVPSummary{EventID: eventID}
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			UPDATE events SET rsvp_closed_at = NOW()
+			WHERE id = $1 AND rsvp_deadline <= NOW() AND rsvp_closed_at IS NULL
+		`
+
+		res, err := tx.ExecContext(ctx, query, eventID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		query = `UPDATE guests SET status = $1 WHERE event_id = $2 AND status = $3 RETURNING id`
+
+		expired, err := tx.QueryContext(ctx, query, GuestStatusNoResponse, eventID, GuestStatusPending)
+		if err != nil {
+			return err
+		}
+
+		defer expired.Close()
+
+		for expired.Next() {
+			var id int64
+			if err := expired.Scan(&id); err != nil {
+				return err
+			}
+
+			summary.ExpiredGuestIDs = append(summary.ExpiredGuestIDs, id)
+		}
+
+		if err := expired.Err(); err != nil {
+			return err
+		}
+		summary.Expired = len(summary.ExpiredGuestIDs)
+
+		return s.summarize(ctx, tx, summary)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+func (s *RSVPStore) summarize(ctx context.Context, tx *sql.Tx, summary *RSVPSummary) error {
+	query := `
+		SELECT status, COUNT(*), COALESCE(SUM(plus_ones), 0)
+		FROM guests
+		WHERE event_id = $1
+		GROUP BY status
+	`
+
+	rows, err := tx.QueryContext(ctx, query, summary.EventID)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var count, plusOnes int
+		if err := rows.Scan(&status, &count, &plusOnes); err != nil {
+			return err
+		}
+
+		switch status {
+		case GuestStatusAccepted:
+			summary.Accepted = count
+			summary.PlusOnes = plusOnes
+		case GuestStatusDeclined:
+			summary.Declined = count
+		case GuestStatusMaybe:
+			summary.Maybe = count
+		case GuestStatusNoResponse:
+			summary.NoResponse = count
+		}
+	}
+
+	return rows.Err()
+}
@@ -23,8 +23,12 @@ type Storage struct {
 	}
 	Guests interface {
 		Create(ctx context.Context, tx *sql.Tx, guest *Guest) error
+		CreateMany(ctx context.Context, guests []*Guest) error
+		Import(ctx context.Context, guests []*Guest) error
+		ForEach(ctx context.Context, eventID int64, search string, fn func(*Guest) error) error
 		Delete(ctx context.Context, guestID int64) error
 		GetByID(ctx context.Context, id int64) (*Guest, error)
+		GetByRSVPToken(ctx context.Context, token string) (*Guest, error)
 		GetGuests(ctx context.Context, eventId int64, fq PaginatedFeedQuery) ([]Guest, error)
 		Update(ctx context.Context, tx *sql.Tx, guest *Guest) error
 	}
@@ -32,7 +36,7 @@ type Storage struct {
 		GetByID(context.Context, int64) (*User, error)
 		GetByEmail(context.Context, string) (*User, error)
 		Create(context.Context, *sql.Tx, *User) error
-		CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration) error
+		CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration, mail *OutboxMail) error
 		Activate(context.Context, string) error
 		Delete(context.Context, int64) error
 	}
@@ -41,18 +45,89 @@ type Storage struct {
 		Delete(ctx context.Context, cardID int64) error
 		GetByID(ctx context.Context, id int64) (*Card, error)
 		GetCards(ctx context.Context, eventId int64, fq PaginatedFeedQuery) ([]Card, error)
+		ForEach(ctx context.Context, eventID int64, search string, fn func(*Card) error) error
 		Update(ctx context.Context, tx *sql.Tx, card *Card) error
+		SetCurrent(ctx context.Context, card *Card, userID int64) error
+		Revoke(ctx context.Context, cardID int64, reason string, userID int64) error
 	}
 	CardTemplates interface {
 		Create(ctx context.Context, tx *sql.Tx, card *CardTemplate) error
 		Delete(ctx context.Context, cardID int64) error
 		GetByID(ctx context.Context, id int64) (*CardTemplate, error)
-		GetCards(ctx context.Context, eventId int64, fq PaginatedFeedQuery) ([]CardTemplate, error)
+		GetAvailable(ctx context.Context, userID int64, visibility string, fq PaginatedFeedQuery) ([]CardTemplate, error)
 		Update(ctx context.Context, tx *sql.Tx, card *CardTemplate) error
 	}
 	Roles interface {
 		GetByName(context.Context, string) (*Role, error)
 	}
+	CheckIns interface {
+		Create(ctx context.Context, checkIn *CheckIn) error
+		GetByGuest(ctx context.Context, guestID int64) (*CheckIn, error)
+		GetByEvent(ctx context.Context, eventID int64) ([]CheckIn, error)
+		Merge(ctx context.Context, checkIn *CheckIn) (bool, error)
+		Undo(ctx context.Context, eventID, guestID, userID int64) error
+	}
+	RSVPs interface {
+		Create(ctx context.Context, rsvp *RSVP) error
+		GetByGuest(ctx context.Context, guestID int64) ([]RSVP, error)
+		GetDueEvents(ctx context.Context, now time.Time) ([]Event, error)
+		Close(ctx context.Context, eventID int64) (*RSVPSummary, error)
+	}
+	Campaigns interface {
+		Create(ctx context.Context, campaign *Campaign) error
+		GetByID(ctx context.Context, id int64) (*Campaign, error)
+		GetByEvent(ctx context.Context, eventID int64) ([]Campaign, error)
+		GetDue(ctx context.Context, now time.Time) ([]Campaign, error)
+		Delete(ctx context.Context, id int64) error
+		Enqueue(ctx context.Context, campaignID int64) (int, error)
+		GetDeliveries(ctx context.Context, campaignID int64) ([]CampaignDelivery, error)
+		ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]CampaignDelivery, error)
+		MarkSent(ctx context.Context, deliveryID int64, providerID string) error
+		UpdateStatus(ctx context.Context, providerID, status, reason string) error
+		MarkFailed(ctx context.Context, deliveryID int64, reason string, retryAt time.Time) error
+		Complete(ctx context.Context) error
+	}
+	CardMessages interface {
+		Create(ctx context.Context, msg *CardMessage) error
+		GetByGuest(ctx context.Context, guestID int64) ([]CardMessage, error)
+		GetByEvent(ctx context.Context, eventID int64) ([]CardMessage, error)
+		UpdateStatus(ctx context.Context, providerID, status, reason string) error
+	}
+	Outbox interface {
+		Enqueue(ctx context.Context, tx *sql.Tx, mail *OutboxMail) error
+		GetByID(ctx context.Context, id int64) (*OutboxMail, error)
+		GetByStatus(ctx context.Context, status string, fq PaginatedFeedQuery) ([]OutboxMail, error)
+		Claim(ctx context.Context, limit int, lease time.Duration) ([]OutboxMail, error)
+		MarkSent(ctx context.Context, id int64) error
+		MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error
+		Redrive(ctx context.Context, id int64) error
+		RedriveAll(ctx context.Context) (int, error)
+	}
+	MailTemplates interface {
+		GetAll(ctx context.Context) ([]MailTemplate, error)
+		Get(ctx context.Context, name, locale string) (*MailTemplate, error)
+		Save(ctx context.Context, t *MailTemplate) error
+		Delete(ctx context.Context, name, locale string) error
+	}
+	Suppressions interface {
+		Suppress(ctx context.Context, suppression *Suppression) error
+		IsSuppressed(ctx context.Context, email string) (bool, error)
+		GetAll(ctx context.Context, fq PaginatedFeedQuery) ([]Suppression, error)
+		Delete(ctx context.Context, email string) error
+	}
+	CardJobs interface {
+		Create(ctx context.Context, job *CardJob) error
+		GetByID(ctx context.Context, id int64) (*CardJob, error)
+		GetByEvent(ctx context.Context, eventID int64) ([]CardJob, error)
+		GetItems(ctx context.Context, jobID int64, status string) ([]CardJobItem, error)
+		ClaimItems(ctx context.Context, limit int, lease time.Duration) ([]CardJobItem, error)
+		MarkDone(ctx context.Context, itemID int64, status string, cardID int64) error
+		MarkFailed(ctx context.Context, itemID int64, reason string, retryAt time.Time) error
+		Complete(ctx context.Context) error
+	}
+	Assets interface {
+		InUse(ctx context.Context, keys []string) (map[string]bool, error)
+	}
 }
 
 func NewStorage(db *sql.DB) Storage {
@@ -63,6 +138,15 @@ func NewStorage(db *sql.DB) Storage {
 		Cards:         &CardStore{db},
 		CardTemplates: &CardTemplateStore{db},
 		Roles:         &RoleStore{db},
+		CheckIns:      &CheckInStore{db},
+		RSVPs:         &RSVPStore{db},
+		Campaigns:     &CampaignStore{db},
+		CardMessages:  &CardMessageStore{db},
+		Outbox:        &OutboxStore{db},
+		MailTemplates: &MailTemplateStore{db},
+		Suppressions:  &SuppressionStore{db},
+		CardJobs:      &CardJobStore{db},
+		Assets:        &AssetStore{db},
 	}
 }
 
@@ -79,3 +163,19 @@ func withTx(db *sql.DB, ctx context.Context, fn func(*sql.Tx) error) error {
 
 	return tx.Commit()
 }
+
+// queryer is implemented by both *sql.DB and *sql.Tx so store methods that
+// accept an optional transaction can fall back to the connection pool.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func conn(db *sql.DB, tx *sql.Tx) queryer {
+	if tx != nil {
+		return tx
+	}
+
+	return db
+}
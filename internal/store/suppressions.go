@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Suppression stops mail to an address the provider reported as bouncing,
+// complaining or unsubscribed. It applies to users and guests alike.
+type Suppression struct {
+	Email     string `json:"email"`
+	Reason    string `json:"reason"`
+	Detail    string `json:"detail"`
+	CreatedAt string `json:"created_at"`
+}
+
+type SuppressionStore struct {
+	db *sql.DB
+}
+
+// Suppress records a suppression, replacing the reason of an address that
+// is already suppressed.
+func (s *SuppressionStore) Suppress(ctx context.Context, suppression *Suppression) error {
+	query := `
+		INSERT INTO email_suppressions (email, reason, detail)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (email) DO UPDATE
+		SET reason = EXCLUDED.reason, detail = EXCLUDED.detail
+		RETURNING created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		suppression.Email,
+		suppression.Reason,
+		suppression.Detail,
+	).Scan(&suppression.CreatedAt)
+}
+
+func (s *SuppressionStore) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM email_suppressions WHERE email = $1)`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var suppressed bool
+	if err := s.db.QueryRowContext(ctx, query, email).Scan(&suppressed); err != nil {
+		return false, err
+	}
+
+	return suppressed, nil
+}
+
+// GetAll lists the suppressed addresses, optionally matching fq.Search.
+func (s *SuppressionStore) GetAll(ctx context.Context, fq PaginatedFeedQuery) ([]Suppression, error) {
+	query := `
+		SELECT email, reason, detail, created_at
+		FROM email_suppressions
+		WHERE ($1 = '' OR email ILIKE '%' || $1 || '%')
+		ORDER BY created_at ` + fq.Sort + `, email ASC
+		LIMIT $2 OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, fq.Search, fq.Limit, fq.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	suppressions := []Suppression{}
+	for rows.Next() {
+		var suppression Suppression
+		if err := rows.Scan(
+			&suppression.Email,
+			&suppression.Reason,
+			&suppression.Detail,
+			&suppression.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		suppressions = append(suppressions, suppression)
+	}
+
+	return suppressions, rows.Err()
+}
+
+// Delete lifts a suppression, e.g. once a guest fixed their mailbox.
+func (s *SuppressionStore) Delete(ctx context.Context, email string) error {
+	query := `DELETE FROM email_suppressions WHERE email = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, email)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}